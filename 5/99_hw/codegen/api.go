@@ -1,5 +1,8 @@
 package main
 
+//go:generate go run ./handlers_gen -output api_codegen.go -proto api.proto -grpc-output api_grpc_codegen.go -proto-go-package codegenhw/apipb
+//go:generate protoc --go_out=. --go_opt=module=codegenhw --go-grpc_out=. --go-grpc_opt=module=codegenhw api.proto
+
 import (
 	"context"
 	"fmt"
@@ -54,25 +57,25 @@ func NewMyApi() *MyApi {
 }
 
 type ProfileParams struct {
-	Login string `apivalidator:"required"`
+	Login string `apivalidator:"required" proto:"1"`
 }
 
 type CreateParams struct {
-	Login  string `apivalidator:"required,min=10"`
-	Name   string `apivalidator:"paramname=full_name"`
-	Status string `apivalidator:"enum=user|moderator|admin,default=user"`
-	Age    int    `apivalidator:"min=0,max=128"`
+	Login  string `apivalidator:"required,min=10" proto:"1"`
+	Name   string `apivalidator:"paramname=full_name" proto:"2"`
+	Status string `apivalidator:"enum=user|moderator|admin,default=user" proto:"3"`
+	Age    int    `apivalidator:"min=0,max=128" proto:"4"`
 }
 
 type User struct {
-	ID       uint64 `json:"id"`
-	Login    string `json:"login"`
-	FullName string `json:"full_name"`
-	Status   int    `json:"status"`
+	ID       uint64 `json:"id" proto:"1"`
+	Login    string `json:"login" proto:"2"`
+	FullName string `json:"full_name" proto:"3"`
+	Status   int    `json:"status" proto:"4"`
 }
 
 type NewUser struct {
-	ID uint64 `json:"id"`
+	ID uint64 `json:"id" proto:"1"`
 }
 
 // apigen:api {"url": "/user/profile", "auth": false}
@@ -131,17 +134,17 @@ func NewOtherApi() *OtherApi {
 }
 
 type OtherCreateParams struct {
-	Username string `apivalidator:"required,min=3"`
-	Name     string `apivalidator:"paramname=account_name"`
-	Class    string `apivalidator:"enum=warrior|sorcerer|rouge,default=warrior"`
-	Level    int    `apivalidator:"min=1,max=50"`
+	Username string `apivalidator:"required,min=3" proto:"1"`
+	Name     string `apivalidator:"paramname=account_name" proto:"2"`
+	Class    string `apivalidator:"enum=warrior|sorcerer|rouge,default=warrior" proto:"3"`
+	Level    int    `apivalidator:"min=1,max=50" proto:"4"`
 }
 
 type OtherUser struct {
-	ID       uint64 `json:"id"`
-	Login    string `json:"login"`
-	FullName string `json:"full_name"`
-	Level    int    `json:"level"`
+	ID       uint64 `json:"id" proto:"1"`
+	Login    string `json:"login" proto:"2"`
+	FullName string `json:"full_name" proto:"3"`
+	Level    int    `json:"level" proto:"4"`
 }
 
 // apigen:api {"url": "/user/create", "auth": true, "method": "POST"}
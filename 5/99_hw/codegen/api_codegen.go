@@ -1,49 +1,52 @@
-// Code generated by go generate; DO NOT EDIT
+// Code generated by handlers_gen. DO NOT EDIT.
+
 package main
-		import (
-		"encoding/json"
-		"fmt"
-		"io/ioutil"
-		"net/http"
-		"net/url"
-		"strconv"
-		"strings"
-	)
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
 func (h *MyApi) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-    var (
-        err error
-        out interface{}
-    )
-
-    switch r.URL.Path {
-        case "/user/profile":
-            out, err = h.wrapperProfile(w, r)
-        case "/user/create":
-            out, err = h.wrapperCreate(w, r)
-        default:
-            err = ApiError{Err: fmt.Errorf("unknown method"), HTTPStatus: http.StatusNotFound}
-    }
-
-    response := struct {
-        Data  interface{} `json:"response,omitempty"`
-        Error string      `json:"error"`
-    }{}
-
-    if err == nil {
-        response.Data = out
-    } else {
-        response.Error = err.Error()
-
-        if errApi, ok := err.(ApiError); ok {
-            w.WriteHeader(errApi.HTTPStatus)
-        } else {
-            w.WriteHeader(http.StatusInternalServerError)
-        }
-    }
-
-    jsonResponse, _ := json.Marshal(response)
-    w.Header().Set("Content-Type", "application/json")
-    w.Write(jsonResponse)
+	var (
+		err error
+		out interface{}
+	)
+
+	switch r.URL.Path {
+	case "/user/profile":
+		out, err = h.wrapperProfile(w, r)
+	case "/user/create":
+		out, err = h.wrapperCreate(w, r)
+	default:
+		err = ApiError{Err: fmt.Errorf("unknown method"), HTTPStatus: http.StatusNotFound}
+	}
+
+	response := struct {
+		Data  interface{} `json:"response,omitempty"`
+		Error string      `json:"error"`
+	}{}
+
+	if err == nil {
+		response.Data = out
+	} else {
+		response.Error = err.Error()
+
+		if errApi, ok := err.(ApiError); ok {
+			w.WriteHeader(errApi.HTTPStatus)
+		} else {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}
+
+	jsonResponse, _ := json.Marshal(response)
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(jsonResponse)
 }
 
 func (h *MyApi) wrapperProfile(w http.ResponseWriter, r *http.Request) (interface{}, error) {
@@ -89,38 +92,38 @@ func (h *MyApi) wrapperCreate(w http.ResponseWriter, r *http.Request) (interface
 }
 
 func (h *OtherApi) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-    var (
-        err error
-        out interface{}
-    )
-
-    switch r.URL.Path {
-        case "/user/create":
-            out, err = h.wrapperCreate(w, r)
-        default:
-            err = ApiError{Err: fmt.Errorf("unknown method"), HTTPStatus: http.StatusNotFound}
-    }
-
-    response := struct {
-        Data  interface{} `json:"response,omitempty"`
-        Error string      `json:"error"`
-    }{}
-
-    if err == nil {
-        response.Data = out
-    } else {
-        response.Error = err.Error()
-
-        if errApi, ok := err.(ApiError); ok {
-            w.WriteHeader(errApi.HTTPStatus)
-        } else {
-            w.WriteHeader(http.StatusInternalServerError)
-        }
-    }
-
-    jsonResponse, _ := json.Marshal(response)
-    w.Header().Set("Content-Type", "application/json")
-    w.Write(jsonResponse)
+	var (
+		err error
+		out interface{}
+	)
+
+	switch r.URL.Path {
+	case "/user/create":
+		out, err = h.wrapperCreate(w, r)
+	default:
+		err = ApiError{Err: fmt.Errorf("unknown method"), HTTPStatus: http.StatusNotFound}
+	}
+
+	response := struct {
+		Data  interface{} `json:"response,omitempty"`
+		Error string      `json:"error"`
+	}{}
+
+	if err == nil {
+		response.Data = out
+	} else {
+		response.Error = err.Error()
+
+		if errApi, ok := err.(ApiError); ok {
+			w.WriteHeader(errApi.HTTPStatus)
+		} else {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}
+
+	jsonResponse, _ := json.Marshal(response)
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(jsonResponse)
 }
 
 func (h *OtherApi) wrapperCreate(w http.ResponseWriter, r *http.Request) (interface{}, error) {
@@ -148,20 +151,6 @@ func (h *OtherApi) wrapperCreate(w http.ResponseWriter, r *http.Request) (interf
 	return h.Create(r.Context(), in)
 }
 
-func newProfileParams(v url.Values) (ProfileParams, error) {
-	var err error
-	s := ProfileParams{}
-
-	// Login
-	s.Login = v.Get("login")
-
-	if s.Login == "" {
-		return s, ApiError{http.StatusBadRequest, fmt.Errorf("login must me not empty")}
-	}
-
-	return s, err
-}
-
 func newCreateParams(v url.Values) (CreateParams, error) {
 	var err error
 	s := CreateParams{}
@@ -273,3 +262,17 @@ func newOtherCreateParams(v url.Values) (OtherCreateParams, error) {
 
 	return s, err
 }
+
+func newProfileParams(v url.Values) (ProfileParams, error) {
+	var err error
+	s := ProfileParams{}
+
+	// Login
+	s.Login = v.Get("login")
+
+	if s.Login == "" {
+		return s, ApiError{http.StatusBadRequest, fmt.Errorf("login must me not empty")}
+	}
+
+	return s, err
+}
@@ -0,0 +1,176 @@
+// Code generated by handlers_gen. DO NOT EDIT.
+
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	apipb "codegenhw/apipb"
+)
+
+// MyApiGRPC serves MyApi methods over gRPC
+type MyApiGRPC struct {
+	apipb.UnimplementedMyApiServer
+	api *MyApi
+}
+
+func NewMyApiGRPC(api *MyApi) *MyApiGRPC {
+	return &MyApiGRPC{api: api}
+}
+
+func (s *MyApiGRPC) Profile(ctx context.Context, req *apipb.ProfileParams) (*apipb.User, error) {
+	params := url.Values{}
+	if req.Login != nil {
+		params.Set("login", req.GetLogin())
+	}
+
+	in, err := newProfileParams(params)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+
+	out, err := s.api.Profile(ctx, in)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	if out == nil {
+		return &apipb.User{}, nil
+	}
+
+	return &apipb.User{
+		Id:       out.ID,
+		Login:    out.Login,
+		FullName: out.FullName,
+		Status:   int64(out.Status),
+	}, nil
+}
+
+func (s *MyApiGRPC) Create(ctx context.Context, req *apipb.CreateParams) (*apipb.NewUser, error) {
+	if !grpcAuthorized(ctx) {
+		return nil, status.Error(codes.Unauthenticated, "unauthorized")
+	}
+
+	params := url.Values{}
+	if req.Login != nil {
+		params.Set("login", req.GetLogin())
+	}
+	if req.FullName != nil {
+		params.Set("full_name", req.GetFullName())
+	}
+	if req.Status != nil {
+		params.Set("status", req.GetStatus())
+	}
+	if req.Age != nil {
+		params.Set("age", strconv.FormatInt(req.GetAge(), 10))
+	}
+
+	in, err := newCreateParams(params)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+
+	out, err := s.api.Create(ctx, in)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	if out == nil {
+		return &apipb.NewUser{}, nil
+	}
+
+	return &apipb.NewUser{
+		Id: out.ID,
+	}, nil
+}
+
+// OtherApiGRPC serves OtherApi methods over gRPC
+type OtherApiGRPC struct {
+	apipb.UnimplementedOtherApiServer
+	api *OtherApi
+}
+
+func NewOtherApiGRPC(api *OtherApi) *OtherApiGRPC {
+	return &OtherApiGRPC{api: api}
+}
+
+func (s *OtherApiGRPC) Create(ctx context.Context, req *apipb.OtherCreateParams) (*apipb.OtherUser, error) {
+	if !grpcAuthorized(ctx) {
+		return nil, status.Error(codes.Unauthenticated, "unauthorized")
+	}
+
+	params := url.Values{}
+	if req.Username != nil {
+		params.Set("username", req.GetUsername())
+	}
+	if req.AccountName != nil {
+		params.Set("account_name", req.GetAccountName())
+	}
+	if req.Class != nil {
+		params.Set("class", req.GetClass())
+	}
+	if req.Level != nil {
+		params.Set("level", strconv.FormatInt(req.GetLevel(), 10))
+	}
+
+	in, err := newOtherCreateParams(params)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+
+	out, err := s.api.Create(ctx, in)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	if out == nil {
+		return &apipb.OtherUser{}, nil
+	}
+
+	return &apipb.OtherUser{
+		Id:       out.ID,
+		Login:    out.Login,
+		FullName: out.FullName,
+		Level:    int64(out.Level),
+	}, nil
+}
+
+// grpcAuthorized is the gRPC counterpart of the X-Auth header check
+func grpcAuthorized(ctx context.Context) bool {
+	md, _ := metadata.FromIncomingContext(ctx)
+	for _, value := range md.Get("x-auth") {
+		if value == "100500" {
+			return true
+		}
+	}
+	return false
+}
+
+// grpcError converts errors of api methods and validators to gRPC statuses
+func grpcError(err error) error {
+	errApi, ok := err.(ApiError)
+	if !ok {
+		return status.Error(codes.Internal, err.Error())
+	}
+
+	code := codes.Unknown
+	switch errApi.HTTPStatus {
+	case http.StatusBadRequest:
+		code = codes.InvalidArgument
+	case http.StatusUnauthorized:
+		code = codes.Unauthenticated
+	case http.StatusForbidden:
+		code = codes.PermissionDenied
+	case http.StatusNotFound:
+		code = codes.NotFound
+	case http.StatusConflict:
+		code = codes.AlreadyExists
+	case http.StatusInternalServerError:
+		code = codes.Internal
+	}
+	return status.Error(code, errApi.Error())
+}
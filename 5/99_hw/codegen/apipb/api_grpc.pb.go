@@ -0,0 +1,227 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.2.0
+// - protoc             (unknown)
+// source: api.proto
+
+package apipb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+// MyApiClient is the client API for MyApi service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type MyApiClient interface {
+	Profile(ctx context.Context, in *ProfileParams, opts ...grpc.CallOption) (*User, error)
+	Create(ctx context.Context, in *CreateParams, opts ...grpc.CallOption) (*NewUser, error)
+}
+
+type myApiClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewMyApiClient(cc grpc.ClientConnInterface) MyApiClient {
+	return &myApiClient{cc}
+}
+
+func (c *myApiClient) Profile(ctx context.Context, in *ProfileParams, opts ...grpc.CallOption) (*User, error) {
+	out := new(User)
+	err := c.cc.Invoke(ctx, "/main.MyApi/Profile", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *myApiClient) Create(ctx context.Context, in *CreateParams, opts ...grpc.CallOption) (*NewUser, error) {
+	out := new(NewUser)
+	err := c.cc.Invoke(ctx, "/main.MyApi/Create", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// MyApiServer is the server API for MyApi service.
+// All implementations must embed UnimplementedMyApiServer
+// for forward compatibility
+type MyApiServer interface {
+	Profile(context.Context, *ProfileParams) (*User, error)
+	Create(context.Context, *CreateParams) (*NewUser, error)
+	mustEmbedUnimplementedMyApiServer()
+}
+
+// UnimplementedMyApiServer must be embedded to have forward compatible implementations.
+type UnimplementedMyApiServer struct {
+}
+
+func (UnimplementedMyApiServer) Profile(context.Context, *ProfileParams) (*User, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Profile not implemented")
+}
+func (UnimplementedMyApiServer) Create(context.Context, *CreateParams) (*NewUser, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Create not implemented")
+}
+func (UnimplementedMyApiServer) mustEmbedUnimplementedMyApiServer() {}
+
+// UnsafeMyApiServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to MyApiServer will
+// result in compilation errors.
+type UnsafeMyApiServer interface {
+	mustEmbedUnimplementedMyApiServer()
+}
+
+func RegisterMyApiServer(s grpc.ServiceRegistrar, srv MyApiServer) {
+	s.RegisterService(&MyApi_ServiceDesc, srv)
+}
+
+func _MyApi_Profile_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ProfileParams)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MyApiServer).Profile(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/main.MyApi/Profile",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MyApiServer).Profile(ctx, req.(*ProfileParams))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MyApi_Create_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateParams)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MyApiServer).Create(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/main.MyApi/Create",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MyApiServer).Create(ctx, req.(*CreateParams))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// MyApi_ServiceDesc is the grpc.ServiceDesc for MyApi service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var MyApi_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "main.MyApi",
+	HandlerType: (*MyApiServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Profile",
+			Handler:    _MyApi_Profile_Handler,
+		},
+		{
+			MethodName: "Create",
+			Handler:    _MyApi_Create_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "api.proto",
+}
+
+// OtherApiClient is the client API for OtherApi service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type OtherApiClient interface {
+	Create(ctx context.Context, in *OtherCreateParams, opts ...grpc.CallOption) (*OtherUser, error)
+}
+
+type otherApiClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewOtherApiClient(cc grpc.ClientConnInterface) OtherApiClient {
+	return &otherApiClient{cc}
+}
+
+func (c *otherApiClient) Create(ctx context.Context, in *OtherCreateParams, opts ...grpc.CallOption) (*OtherUser, error) {
+	out := new(OtherUser)
+	err := c.cc.Invoke(ctx, "/main.OtherApi/Create", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// OtherApiServer is the server API for OtherApi service.
+// All implementations must embed UnimplementedOtherApiServer
+// for forward compatibility
+type OtherApiServer interface {
+	Create(context.Context, *OtherCreateParams) (*OtherUser, error)
+	mustEmbedUnimplementedOtherApiServer()
+}
+
+// UnimplementedOtherApiServer must be embedded to have forward compatible implementations.
+type UnimplementedOtherApiServer struct {
+}
+
+func (UnimplementedOtherApiServer) Create(context.Context, *OtherCreateParams) (*OtherUser, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Create not implemented")
+}
+func (UnimplementedOtherApiServer) mustEmbedUnimplementedOtherApiServer() {}
+
+// UnsafeOtherApiServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to OtherApiServer will
+// result in compilation errors.
+type UnsafeOtherApiServer interface {
+	mustEmbedUnimplementedOtherApiServer()
+}
+
+func RegisterOtherApiServer(s grpc.ServiceRegistrar, srv OtherApiServer) {
+	s.RegisterService(&OtherApi_ServiceDesc, srv)
+}
+
+func _OtherApi_Create_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(OtherCreateParams)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(OtherApiServer).Create(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/main.OtherApi/Create",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(OtherApiServer).Create(ctx, req.(*OtherCreateParams))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// OtherApi_ServiceDesc is the grpc.ServiceDesc for OtherApi service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var OtherApi_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "main.OtherApi",
+	HandlerType: (*OtherApiServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Create",
+			Handler:    _OtherApi_Create_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "api.proto",
+}
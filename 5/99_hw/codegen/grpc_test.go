@@ -0,0 +1,151 @@
+package main
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/proto"
+
+	"codegenhw/apipb"
+)
+
+func startGRPC(t *testing.T) *grpc.ClientConn {
+	lis := bufconn.Listen(1024 * 1024)
+	server := grpc.NewServer()
+	apipb.RegisterMyApiServer(server, NewMyApiGRPC(NewMyApi()))
+	apipb.RegisterOtherApiServer(server, NewOtherApiGRPC(NewOtherApi()))
+	go server.Serve(lis)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatalf("cant connect to grpc: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+func TestMyApiGRPC(t *testing.T) {
+	conn := startGRPC(t)
+	myApi := apipb.NewMyApiClient(conn)
+	otherApi := apipb.NewOtherApiClient(conn)
+
+	ctx := context.Background()
+	authCtx := metadata.AppendToOutgoingContext(ctx, "x-auth", "100500")
+
+	user, err := myApi.Profile(ctx, &apipb.ProfileParams{Login: proto.String("rvasily")})
+	if err != nil {
+		t.Fatalf("Profile: unexpected error %v", err)
+	}
+	if user.GetId() != 42 || user.GetFullName() != "Vasily Romanov" || user.GetStatus() != 20 {
+		t.Errorf("Profile: bad user %v", user)
+	}
+
+	cases := []struct {
+		name    string
+		call    func() error
+		code    codes.Code
+		message string
+	}{
+		{
+			name: "required",
+			call: func() error {
+				_, err := myApi.Profile(ctx, &apipb.ProfileParams{})
+				return err
+			},
+			code:    codes.InvalidArgument,
+			message: "login must me not empty",
+		},
+		{
+			name: "not found",
+			call: func() error {
+				_, err := myApi.Profile(ctx, &apipb.ProfileParams{Login: proto.String("not_exist_user")})
+				return err
+			},
+			code:    codes.NotFound,
+			message: "user not exist",
+		},
+		{
+			name: "internal",
+			call: func() error {
+				_, err := myApi.Profile(ctx, &apipb.ProfileParams{Login: proto.String("bad_user")})
+				return err
+			},
+			code:    codes.Internal,
+			message: "bad user",
+		},
+		{
+			name: "auth",
+			call: func() error {
+				_, err := myApi.Create(ctx, &apipb.CreateParams{Login: proto.String("mr.moderator")})
+				return err
+			},
+			code:    codes.Unauthenticated,
+			message: "unauthorized",
+		},
+		{
+			name: "enum",
+			call: func() error {
+				_, err := myApi.Create(authCtx, &apipb.CreateParams{
+					Login:  proto.String("mr.moderator"),
+					Status: proto.String("adm"),
+					Age:    proto.Int64(32),
+				})
+				return err
+			},
+			code:    codes.InvalidArgument,
+			message: "status must be one of [user, moderator, admin]",
+		},
+		{
+			name: "max",
+			call: func() error {
+				_, err := otherApi.Create(authCtx, &apipb.OtherCreateParams{
+					Username: proto.String("moderator"),
+					Level:    proto.Int64(51),
+				})
+				return err
+			},
+			code:    codes.InvalidArgument,
+			message: "level must be <= 50",
+		},
+	}
+	for _, c := range cases {
+		st, _ := status.FromError(c.call())
+		if st.Code() != c.code || st.Message() != c.message {
+			t.Errorf("[%s] expected %s %q, got %s %q", c.name, c.code, c.message, st.Code(), st.Message())
+		}
+	}
+
+	created, err := myApi.Create(authCtx, &apipb.CreateParams{
+		Login:    proto.String("mr.moderator"),
+		FullName: proto.String("Ivan Ivanov"),
+		Status:   proto.String("moderator"),
+		Age:      proto.Int64(32),
+	})
+	if err != nil {
+		t.Fatalf("Create: unexpected error %v", err)
+	}
+	if created.GetId() != 43 {
+		t.Errorf("Create: expected id 43, got %d", created.GetId())
+	}
+
+	user, err = myApi.Profile(ctx, &apipb.ProfileParams{Login: proto.String("mr.moderator")})
+	if err != nil {
+		t.Fatalf("Profile: unexpected error %v", err)
+	}
+	if user.GetFullName() != "Ivan Ivanov" || user.GetStatus() != 10 {
+		t.Errorf("Profile: bad user %v", user)
+	}
+}
@@ -1,33 +1,61 @@
 package main
 
 import (
+	"bytes"
 	"encoding/json"
+	"flag"
 	"fmt"
 	"go/ast"
-	"go/parser"
+	"go/format"
 	"go/token"
+	"go/types"
+	"io"
 	"log"
 	"os"
-	"regexp"
+	pathpkg "path"
+	"reflect"
+	"sort"
 	"strconv"
 	"strings"
 	"text/template"
+
+	"golang.org/x/tools/go/packages"
 )
 
 type Parser struct {
-	ApiPrefix      string
-	MatchValidator regexp.Regexp
+	ApiPrefix    string
+	ValidatorTag string
+
+	fset        *token.FileSet
+	pkg         *types.Package
+	result      *ParsedFile
+	Diagnostics []Diagnostic
+}
+
+// Diagnostic is a problem found in the parsed sources, reported as file:line:col
+type Diagnostic struct {
+	Pos     token.Position
+	Message string
+}
+
+func (d Diagnostic) String() string {
+	if !d.Pos.IsValid() {
+		return d.Message
+	}
+	return fmt.Sprintf("%s: %s", d.Pos, d.Message)
 }
 
 type CodeGenerator struct {
 	InputFile  *ParsedFile
-	OutputFile *os.File
+	OutputFile io.Writer
 }
 
 type ParsedFile struct {
 	PackageName string
+	Imports     map[string]string
 	ApiHandler  map[string]ApiHandler
 	ApiStructs  map[string]ApiStruct
+	ApiResults  map[string]ApiResult
 }
 
 type ApiHandler struct {
@@ -39,6 +67,8 @@ type ApiMethod struct {
 	Name        string
 	HandlerName string
 	RequestName string
+	ResultName  string
+	ResultPtr   bool
 	Api         ApiMetaInformation
 }
 
@@ -49,14 +79,34 @@ type ApiMetaInformation struct {
 }
 
 type ApiStruct struct {
+	Name     string
+	TypeName string
+	Fields   []StructField
+}
+
+// ApiResult is the struct an api method returns, it is only needed
+// to describe the method outside of net/http, e.g. in protobuf
+type ApiResult struct {
 	Name   string
-	Fields []StructField
+	Fields []ResultField
+}
+
+type ResultField struct {
+	Name     string
+	JSONName string
+	Type     string
+	// ProtoNumber is the protobuf field number from the proto tag, 0 if unset
+	ProtoNumber int
+	Pos         token.Position
 }
 
 type StructField struct {
 	Name            string
 	Type            string
 	StructValueTags structValueTag
+	// ProtoNumber is the protobuf field number from the proto tag, 0 if unset
+	ProtoNumber int
+	Pos         token.Position
 }
 
 type structValueTag struct {
@@ -70,162 +120,475 @@ type structValueTag struct {
 	Default   string
 }
 
+func usage() {
+	fmt.Fprintf(os.Stderr, "usage: handlers_gen [-output file] [-proto file -grpc-output file -proto-go-package path] [package|file.go ...]\n")
+	fmt.Fprintf(os.Stderr, "\tgo:generate mode: //go:generate go run ./handlers_gen -output api_handlers.go\n")
+	flag.PrintDefaults()
+}
+
 func main() {
-	inFile, outFile := os.Args[1], os.Args[2]
-	parser := NewParser("// apigen:api", "`apivalidator:\"(.*)\"`")
-	parsedInFile, err := parser.Parse(inFile)
-	if err != nil {
-		log.Fatalf("Error happened: %s\n", err)
+	log.SetFlags(0)
+	log.SetPrefix("handlers_gen: ")
+
+	output := flag.String("output", "", "output file name; default api_handlers.go")
+	protoOutput := flag.String("proto", "", "write the gRPC service definition of the api to this .proto file; params and result fields need proto:\"N\" tags")
+	grpcOutput := flag.String("grpc-output", "", "write the go adapter serving the api over gRPC to this file")
+	protoGoPackage := flag.String("proto-go-package", "", "import path of the code protoc generates from the .proto file")
+	flag.Usage = usage
+	flag.Parse()
+
+	patterns := flag.Args()
+	// legacy form: handlers_gen api.go api_handlers.go
+	if *output == "" && len(patterns) == 2 && strings.HasSuffix(patterns[1], ".go") {
+		*output, patterns = patterns[1], patterns[:1]
+	}
+	if *output == "" {
+		*output = "api_handlers.go"
+	}
+	if len(patterns) == 0 {
+		patterns = []string{"."}
+	}
+	for i, pattern := range patterns {
+		if strings.HasSuffix(pattern, ".go") {
+			patterns[i] = "file=" + pattern
+		}
+	}
+
+	parser := NewParser("// apigen:api", "apivalidator")
+	parsedInFile, err := parser.Parse(patterns...)
+	for _, d := range parser.Diagnostics {
+		fmt.Fprintln(os.Stderr, d)
 	}
-	out, err := os.Create(outFile)
 	if err != nil {
-		log.Fatalf("Error creating file: %s", err)
+		log.Fatalf("Error happened: %s", err)
+	}
+
+	buf := &bytes.Buffer{}
+	codeGenerator := NewCodeGenerator(parsedInFile, buf)
+	if err := codeGenerator.Generate(); err != nil {
+		log.Fatalf("Error generating code: %s", err)
+	}
+	writeGoFile(*output, buf)
+
+	if *protoOutput == "" && *grpcOutput == "" {
+		return
+	}
+	if *protoGoPackage == "" {
+		log.Fatalf("-proto-go-package is required to generate gRPC code")
 	}
-	defer out.Close()
 
-	codeGenerator := NewCodeGenerator(parsedInFile, out)
-	codeGenerator.Generate()
+	protoGenerator := NewProtoGenerator(parsedInFile, *protoGoPackage)
+	if diagnostics := protoGenerator.Prepare(); len(diagnostics) > 0 {
+		for _, d := range diagnostics {
+			fmt.Fprintln(os.Stderr, d)
+		}
+		log.Fatalf("Error happened: api can't be described in protobuf")
+	}
+
+	if *protoOutput != "" {
+		buf := &bytes.Buffer{}
+		if err := protoGenerator.GenerateProto(buf); err != nil {
+			log.Fatalf("Error generating proto: %s", err)
+		}
+		if err := os.WriteFile(*protoOutput, buf.Bytes(), 0644); err != nil {
+			log.Fatalf("Error writing file: %s", err)
+		}
+	}
+
+	if *grpcOutput != "" {
+		buf := &bytes.Buffer{}
+		if err := protoGenerator.GenerateAdapter(buf); err != nil {
+			log.Fatalf("Error generating gRPC adapter: %s", err)
+		}
+		writeGoFile(*grpcOutput, buf)
+	}
+}
+
+func writeGoFile(name string, buf *bytes.Buffer) {
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		// write the unformatted code anyway, so it can be inspected
+		src = buf.Bytes()
+		log.Printf("Error formatting %s: %s", name, err)
+	}
+	if err := os.WriteFile(name, src, 0644); err != nil {
+		log.Fatalf("Error writing file: %s", err)
+	}
 }
 
-func NewParser(APIPrefix, APIValidator string) *Parser {
+func NewParser(APIPrefix, ValidatorTag string) *Parser {
 	return &Parser{
-		ApiPrefix:      APIPrefix,
-		MatchValidator: *regexp.MustCompile(APIValidator),
+		ApiPrefix:    APIPrefix,
+		ValidatorTag: ValidatorTag,
 	}
 }
 
-func (p *Parser) GetFunctionReceiver(node *ast.FuncDecl) string {
-	if node.Recv != nil {
-		for _, field := range node.Recv.List {
-			if f, ok := field.Type.(*ast.StarExpr); ok {
-				if fi, ok := f.X.(*ast.Ident); ok {
-					return fi.Name
-				}
-			}
+func (p *Parser) errorf(pos token.Pos, format string, args ...interface{}) {
+	p.Diagnostics = append(p.Diagnostics, Diagnostic{
+		Pos:     p.fset.Position(pos),
+		Message: fmt.Sprintf(format, args...),
+	})
+}
 
-			if fi, ok := field.Type.(*ast.Ident); ok {
-				return fi.Name
+// qualifier returns the name the generated file uses for pkg,
+// remembering every foreign package so it gets imported
+func (p *Parser) qualifier(pkg *types.Package) string {
+	if pkg == p.pkg {
+		return ""
+	}
+	p.result.Imports[pkg.Path()] = pkg.Name()
+	return pkg.Name()
+}
+
+func (p *Parser) ParseFunc(decl *ast.FuncDecl, info *types.Info) {
+	if decl.Doc == nil || decl.Recv == nil {
+		return
+	}
+
+	var meta ApiMetaInformation
+	var found bool
+	for _, comment := range decl.Doc.List {
+		if strings.HasPrefix(comment.Text, p.ApiPrefix) {
+			found = true
+			jsonStr := comment.Text[len(p.ApiPrefix):]
+			if err := json.Unmarshal([]byte(jsonStr), &meta); err != nil {
+				p.errorf(comment.Pos(), "bad %s annotation on %s: %s", strings.TrimPrefix(p.ApiPrefix, "// "), decl.Name.Name, err)
+				return
 			}
+		}
+	}
+	if !found {
+		return
+	}
+	if meta.URL == "" {
+		p.errorf(decl.Pos(), "%s: api annotation has no url", decl.Name.Name)
+		return
+	}
+
+	fn, ok := info.Defs[decl.Name].(*types.Func)
+	if !ok {
+		p.errorf(decl.Pos(), "%s: no type information", decl.Name.Name)
+		return
+	}
+	sig := fn.Type().(*types.Signature)
+
+	recv := sig.Recv().Type()
+	if ptr, ok := recv.(*types.Pointer); ok {
+		recv = ptr.Elem()
+	}
+	receiver, ok := recv.(*types.Named)
+	if !ok {
+		p.errorf(decl.Recv.Pos(), "%s: unsupported receiver type %s", decl.Name.Name, recv)
+		return
+	}
+
+	params := sig.Params()
+	if params.Len() != 2 || types.TypeString(params.At(0).Type(), nil) != "context.Context" {
+		p.errorf(decl.Type.Params.Pos(), "%s: api method must have signature func(context.Context, Params) (Result, error)", decl.Name.Name)
+		return
+	}
+	reqType, ok := params.At(1).Type().(*types.Named)
+	if !ok {
+		p.errorf(params.At(1).Pos(), "%s: params must be a named struct type, got %s", decl.Name.Name, params.At(1).Type())
+		return
+	}
+	apiStruct, ok := p.ParseStruct(reqType)
+	if !ok {
+		return
+	}
+
+	results := sig.Results()
+	if results.Len() != 2 || types.TypeString(results.At(1).Type(), nil) != "error" {
+		p.errorf(decl.Type.Pos(), "%s: api method must have signature func(context.Context, Params) (Result, error)", decl.Name.Name)
+		return
+	}
+	resultType, pointer := results.At(0).Type(), false
+	if ptr, ok := resultType.(*types.Pointer); ok {
+		resultType, pointer = ptr.Elem(), true
+	}
+	apiResult, ok := p.ParseResult(results.At(0).Pos(), resultType)
+	if !ok {
+		return
+	}
 
+	name := receiver.Obj().Name()
+	handler, exists := p.result.ApiHandler[name]
+	if !exists {
+		handler = ApiHandler{Name: name}
+	}
+	for _, method := range handler.ApiMethods {
+		if method.Api.URL == meta.URL {
+			p.errorf(decl.Pos(), "%s: url %s is already served by %s.%s", decl.Name.Name, meta.URL, name, method.Name)
+			return
 		}
 	}
-	return ""
+	handler.ApiMethods = append(handler.ApiMethods, ApiMethod{
+		Name:        decl.Name.Name,
+		HandlerName: name,
+		RequestName: apiStruct.Name,
+		ResultName:  apiResult.Name,
+		ResultPtr:   pointer,
+		Api:         meta,
+	})
+	p.result.ApiHandler[name] = handler
 }
 
-func (p *Parser) ParseFunc(file *ParsedFile, decl *ast.FuncDecl) {
-	if decl.Doc != nil {
-		var meta ApiMetaInformation
-		for _, comment := range decl.Doc.List {
-			if strings.HasPrefix(comment.Text, p.ApiPrefix) {
-				jsonStr := comment.Text[len(p.ApiPrefix):]
-				if err := json.Unmarshal([]byte(jsonStr), &meta); err != nil {
-					break
-				}
-			}
+// ParseStruct collects apivalidator fields of a params struct, which may
+// be declared in any file of the package or in an imported package
+func (p *Parser) ParseStruct(named *types.Named) (ApiStruct, bool) {
+	key := named.Obj().Pkg().Path() + "." + named.Obj().Name()
+	if apiStruct, exists := p.result.ApiStructs[key]; exists {
+		return apiStruct, true
+	}
+
+	st, ok := named.Underlying().(*types.Struct)
+	if !ok {
+		p.errorf(named.Obj().Pos(), "%s: params must be a struct, got %s", named.Obj().Name(), named.Underlying())
+		return ApiStruct{}, false
+	}
+
+	apiStruct := ApiStruct{
+		Name:     named.Obj().Name(),
+		TypeName: types.TypeString(named, p.qualifier),
+	}
+	if named.Obj().Pkg() != p.pkg {
+		pkgName := named.Obj().Pkg().Name()
+		apiStruct.Name = strings.ToUpper(pkgName[:1]) + pkgName[1:] + apiStruct.Name
+	}
+
+	valid := true
+	for i := 0; i < st.NumFields(); i++ {
+		field := st.Field(i)
+		tag, ok := reflect.StructTag(st.Tag(i)).Lookup(p.ValidatorTag)
+		if !ok {
+			continue
+		}
+		if !field.Exported() && field.Pkg() != p.pkg {
+			p.errorf(field.Pos(), "%s.%s: field is not exported", apiStruct.Name, field.Name())
+			valid = false
+			continue
 		}
 
-		if meta.URL != "" {
-			if receiver := p.GetFunctionReceiver(decl); receiver != "" {
-				if _, exists := file.ApiHandler[receiver]; !exists {
-					file.ApiHandler[receiver] = ApiHandler{
-						Name: receiver,
-					}
-				}
-
-				if reqType, ok := decl.Type.Params.List[1].Type.(*ast.Ident); ok {
-					handler := file.ApiHandler[receiver]
-					handler.ApiMethods = append(handler.ApiMethods, ApiMethod{
-						Name:        decl.Name.Name,
-						HandlerName: receiver,
-						RequestName: reqType.Name,
-						Api:         meta,
-					})
-					file.ApiHandler[receiver] = handler
-				}
-			}
+		var fieldType string
+		switch {
+		case types.Identical(field.Type(), types.Typ[types.Int]):
+			fieldType = "int"
+		case types.Identical(field.Type(), types.Typ[types.String]):
+			fieldType = "string"
+		default:
+			p.errorf(field.Pos(), "%s.%s: unsupported field type %s, want int or string", apiStruct.Name, field.Name(), field.Type())
+			valid = false
+			continue
 		}
 
+		fieldTag, ok := p.parseTag(field, tag)
+		if !ok {
+			valid = false
+			continue
+		}
+		if _, err := strconv.Atoi(fieldTag.Default); fieldType == "int" && fieldTag.Default != "" && err != nil {
+			p.errorf(field.Pos(), "%s.%s: default %q is not an int", apiStruct.Name, field.Name(), fieldTag.Default)
+			valid = false
+			continue
+		}
+		protoNumber, ok := p.parseProtoTag(apiStruct.Name, field, st.Tag(i))
+		if !ok {
+			valid = false
+			continue
+		}
+		apiStruct.Fields = append(apiStruct.Fields, StructField{
+			Name:            field.Name(),
+			Type:            fieldType,
+			StructValueTags: fieldTag,
+			ProtoNumber:     protoNumber,
+			Pos:             p.fset.Position(field.Pos()),
+		})
+	}
+	if !valid {
+		return ApiStruct{}, false
 	}
+
+	p.result.ApiStructs[key] = apiStruct
+	return apiStruct, true
 }
 
-func (p *Parser) ParseStruct(file *ParsedFile, structName string, tt *ast.StructType) {
-	for _, field := range tt.Fields.List {
-		if field.Tag != nil {
-			if matches := p.MatchValidator.FindStringSubmatch(field.Tag.Value); len(matches) > 0 {
-				if _, exists := file.ApiStructs[structName]; !exists {
-					file.ApiStructs[structName] = ApiStruct{
-						Name: structName,
-					}
-				}
-
-				fieldTag := structValueTag{
-					ParamName: strings.ToLower(field.Names[0].Name),
-				}
-
-				structFieldTags := strings.Split(matches[1], ",")
-				for _, structFieldTag := range structFieldTags {
-					t := strings.Split(structFieldTag, "=")
-					switch t[0] {
-					case "required":
-						fieldTag.Required = true
-					case "min":
-						fieldTag.Min = true
-						fieldTag.MinValue, _ = strconv.Atoi(t[1])
-					case "max":
-						fieldTag.Max = true
-						fieldTag.MaxValue, _ = strconv.Atoi(t[1])
-					case "paramname":
-						fieldTag.ParamName = t[1]
-					case "enum":
-						fieldTag.Enum = strings.Split(t[1], "|")
-					case "default":
-						fieldTag.Default = t[1]
-					}
-				}
-				currStruct := file.ApiStructs[structName]
-				currStruct.Fields = append(currStruct.Fields, StructField{
-					Name:            field.Names[0].Name,
-					Type:            field.Type.(*ast.Ident).Name,
-					StructValueTags: fieldTag,
-				})
-				file.ApiStructs[structName] = currStruct
+// ParseResult collects exported fields of a result struct, they are named
+// after their json tags, exactly like the http handlers encode them
+func (p *Parser) ParseResult(pos token.Pos, typ types.Type) (ApiResult, bool) {
+	named, ok := typ.(*types.Named)
+	if !ok {
+		p.errorf(pos, "result must be a named struct type or a pointer to it, got %s", typ)
+		return ApiResult{}, false
+	}
+	st, ok := named.Underlying().(*types.Struct)
+	if !ok {
+		p.errorf(named.Obj().Pos(), "%s: result must be a struct, got %s", named.Obj().Name(), named.Underlying())
+		return ApiResult{}, false
+	}
 
+	key := named.Obj().Pkg().Path() + "." + named.Obj().Name()
+	if apiResult, exists := p.result.ApiResults[key]; exists {
+		return apiResult, true
+	}
+
+	apiResult := ApiResult{
+		Name: named.Obj().Name(),
+	}
+	if named.Obj().Pkg() != p.pkg {
+		pkgName := named.Obj().Pkg().Name()
+		apiResult.Name = strings.ToUpper(pkgName[:1]) + pkgName[1:] + apiResult.Name
+	}
+
+	valid := true
+	for i := 0; i < st.NumFields(); i++ {
+		field := st.Field(i)
+		if !field.Exported() || field.Embedded() {
+			continue
+		}
+		jsonName := field.Name()
+		if tag, ok := reflect.StructTag(st.Tag(i)).Lookup("json"); ok {
+			if name := strings.Split(tag, ",")[0]; name == "-" {
+				continue
+			} else if name != "" {
+				jsonName = name
 			}
 		}
+		protoNumber, ok := p.parseProtoTag(apiResult.Name, field, st.Tag(i))
+		if !ok {
+			valid = false
+			continue
+		}
+		apiResult.Fields = append(apiResult.Fields, ResultField{
+			Name:        field.Name(),
+			JSONName:    jsonName,
+			Type:        types.TypeString(field.Type(), types.RelativeTo(p.pkg)),
+			ProtoNumber: protoNumber,
+			Pos:         p.fset.Position(field.Pos()),
+		})
+	}
+	if !valid {
+		return ApiResult{}, false
+	}
+
+	p.result.ApiResults[key] = apiResult
+	return apiResult, true
+}
+
+// parseProtoTag reads the protobuf field number from the proto tag, the
+// number must not depend on the field order, or reordering the struct
+// would silently change the wire format
+func (p *Parser) parseProtoTag(structName string, field *types.Var, tag string) (int, bool) {
+	value, ok := reflect.StructTag(tag).Lookup("proto")
+	if !ok {
+		return 0, true
+	}
+	number, err := strconv.Atoi(value)
+	switch {
+	case err != nil || number < 1 || number > maxProtoNumber:
+		p.errorf(field.Pos(), "%s.%s: proto field number %q must be an int from 1 to %d", structName, field.Name(), value, maxProtoNumber)
+		return 0, false
+	case number >= 19000 && number <= 19999:
+		p.errorf(field.Pos(), "%s.%s: proto field numbers 19000-19999 are reserved by protobuf", structName, field.Name())
+		return 0, false
 	}
+	return number, true
 }
 
-func (p *Parser) Parse(inFile string) (*ParsedFile, error) {
-	fs := token.NewFileSet()
-	nodes, err := parser.ParseFile(fs, inFile, nil, parser.ParseComments)
+func (p *Parser) parseTag(field *types.Var, tag string) (structValueTag, bool) {
+	fieldTag := structValueTag{
+		ParamName: strings.ToLower(field.Name()),
+	}
+
+	valid := true
+	for _, structFieldTag := range strings.Split(tag, ",") {
+		t := strings.SplitN(structFieldTag, "=", 2)
+		if len(t) == 1 {
+			t = append(t, "")
+		}
+
+		var err error
+		switch t[0] {
+		case "required":
+			fieldTag.Required = true
+		case "min":
+			fieldTag.Min = true
+			fieldTag.MinValue, err = strconv.Atoi(t[1])
+		case "max":
+			fieldTag.Max = true
+			fieldTag.MaxValue, err = strconv.Atoi(t[1])
+		case "paramname":
+			fieldTag.ParamName = t[1]
+		case "enum":
+			fieldTag.Enum = strings.Split(t[1], "|")
+		case "default":
+			fieldTag.Default = t[1]
+		default:
+			err = fmt.Errorf("unknown option")
+		}
+		if err != nil {
+			p.errorf(field.Pos(), "%s: bad %s option %q: %s", field.Name(), p.ValidatorTag, structFieldTag, err)
+			valid = false
+		}
+	}
+	return fieldTag, valid
+}
+
+// Parse loads the package matched by patterns with full type information,
+// so api methods and their params may live in different files or packages
+func (p *Parser) Parse(patterns ...string) (*ParsedFile, error) {
+	// dependencies are type checked from source: export data of a toolchain
+	// newer than x/tools can't be read and would leave imports without types
+	cfg := &packages.Config{
+		Mode: packages.NeedName | packages.NeedFiles | packages.NeedSyntax |
+			packages.NeedImports | packages.NeedDeps | packages.NeedTypes | packages.NeedTypesInfo,
+	}
+	pkgs, err := packages.Load(cfg, patterns...)
 	if err != nil {
-		fmt.Errorf("parsing error: %s\n", err)
+		return nil, fmt.Errorf("loading packages: %w", err)
 	}
+	if len(pkgs) != 1 {
+		return nil, fmt.Errorf("expected exactly one package for %v, got %d", patterns, len(pkgs))
+	}
+	pkg := pkgs[0]
 
-	result := &ParsedFile{
-		PackageName: nodes.Name.Name,
+	p.fset = pkg.Fset
+	p.pkg = pkg.Types
+	p.result = &ParsedFile{
+		PackageName: pkg.Name,
+		Imports:     make(map[string]string),
 		ApiHandler:  make(map[string]ApiHandler),
 		ApiStructs:  make(map[string]ApiStruct),
+		ApiResults:  make(map[string]ApiResult),
+	}
+
+	// only syntax errors are fatal: type errors are expected as long as the
+	// handlers this tool generates are missing or stale
+	for _, e := range pkg.Errors {
+		if e.Kind == packages.ParseError {
+			p.Diagnostics = append(p.Diagnostics, Diagnostic{Message: e.Error()})
+		}
+	}
+	if len(p.Diagnostics) > 0 {
+		return nil, fmt.Errorf("package %s has syntax errors", pkg.PkgPath)
 	}
 
-	for _, decl := range nodes.Decls {
-		switch decl.(type) {
-		case *ast.FuncDecl:
-			p.ParseFunc(result, decl.(*ast.FuncDecl))
-		case *ast.GenDecl:
-			for _, t := range decl.(*ast.GenDecl).Specs {
-				if tt, ok := t.(*ast.TypeSpec); ok {
-					if ttt, ok := tt.Type.(*ast.StructType); ok {
-						p.ParseStruct(result, tt.Name.Name, ttt)
-					}
-				}
+	for _, file := range pkg.Syntax {
+		if ast.IsGenerated(file) {
+			continue
+		}
+		for _, decl := range file.Decls {
+			if funcDecl, ok := decl.(*ast.FuncDecl); ok {
+				p.ParseFunc(funcDecl, pkg.TypesInfo)
 			}
 		}
 	}
-	return result, nil
+	if len(p.Diagnostics) > 0 {
+		return nil, fmt.Errorf("%d problem(s) found in package %s", len(p.Diagnostics), pkg.PkgPath)
+	}
+	return p.result, nil
 }
 
-func NewCodeGenerator(parsedFile *ParsedFile, out *os.File) *CodeGenerator {
+func NewCodeGenerator(parsedFile *ParsedFile, out io.Writer) *CodeGenerator {
 	return &CodeGenerator{
 		InputFile:  parsedFile,
 		OutputFile: out,
@@ -233,18 +596,32 @@ func NewCodeGenerator(parsedFile *ParsedFile, out *os.File) *CodeGenerator {
 }
 
 func (c *CodeGenerator) WriteHeader() {
-	c.OutputFile.WriteString("// Code generated by go generate; DO NOT EDIT\n")
+	io.WriteString(c.OutputFile, "// Code generated by handlers_gen. DO NOT EDIT.\n\n")
 	fmt.Fprintf(c.OutputFile, `package %s
-		import (
-		"encoding/json"
-		"fmt"
-		"io/ioutil"
-		"net/http"
-		"net/url"
-		"strconv"
-		"strings"
-	)`, c.InputFile.PackageName)
 
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+`, c.InputFile.PackageName)
+
+	paths := make([]string, 0, len(c.InputFile.Imports))
+	for path := range c.InputFile.Imports {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		if name := c.InputFile.Imports[path]; name != pathpkg.Base(path) {
+			fmt.Fprintf(c.OutputFile, "\t%s %q\n", name, path)
+		} else {
+			fmt.Fprintf(c.OutputFile, "\t%q\n", path)
+		}
+	}
+	io.WriteString(c.OutputFile, ")\n")
 }
 
 func (c *CodeGenerator) generateServe() *template.Template {
@@ -288,13 +665,23 @@ func (h *{{ .Name }}) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 
 func (c *CodeGenerator) generateStructValidation() *template.Template {
 	return template.Must(template.New("validatorTpl").Parse(`
-func new{{ .Name }}(v url.Values) ({{ .Name }}, error) {
+func new{{ .Name }}(v url.Values) ({{ .TypeName }}, error) {
 	var err error
-	s := {{ .Name }}{}
+	s := {{ .TypeName }}{}
 
 	{{ range .Fields }}// {{ .Name }}
 	
 	{{- if eq .Type "int" }}
+	{{- if .StructValueTags.Default }}
+	if v.Get("{{ .StructValueTags.ParamName }}") == "" {
+		v.Set("{{ .StructValueTags.ParamName }}", "{{ .StructValueTags.Default }}")
+	}
+	{{- end }}
+	{{- if .StructValueTags.Required }}
+	if v.Get("{{ .StructValueTags.ParamName }}") == "" {
+		return s, ApiError{http.StatusBadRequest, fmt.Errorf("{{ .StructValueTags.ParamName }} must me not empty")}
+	}
+	{{- end }}
 	s.{{ .Name }}, err = strconv.Atoi(v.Get("{{ .StructValueTags.ParamName }}"))
 	if err != nil {
 		return s, ApiError{http.StatusBadRequest, fmt.Errorf("{{ .StructValueTags.ParamName }} must be int")}
@@ -305,14 +692,14 @@ func new{{ .Name }}(v url.Values) ({{ .Name }}, error) {
 
 	{{ end -}}
 
-	{{- if .StructValueTags.Default -}}
+	{{- if and .StructValueTags.Default (eq .Type "string") -}}
 	if s.{{ .Name }} == "" {
 		s.{{ .Name }} = "{{ .StructValueTags.Default }}"
 	}
 
 	{{ end -}}
 
-	{{- if .StructValueTags.Required -}}
+	{{- if and .StructValueTags.Required (eq .Type "string") -}}
 	if s.{{ .Name }} == "" {
 		return s, ApiError{http.StatusBadRequest, fmt.Errorf("{{ .StructValueTags.ParamName }} must me not empty")}
 	}
@@ -405,20 +792,40 @@ func (h *{{ .HandlerName }}) wrapper{{ .Name }}(w http.ResponseWriter, r *http.R
 `))
 }
 
-func (c *CodeGenerator) Generate() {
+func (c *CodeGenerator) Generate() error {
 	c.WriteHeader()
 	ServeTmpl := c.generateServe()
 	WrapperTmpl := c.generateWrapper()
 	ValidationTmpl := c.generateStructValidation()
 
-	for _, handler := range c.InputFile.ApiHandler {
-		ServeTmpl.Execute(c.OutputFile, handler)
+	handlerNames := make([]string, 0, len(c.InputFile.ApiHandler))
+	for name := range c.InputFile.ApiHandler {
+		handlerNames = append(handlerNames, name)
+	}
+	sort.Strings(handlerNames)
+
+	for _, name := range handlerNames {
+		handler := c.InputFile.ApiHandler[name]
+		if err := ServeTmpl.Execute(c.OutputFile, handler); err != nil {
+			return err
+		}
 		for _, method := range handler.ApiMethods {
-			WrapperTmpl.Execute(c.OutputFile, method)
+			if err := WrapperTmpl.Execute(c.OutputFile, method); err != nil {
+				return err
+			}
 		}
 	}
 
-	for _, apiStruct := range c.InputFile.ApiStructs {
-		ValidationTmpl.Execute(c.OutputFile, apiStruct)
+	structKeys := make([]string, 0, len(c.InputFile.ApiStructs))
+	for key := range c.InputFile.ApiStructs {
+		structKeys = append(structKeys, key)
+	}
+	sort.Strings(structKeys)
+
+	for _, key := range structKeys {
+		if err := ValidationTmpl.Execute(c.OutputFile, c.InputFile.ApiStructs[key]); err != nil {
+			return err
+		}
 	}
+	return nil
 }
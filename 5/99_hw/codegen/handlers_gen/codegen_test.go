@@ -0,0 +1,121 @@
+package main
+
+import (
+	"go/token"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func parse(t *testing.T, pattern string) (*ParsedFile, *Parser, error) {
+	t.Helper()
+	parser := NewParser("// apigen:api", "apivalidator")
+	parsed, err := parser.Parse(pattern)
+	return parsed, parser, err
+}
+
+// checkDiagnostics expects exactly one diagnostic per want, in order,
+// each pointing to file:line and containing the message
+func checkDiagnostics(t *testing.T, got []Diagnostic, want []Diagnostic) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("expected %d diagnostics, got %d: %v", len(want), len(got), got)
+	}
+	for i, w := range want {
+		g := got[i]
+		if filepath.Base(g.Pos.Filename) != w.Pos.Filename || g.Pos.Line != w.Pos.Line {
+			t.Errorf("diagnostic %d: expected position %s:%d, got %s", i, w.Pos.Filename, w.Pos.Line, g)
+		}
+		if !strings.Contains(g.Message, w.Message) {
+			t.Errorf("diagnostic %d: expected message containing %q, got %q", i, w.Message, g.Message)
+		}
+	}
+}
+
+func diag(file string, line int, message string) Diagnostic {
+	d := Diagnostic{Message: message}
+	d.Pos.Filename, d.Pos.Line = file, line
+	return d
+}
+
+func TestParseBadTag(t *testing.T) {
+	parsed, parser, err := parse(t, "./testdata/badtag")
+	if err == nil {
+		t.Fatalf("expected an error, got %+v", parsed)
+	}
+	checkDiagnostics(t, parser.Diagnostics, []Diagnostic{
+		diag("api.go", 8, `Age: bad apivalidator option "min=ten"`),
+		diag("api.go", 9, `Login: bad apivalidator option "size=10": unknown option`),
+		diag("api.go", 10, `Params.Level: proto field number "0" must be an int from 1 to 536870911`),
+		diag("api.go", 11, "Params.Class: proto field numbers 19000-19999 are reserved by protobuf"),
+	})
+}
+
+func TestParseUnknownParamType(t *testing.T) {
+	parsed, parser, err := parse(t, "./testdata/badtype")
+	if err == nil {
+		t.Fatalf("expected an error, got %+v", parsed)
+	}
+	checkDiagnostics(t, parser.Diagnostics, []Diagnostic{
+		diag("api.go", 9, "Params.Weight: unsupported field type float64, want int or string"),
+	})
+}
+
+func TestParseSplitPackage(t *testing.T) {
+	parsed, parser, err := parse(t, "./testdata/split")
+	if err != nil {
+		t.Fatalf("unexpected error %v: %v", err, parser.Diagnostics)
+	}
+
+	const paramsPkg = "codegenhw/handlers_gen/testdata/split/params"
+	if name := parsed.Imports[paramsPkg]; name != "params" {
+		t.Errorf("expected %s to be imported as params, got %v", paramsPkg, parsed.Imports)
+	}
+
+	expectedStructs := map[string]ApiStruct{
+		"codegenhw/handlers_gen/testdata/split.ProfileParams": {
+			Name:     "ProfileParams",
+			TypeName: "ProfileParams",
+			Fields: []StructField{
+				{Name: "Login", Type: "string", StructValueTags: structValueTag{ParamName: "login", Required: true}},
+			},
+		},
+		paramsPkg + ".CreateParams": {
+			Name:     "ParamsCreateParams",
+			TypeName: "params.CreateParams",
+			Fields: []StructField{
+				{Name: "Login", Type: "string", StructValueTags: structValueTag{ParamName: "login", Required: true, Min: true, MinValue: 3}},
+				{Name: "Age", Type: "int", StructValueTags: structValueTag{ParamName: "age", Min: true, Max: true, MaxValue: 128, Default: "18"}},
+			},
+		},
+	}
+	// params are declared next to the api and in the imported package
+	for key, apiStruct := range parsed.ApiStructs {
+		for i, field := range apiStruct.Fields {
+			if file := filepath.Base(field.Pos.Filename); file != "types.go" && file != "params.go" {
+				t.Errorf("%s.%s: unexpected position %s", key, field.Name, field.Pos)
+			}
+			apiStruct.Fields[i].Pos = token.Position{}
+		}
+	}
+	if !reflect.DeepEqual(parsed.ApiStructs, expectedStructs) {
+		t.Errorf("bad params structs\nexpected %+v\ngot      %+v", expectedStructs, parsed.ApiStructs)
+	}
+
+	var results []string
+	for _, key := range sortedKeys(parsed.ApiResults) {
+		results = append(results, parsed.ApiResults[key].Name)
+	}
+	if expected := []string{"User", "ParamsUser"}; !reflect.DeepEqual(results, expected) {
+		t.Errorf("expected results %v, got %v", expected, results)
+	}
+
+	methods := parsed.ApiHandler["Api"].ApiMethods
+	if len(methods) != 2 {
+		t.Fatalf("expected 2 api methods, got %+v", methods)
+	}
+	if m := methods[1]; m.RequestName != "ParamsCreateParams" || m.ResultName != "ParamsUser" || m.ResultPtr || !m.Api.Auth {
+		t.Errorf("bad Create method %+v", m)
+	}
+}
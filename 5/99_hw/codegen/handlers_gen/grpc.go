@@ -0,0 +1,374 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	pathpkg "path"
+	"regexp"
+	"sort"
+	"text/template"
+)
+
+// maxProtoNumber is the largest field number protobuf allows
+const maxProtoNumber = 1<<29 - 1
+
+var protoIdent = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// protoScalars maps go types of result fields to protobuf scalars
+var protoScalars = map[string]string{
+	"int":     "int64",
+	"int64":   "int64",
+	"int32":   "int32",
+	"uint":    "uint64",
+	"uint64":  "uint64",
+	"uint32":  "uint32",
+	"bool":    "bool",
+	"string":  "string",
+	"float32": "float",
+	"float64": "double",
+}
+
+// ProtoGenerator describes the parsed api as a gRPC service and writes
+// an adapter, which serves it by the same validators as ServeHTTP does
+type ProtoGenerator struct {
+	InputFile *ParsedFile
+	// GoPackage is the import path of the code protoc generates from the .proto file
+	GoPackage string
+
+	messages []protoMessage
+	services []protoService
+}
+
+type protoMessage struct {
+	Name   string
+	Fields []protoField
+}
+
+type protoField struct {
+	Name     string
+	Type     string
+	Optional bool
+	Number   int
+	// GoName is the field name protoc-gen-go uses for the message struct
+	GoName string
+	// Source is the struct field the proto field is read from or written to
+	Source    string
+	GoType    string
+	ParamName string
+}
+
+type protoService struct {
+	Name    string
+	Methods []protoMethod
+}
+
+type protoMethod struct {
+	Name      string
+	Request   string
+	Response  string
+	ResultPtr bool
+	Auth      bool
+}
+
+func NewProtoGenerator(parsedFile *ParsedFile, goPackage string) *ProtoGenerator {
+	return &ProtoGenerator{
+		InputFile: parsedFile,
+		GoPackage: goPackage,
+	}
+}
+
+// Prepare builds proto messages and services from the parsed api, it
+// reports api types which can't be expressed in protobuf
+func (g *ProtoGenerator) Prepare() []Diagnostic {
+	var diagnostics []Diagnostic
+	errorf := func(pos Diagnostic, format string, args ...interface{}) {
+		pos.Message = fmt.Sprintf(format, args...)
+		diagnostics = append(diagnostics, pos)
+	}
+
+	for _, key := range sortedKeys(g.InputFile.ApiStructs) {
+		apiStruct := g.InputFile.ApiStructs[key]
+		message := protoMessage{Name: apiStruct.Name}
+		numbers := map[int]string{}
+		for _, field := range apiStruct.Fields {
+			name := field.StructValueTags.ParamName
+			if !protoIdent.MatchString(name) {
+				errorf(Diagnostic{Pos: field.Pos}, "%s.%s: param name %q is not a valid protobuf field name", apiStruct.Name, field.Name, name)
+				continue
+			}
+			if err := checkProtoNumber(field.Name, field.ProtoNumber, numbers); err != nil {
+				errorf(Diagnostic{Pos: field.Pos}, "%s.%s: %s", apiStruct.Name, field.Name, err)
+				continue
+			}
+			message.Fields = append(message.Fields, protoField{
+				Name:      name,
+				Type:      protoScalars[field.Type],
+				Optional:  true,
+				Number:    field.ProtoNumber,
+				GoName:    goCamelCase(name),
+				Source:    field.Name,
+				GoType:    field.Type,
+				ParamName: name,
+			})
+		}
+		g.messages = append(g.messages, message)
+	}
+
+	for _, key := range sortedKeys(g.InputFile.ApiResults) {
+		apiResult := g.InputFile.ApiResults[key]
+		if _, exists := g.InputFile.ApiStructs[key]; exists {
+			errorf(Diagnostic{}, "%s: the same struct can't be both params and result", apiResult.Name)
+			continue
+		}
+		message := protoMessage{Name: apiResult.Name}
+		numbers := map[int]string{}
+		for _, field := range apiResult.Fields {
+			protoType, ok := protoScalars[field.Type]
+			if !ok {
+				errorf(Diagnostic{Pos: field.Pos}, "%s.%s: type %s can't be mapped to protobuf", apiResult.Name, field.Name, field.Type)
+				continue
+			}
+			if !protoIdent.MatchString(field.JSONName) {
+				errorf(Diagnostic{Pos: field.Pos}, "%s.%s: json name %q is not a valid protobuf field name", apiResult.Name, field.Name, field.JSONName)
+				continue
+			}
+			if err := checkProtoNumber(field.Name, field.ProtoNumber, numbers); err != nil {
+				errorf(Diagnostic{Pos: field.Pos}, "%s.%s: %s", apiResult.Name, field.Name, err)
+				continue
+			}
+			message.Fields = append(message.Fields, protoField{
+				Name:   field.JSONName,
+				Type:   protoType,
+				Number: field.ProtoNumber,
+				GoName: goCamelCase(field.JSONName),
+				Source: field.Name,
+				GoType: field.Type,
+			})
+		}
+		g.messages = append(g.messages, message)
+	}
+
+	for _, name := range sortedKeys(g.InputFile.ApiHandler) {
+		handler := g.InputFile.ApiHandler[name]
+		service := protoService{Name: handler.Name}
+		for _, method := range handler.ApiMethods {
+			service.Methods = append(service.Methods, protoMethod{
+				Name:      method.Name,
+				Request:   method.RequestName,
+				Response:  method.ResultName,
+				ResultPtr: method.ResultPtr,
+				Auth:      method.Api.Auth,
+			})
+		}
+		g.services = append(g.services, service)
+	}
+	return diagnostics
+}
+
+func (g *ProtoGenerator) GenerateProto(out io.Writer) error {
+	return template.Must(template.New("protoTpl").Parse(`// Code generated by handlers_gen. DO NOT EDIT.
+
+syntax = "proto3";
+
+package {{ .Package }};
+
+option go_package = "{{ .GoPackage }}";
+{{ range .Messages }}
+message {{ .Name }} {
+{{- range .Fields }}
+    {{ if .Optional }}optional {{ end }}{{ .Type }} {{ .Name }} = {{ .Number }};
+{{- end }}
+}
+{{ end -}}
+{{ range .Services }}
+service {{ .Name }} {
+{{- range .Methods }}
+    rpc {{ .Name }} ({{ .Request }}) returns ({{ .Response }}) {}
+{{- end }}
+}
+{{ end -}}
+`)).Execute(out, map[string]interface{}{
+		"Package":   g.InputFile.PackageName,
+		"GoPackage": g.GoPackage,
+		"Messages":  g.messages,
+		"Services":  g.services,
+	})
+}
+
+func (g *ProtoGenerator) GenerateAdapter(out io.Writer) error {
+	messages := make(map[string]protoMessage, len(g.messages))
+	var needStrconv bool
+	for _, message := range g.messages {
+		messages[message.Name] = message
+		for _, field := range message.Fields {
+			needStrconv = needStrconv || field.ParamName != "" && field.GoType == "int"
+		}
+	}
+
+	return template.Must(template.New("adapterTpl").Funcs(template.FuncMap{
+		"message": func(name string) protoMessage { return messages[name] },
+	}).Parse(`// Code generated by handlers_gen. DO NOT EDIT.
+
+package {{ .PackageName }}
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	{{- if .NeedStrconv }}
+	"strconv"
+	{{- end }}
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	{{ .PbName }} "{{ .GoPackage }}"
+)
+{{ range .Services }}
+{{ $service := . -}}
+// {{ .Name }}GRPC serves {{ .Name }} methods over gRPC
+type {{ .Name }}GRPC struct {
+	{{ $.PbName }}.Unimplemented{{ .Name }}Server
+	api *{{ .Name }}
+}
+
+func New{{ .Name }}GRPC(api *{{ .Name }}) *{{ .Name }}GRPC {
+	return &{{ .Name }}GRPC{api: api}
+}
+{{ range .Methods }}
+func (s *{{ $service.Name }}GRPC) {{ .Name }}(ctx context.Context, req *{{ $.PbName }}.{{ .Request }}) (*{{ $.PbName }}.{{ .Response }}, error) {
+	{{- if .Auth }}
+	if !grpcAuthorized(ctx) {
+		return nil, status.Error(codes.Unauthenticated, "unauthorized")
+	}
+	{{ end }}
+	params := url.Values{}
+	{{- range (message .Request).Fields }}
+	if req.{{ .GoName }} != nil {
+		{{- if eq .GoType "int" }}
+		params.Set("{{ .ParamName }}", strconv.FormatInt(req.Get{{ .GoName }}(), 10))
+		{{- else }}
+		params.Set("{{ .ParamName }}", req.Get{{ .GoName }}())
+		{{- end }}
+	}
+	{{- end }}
+
+	in, err := new{{ .Request }}(params)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+
+	out, err := s.api.{{ .Name }}(ctx, in)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	{{- if .ResultPtr }}
+	if out == nil {
+		return &{{ $.PbName }}.{{ .Response }}{}, nil
+	}
+	{{- end }}
+
+	return &{{ $.PbName }}.{{ .Response }}{
+		{{- range (message .Response).Fields }}
+		{{ .GoName }}: {{ if eq .GoType "int" }}int64(out.{{ .Source }}){{ else if eq .GoType "uint" }}uint64(out.{{ .Source }}){{ else }}out.{{ .Source }}{{ end }},
+		{{- end }}
+	}, nil
+}
+{{ end -}}
+{{ end }}
+// grpcAuthorized is the gRPC counterpart of the X-Auth header check
+func grpcAuthorized(ctx context.Context) bool {
+	md, _ := metadata.FromIncomingContext(ctx)
+	for _, value := range md.Get("x-auth") {
+		if value == "100500" {
+			return true
+		}
+	}
+	return false
+}
+
+// grpcError converts errors of api methods and validators to gRPC statuses
+func grpcError(err error) error {
+	errApi, ok := err.(ApiError)
+	if !ok {
+		return status.Error(codes.Internal, err.Error())
+	}
+
+	code := codes.Unknown
+	switch errApi.HTTPStatus {
+	case http.StatusBadRequest:
+		code = codes.InvalidArgument
+	case http.StatusUnauthorized:
+		code = codes.Unauthenticated
+	case http.StatusForbidden:
+		code = codes.PermissionDenied
+	case http.StatusNotFound:
+		code = codes.NotFound
+	case http.StatusConflict:
+		code = codes.AlreadyExists
+	case http.StatusInternalServerError:
+		code = codes.Internal
+	}
+	return status.Error(code, errApi.Error())
+}
+`)).Execute(out, map[string]interface{}{
+		"PackageName": g.InputFile.PackageName,
+		"NeedStrconv": needStrconv,
+		"PbName":      pathpkg.Base(g.GoPackage),
+		"GoPackage":   g.GoPackage,
+		"Services":    g.services,
+	})
+}
+
+// checkProtoNumber makes sure a field has an explicit proto number, which
+// no other field of the message in numbers already took
+func checkProtoNumber(field string, number int, numbers map[int]string) error {
+	if number == 0 {
+		return fmt.Errorf("no proto field number, add a proto:\"N\" tag")
+	}
+	if other, exists := numbers[number]; exists {
+		return fmt.Errorf("proto field number %d is already used by %s", number, other)
+	}
+	numbers[number] = field
+	return nil
+}
+
+// goCamelCase converts a protobuf field name to the go field name
+// protoc-gen-go generates for it
+func goCamelCase(s string) string {
+	var b []byte
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '_' && i == 0:
+			b = append(b, 'X')
+		case c == '_' && i+1 < len(s) && isASCIILower(s[i+1]):
+			// skip the underscore, the next letter is capitalized
+		case '0' <= c && c <= '9':
+			b = append(b, c)
+		default:
+			if isASCIILower(c) {
+				c -= 'a' - 'A'
+			}
+			b = append(b, c)
+			for ; i+1 < len(s) && isASCIILower(s[i+1]); i++ {
+				b = append(b, s[i+1])
+			}
+		}
+	}
+	return string(b)
+}
+
+func isASCIILower(c byte) bool {
+	return 'a' <= c && c <= 'z'
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite golden .proto files of testdata packages")
+
+func generateProto(t *testing.T, pattern string) []byte {
+	t.Helper()
+	parsed, parser, err := parse(t, pattern)
+	if err != nil {
+		t.Fatalf("unexpected error %v: %v", err, parser.Diagnostics)
+	}
+	generator := NewProtoGenerator(parsed, "codegenhw/apipb")
+	if diagnostics := generator.Prepare(); len(diagnostics) > 0 {
+		t.Fatalf("unexpected diagnostics %v", diagnostics)
+	}
+	buf := &bytes.Buffer{}
+	if err := generator.GenerateProto(buf); err != nil {
+		t.Fatalf("cant generate proto: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func checkGolden(t *testing.T, golden string, got []byte) {
+	t.Helper()
+	if *update {
+		if err := os.WriteFile(golden, got, 0644); err != nil {
+			t.Fatalf("cant update %s: %v", golden, err)
+		}
+	}
+	expected, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatalf("cant read %s: %v", golden, err)
+	}
+	if !bytes.Equal(got, expected) {
+		t.Errorf("generated proto differs from %s\nexpected:\n%s\ngot:\n%s", golden, expected, got)
+	}
+}
+
+// api.proto is what go generate writes for the real api, apipb is built
+// from it, so any change of field numbers there breaks the wire format
+func TestGenerateProtoApi(t *testing.T) {
+	got := generateProto(t, "..")
+	expected, err := os.ReadFile("../api.proto")
+	if err != nil {
+		t.Fatalf("cant read api.proto: %v", err)
+	}
+	if !bytes.Equal(got, expected) {
+		t.Errorf("generated proto differs from api.proto, run go generate\nexpected:\n%s\ngot:\n%s", expected, got)
+	}
+}
+
+func TestGenerateProtoReordered(t *testing.T) {
+	checkGolden(t, "testdata/reorder/api.proto.golden", generateProto(t, "./testdata/reorder"))
+}
+
+func TestPrepareProtoNumbers(t *testing.T) {
+	parsed, parser, err := parse(t, "./testdata/badproto")
+	if err != nil {
+		t.Fatalf("unexpected error %v: %v", err, parser.Diagnostics)
+	}
+	checkDiagnostics(t, NewProtoGenerator(parsed, "codegenhw/apipb").Prepare(), []Diagnostic{
+		diag("api.go", 9, `Params.Name: no proto field number, add a proto:"N" tag`),
+		diag("api.go", 14, "User.Login: proto field number 1 is already used by ID"),
+	})
+}
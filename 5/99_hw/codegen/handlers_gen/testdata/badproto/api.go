@@ -0,0 +1,20 @@
+package badproto
+
+import "context"
+
+type Api struct{}
+
+type Params struct {
+	Login string `apivalidator:"required" proto:"1"`
+	Name  string `apivalidator:"paramname=full_name"`
+}
+
+type User struct {
+	ID    uint64 `json:"id" proto:"1"`
+	Login string `json:"login" proto:"1"`
+}
+
+// apigen:api {"url": "/user/create", "method": "POST"}
+func (a *Api) Create(ctx context.Context, in Params) (*User, error) {
+	return &User{}, nil
+}
@@ -0,0 +1,21 @@
+package badtag
+
+import "context"
+
+type Api struct{}
+
+type Params struct {
+	Age   int    `apivalidator:"min=ten"`
+	Login string `apivalidator:"required,size=10"`
+	Level int    `apivalidator:"min=1" proto:"0"`
+	Class string `apivalidator:"required" proto:"19500"`
+}
+
+type Result struct {
+	ID int `json:"id"`
+}
+
+// apigen:api {"url": "/create", "method": "POST"}
+func (a *Api) Create(ctx context.Context, in Params) (*Result, error) {
+	return &Result{}, nil
+}
@@ -0,0 +1,19 @@
+package badtype
+
+import "context"
+
+type Api struct{}
+
+type Params struct {
+	Login  string  `apivalidator:"required"`
+	Weight float64 `apivalidator:"min=0"`
+}
+
+type Result struct {
+	ID int `json:"id"`
+}
+
+// apigen:api {"url": "/create", "method": "POST"}
+func (a *Api) Create(ctx context.Context, in Params) (*Result, error) {
+	return &Result{}, nil
+}
@@ -0,0 +1,22 @@
+package reorder
+
+import "context"
+
+type Api struct{}
+
+// fields are declared out of order, the proto tags keep their numbers
+type Params struct {
+	Age   int    `apivalidator:"min=0" proto:"3"`
+	Login string `apivalidator:"required" proto:"1"`
+}
+
+type User struct {
+	Login string `json:"login" proto:"2"`
+	ID    uint64 `json:"id" proto:"1"`
+	Note  string `json:"-"`
+}
+
+// apigen:api {"url": "/user/create", "method": "POST"}
+func (a *Api) Create(ctx context.Context, in Params) (*User, error) {
+	return &User{}, nil
+}
@@ -0,0 +1,19 @@
+package split
+
+import (
+	"context"
+
+	"codegenhw/handlers_gen/testdata/split/params"
+)
+
+type Api struct{}
+
+// apigen:api {"url": "/user/profile"}
+func (a *Api) Profile(ctx context.Context, in ProfileParams) (*User, error) {
+	return &User{}, nil
+}
+
+// apigen:api {"url": "/user/create", "auth": true, "method": "POST"}
+func (a *Api) Create(ctx context.Context, in params.CreateParams) (params.User, error) {
+	return params.User{}, nil
+}
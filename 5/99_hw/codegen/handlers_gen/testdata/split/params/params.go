@@ -0,0 +1,10 @@
+package params
+
+type CreateParams struct {
+	Login string `apivalidator:"required,min=3"`
+	Age   int    `apivalidator:"min=0,max=128,default=18"`
+}
+
+type User struct {
+	ID uint64 `json:"id"`
+}
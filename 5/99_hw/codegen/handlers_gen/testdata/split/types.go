@@ -0,0 +1,10 @@
+package split
+
+type ProfileParams struct {
+	Login string `apivalidator:"required"`
+}
+
+type User struct {
+	ID    uint64 `json:"id"`
+	Login string `json:"login"`
+}
@@ -7,12 +7,35 @@ package main
 
 import (
 	"fmt"
+	"log"
+	"net"
 	"net/http"
+
+	"google.golang.org/grpc"
+
+	"codegenhw/apipb"
 )
 
 func main() {
+	api := NewMyApi()
+
+	// те же методы MyApi доступны по gRPC, см. api.proto
+	go func() {
+		lis, err := net.Listen("tcp", ":8081")
+		if err != nil {
+			log.Fatalln("cant listen port", err)
+		}
+		server := grpc.NewServer()
+		apipb.RegisterMyApiServer(server, NewMyApiGRPC(api))
+
+		fmt.Println("starting grpc server at :8081")
+		if err := server.Serve(lis); err != nil {
+			log.Fatalln("grpc server failed", err)
+		}
+	}()
+
 	// будет вызван метод ServeHTTP у структуры MyApi
-	http.Handle("/user/", NewMyApi())
+	http.Handle("/user/", api)
 
 	fmt.Println("starting server at :8080")
 	http.ListenAndServe(":8080", nil)
@@ -0,0 +1,269 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+)
+
+const (
+	PermissionRead   = "read"
+	PermissionInsert = "insert"
+	PermissionUpdate = "update"
+	PermissionDelete = "delete"
+)
+
+// methodPermissions maps request methods to what they do with a table
+var methodPermissions = map[string]string{
+	http.MethodGet:    PermissionRead,
+	http.MethodPut:    PermissionInsert,
+	http.MethodPost:   PermissionUpdate,
+	http.MethodDelete: PermissionDelete,
+}
+
+// TableAccess is what an api key may do with a table. Columns override
+// the table permissions for single columns, hidden columns are neither
+// returned nor written and look like they don't exist.
+// The primary key can't be hidden, records are addressed by it
+type TableAccess struct {
+	Permissions []string            `json:"permissions"`
+	Columns     map[string][]string `json:"columns,omitempty"`
+	Hidden      []string            `json:"hidden,omitempty"`
+}
+
+// KeyAccess lists tables of an api key, "*" matches tables not listed by name
+type KeyAccess struct {
+	Tables map[string]TableAccess `json:"tables"`
+	// Admin allows /_schema, POST /_schema/reload is only served to admins
+	Admin bool `json:"admin,omitempty"`
+}
+
+// AccessConfig is loaded from a file like
+//
+//	{
+//	    "read_only": false,
+//	    "keys": {
+//	        "secret": {"tables": {"*": {"permissions": ["read", "insert", "update", "delete"]}}, "admin": true},
+//	        "reader": {"tables": {"users": {"permissions": ["read"], "hidden": ["password"]}}}
+//	    },
+//	    "anonymous": {"tables": {"items": {"permissions": ["read"]}}}
+//	}
+//
+// The key is passed in the X-Api-Key header, requests without it get
+// the anonymous permissions or are rejected if there are none
+type AccessConfig struct {
+	ReadOnly  bool                 `json:"read_only"`
+	Keys      map[string]KeyAccess `json:"keys"`
+	Anonymous *KeyAccess           `json:"anonymous,omitempty"`
+}
+
+func LoadAccessConfig(path string) (*AccessConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read access config: %s", err)
+	}
+
+	config := &AccessConfig{}
+	if err := json.Unmarshal(data, config); err != nil {
+		return nil, fmt.Errorf("failed to parse access config %s: %s", path, err)
+	}
+	if err := config.Validate(); err != nil {
+		return nil, fmt.Errorf("bad access config %s: %s", path, err)
+	}
+	return config, nil
+}
+
+// Validate rejects unknown permissions, typos would silently deny access
+func (c *AccessConfig) Validate() error {
+	check := func(key, table string, permissions []string) error {
+		for _, p := range permissions {
+			switch p {
+			case PermissionRead, PermissionInsert, PermissionUpdate, PermissionDelete:
+			default:
+				return fmt.Errorf("key %q, table %q: unknown permission %q", key, table, p)
+			}
+		}
+		return nil
+	}
+
+	keys := map[string]*KeyAccess{}
+	for key, access := range c.Keys {
+		access := access
+		keys[key] = &access
+	}
+	if c.Anonymous != nil {
+		keys["anonymous"] = c.Anonymous
+	}
+	for key, access := range keys {
+		for table, ta := range access.Tables {
+			if err := check(key, table, ta.Permissions); err != nil {
+				return err
+			}
+			for _, permissions := range ta.Columns {
+				if err := check(key, table, permissions); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// SetAccessConfig enables access control, it must be called before serving requests
+func (e *DbExplorer) SetAccessConfig(config *AccessConfig) {
+	e.access = config
+}
+
+// SetReadOnly rejects all requests which modify data, whatever the api key is
+func (e *DbExplorer) SetReadOnly(readOnly bool) {
+	e.readOnly = readOnly
+}
+
+func (e *DbExplorer) isReadOnly() bool {
+	return e.readOnly || e.access != nil && e.access.ReadOnly
+}
+
+// keyAccess finds the permissions of the request, nil means access control is off
+func (e *DbExplorer) keyAccess(r *http.Request) (*KeyAccess, error) {
+	if e.access == nil {
+		return nil, nil
+	}
+
+	key := r.Header.Get("X-Api-Key")
+	if key == "" {
+		if e.access.Anonymous == nil {
+			return nil, ResponseError{"unauthorized", http.StatusUnauthorized}
+		}
+		return e.access.Anonymous, nil
+	}
+
+	access, ok := e.access.Keys[key]
+	if !ok {
+		return nil, ResponseError{"unauthorized", http.StatusUnauthorized}
+	}
+	return &access, nil
+}
+
+func (k *KeyAccess) table(name string) (TableAccess, bool) {
+	ta, ok := k.Tables[name]
+	if !ok {
+		ta, ok = k.Tables["*"]
+	}
+	return ta, ok
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+func (ta TableAccess) allows(permission string) bool {
+	return contains(ta.Permissions, permission)
+}
+
+// sees reports whether the column of the table is in views for the permission,
+// the primary key always is
+func (ta TableAccess) sees(table Table, column, permission string) bool {
+	return column == table.Pk || ta.columnAllows(column, permission)
+}
+
+func (ta TableAccess) columnAllows(column, permission string) bool {
+	if contains(ta.Hidden, column) {
+		return false
+	}
+	if permissions, ok := ta.Columns[column]; ok {
+		return contains(permissions, permission)
+	}
+	return ta.allows(permission)
+}
+
+// View returns the tables the key has the permission for, with only the
+// columns it has the permission for. Relations to tables or columns out
+// of the view are dropped
+func (k *KeyAccess) View(tables map[string]Table, permission string) map[string]Table {
+	view := make(map[string]Table, len(tables))
+	for name, table := range tables {
+		ta, ok := k.table(name)
+		if !ok || !ta.allows(permission) {
+			continue
+		}
+
+		columns := make([]TableColumn, 0, len(table.Columns))
+		for _, c := range table.Columns {
+			if ta.sees(table, c.Field, permission) {
+				columns = append(columns, c)
+			}
+		}
+		table.Columns = columns
+		view[name] = table
+	}
+
+	for name, table := range view {
+		relations := make(map[string]Relation, len(table.Relations))
+		for relName, rel := range table.Relations {
+			related, ok := view[rel.Table]
+			if !ok {
+				continue
+			}
+			if _, ok := table.Column(rel.Column); !ok {
+				continue
+			}
+			if _, ok := related.Column(rel.RefColumn); !ok {
+				continue
+			}
+			relations[relName] = rel
+		}
+		table.Relations = relations
+		view[name] = table
+	}
+	return view
+}
+
+// CheckRecord rejects values of columns the key can see but can't write.
+// Hidden columns are left to be ignored like unknown ones
+func (k *KeyAccess) CheckRecord(table string, permission string, record TableRecord) error {
+	ta, _ := k.table(table)
+	for field := range record {
+		if contains(ta.Hidden, field) {
+			continue
+		}
+		if !ta.columnAllows(field, permission) {
+			return ResponseError{fmt.Sprintf("field %s is not writable", field), http.StatusForbidden}
+		}
+	}
+	return nil
+}
+
+// authorize checks the request against the read-only switch and the api key,
+// the returned explorer sees only what the key may touch by the request
+func (e *DbExplorer) authorize(access *KeyAccess, r *http.Request) (*DbExplorer, error) {
+	permission, ok := methodPermissions[r.Method]
+	if !ok {
+		return nil, ResponseError{"method not found", http.StatusNotFound}
+	}
+	if permission != PermissionRead && e.isReadOnly() {
+		return nil, ResponseError{"read-only mode", http.StatusForbidden}
+	}
+	if access == nil {
+		return e, nil
+	}
+
+	view := access.View(e.tables, permission)
+	name, _, _ := strings.Cut(strings.Trim(r.URL.Path, "/"), "/")
+	if _, exists := e.tables[name]; exists {
+		if _, ok := view[name]; !ok {
+			return nil, ResponseError{"access denied", http.StatusForbidden}
+		}
+	}
+
+	restricted := *e
+	restricted.tables = view
+	restricted.key = access
+	return &restricted, nil
+}
@@ -0,0 +1,243 @@
+package main
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+var testAccessConfig = `{
+	"keys": {
+		"admin": {"tables": {"*": {"permissions": ["read", "insert", "update", "delete"]}}, "admin": true},
+		"editor": {
+			"tables": {
+				"items": {
+					"permissions": ["read", "insert", "update"],
+					"columns": {"updated": ["read"]}
+				},
+				"users": {"permissions": ["read", "update"], "hidden": ["password", "email"]}
+			}
+		}
+	},
+	"anonymous": {"tables": {"items": {"permissions": ["read"], "hidden": ["description"]}}}
+}`
+
+func TestAccess(t *testing.T) {
+	ts, handler, db := newTestServer(t, PrepareTestApis)
+
+	path := filepath.Join(t.TempDir(), "access.json")
+	if err := os.WriteFile(path, []byte(testAccessConfig), 0o600); err != nil {
+		panic(err)
+	}
+	config, err := LoadAccessConfig(path)
+	if err != nil {
+		t.Fatalf("cant load access config: %v", err)
+	}
+
+	handler.SetAccessConfig(config)
+
+	editor := http.Header{"X-Api-Key": {"editor"}}
+
+	cases := []Case{
+		Case{
+			Path: "/",
+			Result: CR{
+				"response": CR{
+					"tables": []string{"items"},
+				},
+			},
+		},
+		Case{
+			Path:   "/items/1",
+			Query:  "embed=nothing",
+			Status: http.StatusBadRequest,
+			Result: CR{
+				"error": "unknown relation nothing",
+			},
+		},
+		Case{
+			Path: "/items/1",
+			Result: CR{
+				"response": CR{
+					"record": CR{
+						"id":      1,
+						"title":   "database/sql",
+						"updated": "rvasily",
+					},
+				},
+			},
+		},
+		// скрытое поле выглядит как несуществующее
+		Case{
+			Path:   "/items",
+			Query:  "where=description~%25",
+			Status: http.StatusBadRequest,
+			Result: CR{
+				"error": "unknown field description",
+			},
+		},
+		Case{
+			Path:   "/users/1",
+			Status: http.StatusForbidden,
+			Result: CR{
+				"error": "access denied",
+			},
+		},
+		Case{
+			Method: http.MethodDelete,
+			Path:   "/items/1",
+			Status: http.StatusForbidden,
+			Result: CR{
+				"error": "access denied",
+			},
+		},
+		Case{
+			Path:   "/items/1",
+			Header: http.Header{"X-Api-Key": {"nobody"}},
+			Status: http.StatusUnauthorized,
+			Result: CR{
+				"error": "unauthorized",
+			},
+		},
+		Case{
+			Path:   "/users/1",
+			Header: editor,
+			Result: CR{
+				"response": CR{
+					"record": CR{
+						"user_id": 1,
+						"login":   "rvasily",
+						"info":    "none",
+						"updated": nil,
+					},
+				},
+			},
+		},
+		Case{
+			Method: http.MethodPost,
+			Path:   "/items/1",
+			Header: editor,
+			Body: CR{
+				"updated": "autobot",
+			},
+			Status: http.StatusForbidden,
+			Result: CR{
+				"error": "field updated is not writable",
+			},
+		},
+		Case{
+			Method: http.MethodPost,
+			Path:   "/items/1",
+			Header: editor,
+			Body: CR{
+				"title": "new title",
+			},
+			Result: CR{
+				"response": CR{
+					"updated": 1,
+				},
+			},
+		},
+		// скрытые поля игнорируются, как неизвестные
+		Case{
+			Method: http.MethodPost,
+			Path:   "/users/1",
+			Header: editor,
+			Body: CR{
+				"password": "hacked",
+			},
+			Result: CR{
+				"response": CR{
+					"updated": 0,
+				},
+			},
+		},
+		Case{
+			Method: http.MethodDelete,
+			Path:   "/items/1",
+			Header: editor,
+			Status: http.StatusForbidden,
+			Result: CR{
+				"error": "access denied",
+			},
+		},
+		// батч проверяет каждую операцию
+		Case{
+			Method: http.MethodPost,
+			Path:   "/_batch",
+			Header: editor,
+			Body: CR{
+				"operations": []CR{
+					CR{"method": "PUT", "path": "/items/", "body": CR{"title": "batch", "description": "batch"}},
+					CR{"method": "DELETE", "path": "/items/1"},
+				},
+			},
+			Status: http.StatusForbidden,
+			Result: CR{
+				"error": "operation 1: access denied",
+			},
+		},
+		Case{
+			Method: http.MethodPost,
+			Path:   "/_schema/reload",
+			Header: editor,
+			Status: http.StatusForbidden,
+			Result: CR{
+				"error": "access denied",
+			},
+		},
+		Case{
+			Method: http.MethodDelete,
+			Path:   "/items/2",
+			Header: http.Header{"X-Api-Key": {"admin"}},
+			Result: CR{
+				"response": CR{
+					"deleted": 1,
+				},
+			},
+		},
+	}
+
+	runCases(t, ts, db, cases)
+
+	handler.SetReadOnly(true)
+	runCases(t, ts, db, []Case{
+		Case{
+			Method: http.MethodPut,
+			Path:   "/items/",
+			Header: http.Header{"X-Api-Key": {"admin"}},
+			Body: CR{
+				"title":       "read-only",
+				"description": "read-only",
+			},
+			Status: http.StatusForbidden,
+			Result: CR{
+				"error": "read-only mode",
+			},
+		},
+		Case{
+			Path:   "/items",
+			Query:  "fields=id,title",
+			Header: http.Header{"X-Api-Key": {"admin"}},
+			Result: CR{
+				"response": CR{
+					"records": []CR{
+						CR{"id": 1, "title": "new title"},
+					},
+				},
+			},
+		},
+	})
+}
+
+func TestAccessConfigValidate(t *testing.T) {
+	config := &AccessConfig{
+		Keys: map[string]KeyAccess{
+			"key": {Tables: map[string]TableAccess{"items": {Permissions: []string{"raed"}}}},
+		},
+	}
+	if err := config.Validate(); err == nil {
+		t.Errorf("expected an error for an unknown permission")
+	}
+}
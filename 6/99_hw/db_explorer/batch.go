@@ -0,0 +1,108 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+const maxBatchOperations = 1000
+
+// BatchOperation is handled the same way as if it came as a request of its own:
+//
+//	{"method": "PUT", "path": "/items/", "body": {"title": "db_explorer"}}
+type BatchOperation struct {
+	Method string          `json:"method"`
+	Path   string          `json:"path"`
+	Body   json.RawMessage `json:"body,omitempty"`
+	// IfMatch is the If-Match header of the operation, see handleIfMatch
+	IfMatch string `json:"if_match,omitempty"`
+}
+
+type BatchRequest struct {
+	Operations []BatchOperation `json:"operations"`
+}
+
+// BatchResponse holds responses of the operations in the same order
+type BatchResponse struct {
+	Results []interface{} `json:"results"`
+}
+
+// NewBatchError reports the failed operation, numbered from 0. Errors which
+// are not ResponseError come from the database
+func NewBatchError(i int, err error) ResponseError {
+	status := http.StatusInternalServerError
+	var re ResponseError
+	if errors.As(err, &re) {
+		status = re.StatusCode
+	}
+	return ResponseError{
+		Text:       fmt.Sprintf("operation %d: %s", i, err),
+		StatusCode: status,
+	}
+}
+
+// handleBatch runs operations in one transaction, the first failed operation
+// rolls back all of them
+func (e *DbExplorer) handleBatch(r *http.Request) (*BatchResponse, error) {
+	if r.Method != http.MethodPost {
+		return nil, ResponseError{"method not found", http.StatusNotFound}
+	}
+
+	var batch BatchRequest
+	if err := json.NewDecoder(r.Body).Decode(&batch); err != nil {
+		return nil, ResponseError{"invalid json body", http.StatusBadRequest}
+	}
+	if len(batch.Operations) > maxBatchOperations {
+		return nil, ResponseError{
+			fmt.Sprintf("too many operations, max %d", maxBatchOperations),
+			http.StatusBadRequest,
+		}
+	}
+
+	tx, err := e.db.BeginTx(r.Context(), nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	txExplorer := *e
+	txExplorer.conn = tx
+
+	res := &BatchResponse{
+		Results: make([]interface{}, 0, len(batch.Operations)),
+	}
+	for i, op := range batch.Operations {
+		if !strings.HasPrefix(op.Path, "/") || strings.HasPrefix(op.Path, "/_") || op.Path == "/graphql" {
+			return nil, NewBatchError(i, ResponseError{"invalid path", http.StatusBadRequest})
+		}
+
+		opRequest, err := http.NewRequestWithContext(r.Context(), op.Method, op.Path, bytes.NewReader(op.Body))
+		if err != nil {
+			return nil, NewBatchError(i, ResponseError{"invalid request", http.StatusBadRequest})
+		}
+		opRequest.Header = r.Header.Clone()
+		opRequest.Header.Del("If-Match")
+		if op.IfMatch != "" {
+			opRequest.Header.Set("If-Match", op.IfMatch)
+		}
+
+		data, err := txExplorer.handleRequest(opRequest)
+		if err != nil {
+			return nil, NewBatchError(i, err)
+		}
+		if stream, ok := data.(*RecordStream); ok {
+			stream.Close()
+			return nil, NewBatchError(i, ResponseError{"export is not supported in a batch", http.StatusBadRequest})
+		}
+		res.Results = append(res.Results, data)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestBatch(t *testing.T) {
+	ts, _, db := newTestServer(t, PrepareTestApis)
+
+	cases := []Case{
+		Case{
+			Method: http.MethodPost,
+			Path:   "/_batch",
+			Body: CR{
+				"operations": []CR{
+					CR{"method": "PUT", "path": "/items/", "body": CR{"title": "batch", "description": "Рассказать про батчи"}},
+					CR{"method": "POST", "path": "/items/1", "body": CR{"updated": "autobot"}},
+					CR{"method": "DELETE", "path": "/items/2"},
+					CR{"method": "POST", "path": "/users/1", "body": CR{"info": "batched"}},
+					CR{"method": "GET", "path": "/items/3"},
+				},
+			},
+			Result: CR{
+				"response": CR{
+					"results": []interface{}{
+						CR{"id": 3},
+						CR{"updated": 1},
+						CR{"deleted": 1},
+						CR{"updated": 1},
+						CR{
+							"record": CR{
+								"id":          3,
+								"title":       "batch",
+								"description": "Рассказать про батчи",
+								"updated":     nil,
+							},
+						},
+					},
+				},
+			},
+		},
+		Case{
+			Path:  "/items",
+			Query: "fields=id,updated",
+			Result: CR{
+				"response": CR{
+					"records": []CR{
+						CR{"id": 1, "updated": "autobot"},
+						CR{"id": 3, "updated": nil},
+					},
+				},
+			},
+		},
+		// вторая операция падает - первая откатывается
+		Case{
+			Method: http.MethodPost,
+			Path:   "/_batch",
+			Body: CR{
+				"operations": []CR{
+					CR{"method": "DELETE", "path": "/items/3"},
+					CR{"method": "POST", "path": "/items/1", "body": CR{"title": 42}},
+				},
+			},
+			Status: http.StatusBadRequest,
+			Result: CR{
+				"error": "operation 1: field title have invalid type",
+			},
+		},
+		Case{
+			Method: http.MethodPost,
+			Path:   "/_batch",
+			Body: CR{
+				"operations": []CR{
+					CR{"method": "DELETE", "path": "/items/3"},
+					CR{"method": "DELETE", "path": "/tags/1"},
+				},
+			},
+			Status: http.StatusNotFound,
+			Result: CR{
+				"error": "operation 1: unknown table",
+			},
+		},
+		Case{
+			Path:  "/items",
+			Query: "fields=id,title",
+			Result: CR{
+				"response": CR{
+					"records": []CR{
+						CR{"id": 1, "title": "database/sql"},
+						CR{"id": 3, "title": "batch"},
+					},
+				},
+			},
+		},
+		Case{
+			Method: http.MethodPost,
+			Path:   "/_batch",
+			Body: CR{
+				"operations": []CR{
+					CR{"method": "POST", "path": "/_batch", "body": CR{"operations": []CR{}}},
+				},
+			},
+			Status: http.StatusBadRequest,
+			Result: CR{
+				"error": "operation 0: invalid path",
+			},
+		},
+		Case{
+			Method: http.MethodPost,
+			Path:   "/_batch",
+			Body:   []CR{},
+			Status: http.StatusBadRequest,
+			Result: CR{
+				"error": "invalid json body",
+			},
+		},
+		Case{
+			Path:   "/_batch",
+			Status: http.StatusNotFound,
+			Result: CR{
+				"error": "method not found",
+			},
+		},
+	}
+
+	runCases(t, ts, db, cases)
+}
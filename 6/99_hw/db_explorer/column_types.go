@@ -0,0 +1,739 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// ColumnType knows how values of an SQL type are read from the database,
+// encoded to JSON and validated when they come in a request body.
+// Request bodies are decoded with json.Decoder.UseNumber, so numbers
+// arrive as json.Number and keep their precision
+type ColumnType interface {
+	// NewVar returns a pointer to scan a column value into, it is encoded to JSON as is
+	NewVar() interface{}
+	// IsValidValue reports whether a value decoded from JSON fits the column
+	IsValidValue(val interface{}) bool
+	// DBValue converts a valid value to a query argument
+	DBValue(val interface{}) interface{}
+	// ZeroValue is inserted for NOT NULL columns without a default missing in the body
+	ZeroValue() interface{}
+}
+
+// SQLType is a column type as the database reports it, split into parts:
+// "decimal(10,2) unsigned" is {Name: "decimal", Args: ["10", "2"], Unsigned: true}
+type SQLType struct {
+	Name     string
+	Args     []string
+	Unsigned bool
+}
+
+func ParseSQLType(dataType string) SQLType {
+	t := SQLType{}
+	s := strings.ToLower(strings.TrimSpace(dataType))
+
+	if i := strings.Index(s, "("); i >= 0 {
+		if j := strings.LastIndex(s, ")"); j > i {
+			for _, arg := range splitTypeArgs(s[i+1 : j]) {
+				t.Args = append(t.Args, strings.Trim(strings.TrimSpace(arg), "'"))
+			}
+			s = s[:i] + s[j+1:]
+		}
+	}
+
+	words := strings.Fields(s)
+	for i := 0; i < len(words); i++ {
+		if words[i] == "unsigned" || words[i] == "zerofill" {
+			t.Unsigned = true
+			words = append(words[:i], words[i+1:]...)
+			i--
+		}
+	}
+	t.Name = strings.Join(words, " ")
+	return t
+}
+
+// splitTypeArgs splits enum('a,b','c') arguments by commas outside of quotes
+func splitTypeArgs(s string) (args []string) {
+	var quoted bool
+	start := 0
+	for i, r := range s {
+		switch {
+		case r == '\'':
+			quoted = !quoted
+		case r == ',' && !quoted:
+			args = append(args, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(args, s[start:])
+}
+
+func (t SQLType) intArg(i int) (int, bool) {
+	if i >= len(t.Args) {
+		return 0, false
+	}
+	n, err := strconv.Atoi(t.Args[i])
+	return n, err == nil
+}
+
+// ColumnTypeFactory builds a ColumnType for a parsed SQL type
+type ColumnTypeFactory func(t SQLType, null bool) ColumnType
+
+// ColumnTypeRegistry maps SQL type names to column types
+type ColumnTypeRegistry struct {
+	factories map[string]ColumnTypeFactory
+}
+
+func NewColumnTypeRegistry() *ColumnTypeRegistry {
+	r := &ColumnTypeRegistry{factories: make(map[string]ColumnTypeFactory)}
+
+	for name, bits := range map[string]int{
+		"smallint": 16, "mediumint": 24, "int": 32, "bigint": 64,
+		"int2": 16, "int4": 32, "int8": 64, "serial": 32, "bigserial": 64,
+	} {
+		r.Register(name, intColumnFactory(bits))
+	}
+	// sqlite's INTEGER is 64 bit, postgres' one is checked by the database itself
+	r.Register("integer", intColumnFactory(64))
+	// mysql has no real booleans, tinyint(1) is used instead
+	r.Register("tinyint", func(t SQLType, null bool) ColumnType {
+		if len(t.Args) == 1 && t.Args[0] == "1" {
+			return BoolColumn{Null: null}
+		}
+		return intColumnFactory(8)(t, null)
+	})
+
+	for _, name := range []string{"float", "float4"} {
+		r.Register(name, func(t SQLType, null bool) ColumnType {
+			return FloatColumn{Null: null, Bits: 32}
+		})
+	}
+	// real is a double in mysql and sqlite, in postgres the driver parses it from text anyway
+	for _, name := range []string{"double", "double precision", "float8", "real"} {
+		r.Register(name, func(t SQLType, null bool) ColumnType {
+			return FloatColumn{Null: null, Bits: 64}
+		})
+	}
+	for _, name := range []string{"decimal", "numeric", "dec", "fixed"} {
+		r.Register(name, func(t SQLType, null bool) ColumnType {
+			c := DecimalColumn{Null: null, Unsigned: t.Unsigned}
+			c.Precision, _ = t.intArg(0)
+			c.Scale, _ = t.intArg(1)
+			return c
+		})
+	}
+
+	for _, name := range []string{"bool", "boolean"} {
+		r.Register(name, func(t SQLType, null bool) ColumnType {
+			return BoolColumn{Null: null}
+		})
+	}
+
+	for name, layout := range map[string]string{
+		"date":                        DateLayout,
+		"time":                        TimeOfDayLayout,
+		"time without time zone":      TimeOfDayLayout,
+		"datetime":                    DateTimeLayout,
+		"timestamp":                   DateTimeLayout,
+		"timestamp without time zone": DateTimeLayout,
+		"timestamp with time zone":    time.RFC3339Nano,
+		"timestamptz":                 time.RFC3339Nano,
+	} {
+		layout := layout
+		r.Register(name, func(t SQLType, null bool) ColumnType {
+			return TimeColumn{Null: null, Layout: layout}
+		})
+	}
+
+	for _, name := range []string{"json", "jsonb"} {
+		r.Register(name, func(t SQLType, null bool) ColumnType {
+			return JSONColumn{Null: null}
+		})
+	}
+
+	for _, name := range []string{
+		"blob", "tinyblob", "mediumblob", "longblob", "binary", "varbinary", "bytea",
+	} {
+		r.Register(name, func(t SQLType, null bool) ColumnType {
+			return BlobColumn{Null: null}
+		})
+	}
+
+	for _, name := range []string{
+		"char", "varchar", "character", "character varying", "nchar", "nvarchar",
+	} {
+		r.Register(name, func(t SQLType, null bool) ColumnType {
+			c := StringColumn{Null: null}
+			c.Size, _ = t.intArg(0)
+			return c
+		})
+	}
+	r.Register("enum", func(t SQLType, null bool) ColumnType {
+		return StringColumn{Null: null, Enum: t.Args}
+	})
+	return r
+}
+
+// Register adds or replaces the column type for an SQL type name,
+// the name is lower case and has no arguments: "varchar", "double precision"
+func (r *ColumnTypeRegistry) Register(name string, factory ColumnTypeFactory) {
+	r.factories[name] = factory
+}
+
+// Lookup returns the column type for an SQL type as the database reports it.
+// Unknown types are resolved by sqlite type affinity rules, the last resort is a string
+func (r *ColumnTypeRegistry) Lookup(dataType string, null bool) ColumnType {
+	t := ParseSQLType(dataType)
+	if factory, ok := r.factories[t.Name]; ok {
+		return factory(t, null)
+	}
+
+	switch {
+	case strings.Contains(t.Name, "int"):
+		return intColumnFactory(64)(t, null)
+	case strings.Contains(t.Name, "char"), strings.Contains(t.Name, "clob"), strings.Contains(t.Name, "text"):
+		return StringColumn{Null: null}
+	case strings.Contains(t.Name, "blob"):
+		return BlobColumn{Null: null}
+	case strings.Contains(t.Name, "real"), strings.Contains(t.Name, "floa"), strings.Contains(t.Name, "doub"):
+		return FloatColumn{Null: null, Bits: 64}
+	}
+	return StringColumn{Null: null}
+}
+
+func intColumnFactory(bits int) ColumnTypeFactory {
+	return func(t SQLType, null bool) ColumnType {
+		return IntColumn{Null: null, Bits: bits, Unsigned: t.Unsigned}
+	}
+}
+
+// newVar returns a pointer to scan a T into, for nullable columns
+// it's a pointer to a nil pointer, which is encoded to JSON as null
+func newVar[T any](null bool) interface{} {
+	if null {
+		return new(*T)
+	}
+	return new(T)
+}
+
+type IntColumn struct {
+	Null     bool
+	Bits     int
+	Unsigned bool
+}
+
+func (c IntColumn) NewVar() interface{} {
+	if c.Unsigned && c.Bits == 64 {
+		return newVar[uint64](c.Null)
+	}
+	return newVar[int64](c.Null)
+}
+
+func (c IntColumn) parse(val interface{}) (*big.Int, bool) {
+	n := new(big.Int)
+	switch v := val.(type) {
+	case json.Number:
+		if _, ok := n.SetString(string(v), 10); !ok {
+			return nil, false
+		}
+	case int:
+		n.SetInt64(int64(v))
+	case int64:
+		n.SetInt64(v)
+	case uint64:
+		n.SetUint64(v)
+	case float64:
+		if v != math.Trunc(v) || math.IsInf(v, 0) {
+			return nil, false
+		}
+		big.NewFloat(v).Int(n)
+	default:
+		return nil, false
+	}
+
+	bits := c.Bits
+	if bits == 0 {
+		bits = 64
+	}
+	min, max := new(big.Int), new(big.Int).Lsh(big.NewInt(1), uint(bits))
+	if c.Unsigned {
+		max.Sub(max, big.NewInt(1))
+	} else {
+		max.Rsh(max, 1)
+		min.Neg(max)
+		max.Sub(max, big.NewInt(1))
+	}
+	return n, n.Cmp(min) >= 0 && n.Cmp(max) <= 0
+}
+
+func (c IntColumn) IsValidValue(val interface{}) bool {
+	if val == nil {
+		return c.Null
+	}
+
+	_, ok := c.parse(val)
+	return ok
+}
+
+func (c IntColumn) DBValue(val interface{}) interface{} {
+	n, ok := c.parse(val)
+	if !ok {
+		return nil
+	}
+	if n.IsInt64() {
+		return n.Int64()
+	}
+	return n.Uint64()
+}
+
+func (c IntColumn) ZeroValue() interface{} {
+	return int64(0)
+}
+
+type FloatColumn struct {
+	Null bool
+	Bits int
+}
+
+// NewVar scans FLOAT into float32, so it's encoded without float64 noise: 1.1, not 1.100000023841858
+func (c FloatColumn) NewVar() interface{} {
+	if c.Bits == 32 {
+		return newVar[float32](c.Null)
+	}
+	return newVar[float64](c.Null)
+}
+
+func (c FloatColumn) parse(val interface{}) (float64, bool) {
+	switch v := val.(type) {
+	case json.Number:
+		f, err := strconv.ParseFloat(string(v), c.bits())
+		return f, err == nil
+	case float64:
+		return v, true
+	case int64:
+		return float64(v), true
+	case int:
+		return float64(v), true
+	}
+	return 0, false
+}
+
+func (c FloatColumn) bits() int {
+	if c.Bits == 32 {
+		return 32
+	}
+	return 64
+}
+
+func (c FloatColumn) IsValidValue(val interface{}) bool {
+	if val == nil {
+		return c.Null
+	}
+
+	_, ok := c.parse(val)
+	return ok
+}
+
+func (c FloatColumn) DBValue(val interface{}) interface{} {
+	f, _ := c.parse(val)
+	return f
+}
+
+func (c FloatColumn) ZeroValue() interface{} {
+	return float64(0)
+}
+
+// Decimal keeps DECIMAL and NUMERIC values as text, they never go through
+// float64 and are encoded to JSON as numbers with all their digits
+type Decimal string
+
+func (d *Decimal) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case nil:
+		*d = ""
+	case []byte:
+		*d = Decimal(v)
+	case string:
+		*d = Decimal(v)
+	case int64:
+		*d = Decimal(strconv.FormatInt(v, 10))
+	case float64:
+		*d = Decimal(strconv.FormatFloat(v, 'f', -1, 64))
+	default:
+		return fmt.Errorf("can't scan %T into Decimal", src)
+	}
+	return nil
+}
+
+func (d Decimal) MarshalJSON() ([]byte, error) {
+	if d == "" {
+		return []byte("null"), nil
+	}
+	return []byte(d), nil
+}
+
+// scaledDecimal formats numbers a driver returns instead of text with the scale
+// of the column. sqlite has no exact decimals and stores them as REAL or
+// INTEGER, so there they keep only 15 significant digits, but "0.10" is not
+// turned into 0.1
+type scaledDecimal struct {
+	Decimal
+	scale int
+}
+
+func (d *scaledDecimal) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case int64:
+		d.Decimal = Decimal(new(big.Rat).SetInt64(v).FloatString(d.scale))
+		return nil
+	case float64:
+		d.Decimal = Decimal(strconv.FormatFloat(v, 'f', d.scale, 64))
+		return nil
+	}
+	return d.Decimal.Scan(src)
+}
+
+type DecimalColumn struct {
+	Null      bool
+	Precision int
+	Scale     int
+	Unsigned  bool
+}
+
+func (c DecimalColumn) NewVar() interface{} {
+	if c.Precision > 0 {
+		return &scaledDecimal{scale: c.Scale}
+	}
+	return new(Decimal)
+}
+
+func (c DecimalColumn) parse(val interface{}) (string, bool) {
+	var s string
+	switch v := val.(type) {
+	case json.Number:
+		s = string(v)
+	case string:
+		s = v
+	case int64:
+		s = strconv.FormatInt(v, 10)
+	case float64:
+		s = strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		return "", false
+	}
+
+	r, ok := new(big.Rat).SetString(s)
+	if !ok || c.Unsigned && r.Sign() < 0 {
+		return "", false
+	}
+	if c.Precision == 0 {
+		return s, true
+	}
+
+	// the value must fit without rounding: at most Scale digits after the point
+	// and Precision digits in total
+	scaled := new(big.Rat).Mul(r, new(big.Rat).SetInt(new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(c.Scale)), nil)))
+	if !scaled.IsInt() {
+		return "", false
+	}
+	digits := len(new(big.Int).Abs(scaled.Num()).String())
+	if digits > c.Precision {
+		return "", false
+	}
+	return r.FloatString(c.Scale), true
+}
+
+func (c DecimalColumn) IsValidValue(val interface{}) bool {
+	if val == nil {
+		return c.Null
+	}
+
+	_, ok := c.parse(val)
+	return ok
+}
+
+func (c DecimalColumn) DBValue(val interface{}) interface{} {
+	s, _ := c.parse(val)
+	return s
+}
+
+func (c DecimalColumn) ZeroValue() interface{} {
+	return "0"
+}
+
+type BoolColumn struct {
+	Null bool
+}
+
+func (c BoolColumn) NewVar() interface{} {
+	return newVar[bool](c.Null)
+}
+
+func (c BoolColumn) IsValidValue(val interface{}) bool {
+	if val == nil {
+		return c.Null
+	}
+
+	_, ok := val.(bool)
+	return ok
+}
+
+func (c BoolColumn) DBValue(val interface{}) interface{} {
+	return val
+}
+
+func (c BoolColumn) ZeroValue() interface{} {
+	return false
+}
+
+const (
+	DateLayout      = "2006-01-02"
+	TimeOfDayLayout = "15:04:05.999999"
+	DateTimeLayout  = "2006-01-02 15:04:05.999999"
+)
+
+// timeLayouts are accepted in request bodies and from drivers returning time as text
+var timeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02 15:04:05.999999999Z07:00",
+	"2006-01-02 15:04:05.999999999",
+	DateLayout,
+	"15:04:05.999999999",
+}
+
+func parseTime(s string) (time.Time, bool) {
+	for _, layout := range timeLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// Time is a DATE, TIME, DATETIME or TIMESTAMP value, encoded to JSON
+// as a string with the layout of its column
+type Time struct {
+	Time   time.Time
+	Valid  bool
+	Layout string
+}
+
+func (t *Time) Scan(src interface{}) error {
+	t.Valid = src != nil
+	switch v := src.(type) {
+	case nil:
+		t.Time = time.Time{}
+	case time.Time:
+		t.Time = v
+	case []byte:
+		return t.Scan(string(v))
+	case string:
+		parsed, ok := parseTime(v)
+		if !ok {
+			return fmt.Errorf("can't parse time %q", v)
+		}
+		t.Time = parsed
+	default:
+		return fmt.Errorf("can't scan %T into Time", src)
+	}
+	return nil
+}
+
+func (t Time) MarshalJSON() ([]byte, error) {
+	if !t.Valid {
+		return []byte("null"), nil
+	}
+	return json.Marshal(t.Time.Format(t.Layout))
+}
+
+type TimeColumn struct {
+	Null   bool
+	Layout string
+}
+
+func (c TimeColumn) NewVar() interface{} {
+	return &Time{Layout: c.Layout}
+}
+
+func (c TimeColumn) IsValidValue(val interface{}) bool {
+	if val == nil {
+		return c.Null
+	}
+
+	s, ok := val.(string)
+	if !ok {
+		return false
+	}
+	_, ok = parseTime(s)
+	return ok
+}
+
+func (c TimeColumn) DBValue(val interface{}) interface{} {
+	t, _ := parseTime(val.(string))
+	return t.Format(c.Layout)
+}
+
+func (c TimeColumn) ZeroValue() interface{} {
+	return time.Time{}.Format(c.Layout)
+}
+
+// RawJSON is a JSON column value, it's put into responses as is
+type RawJSON []byte
+
+func (j *RawJSON) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case nil:
+		*j = nil
+	case []byte:
+		*j = append((*j)[:0], v...)
+	case string:
+		*j = RawJSON(v)
+	default:
+		return fmt.Errorf("can't scan %T into RawJSON", src)
+	}
+	return nil
+}
+
+func (j RawJSON) MarshalJSON() ([]byte, error) {
+	if j == nil {
+		return []byte("null"), nil
+	}
+	return j, nil
+}
+
+type JSONColumn struct {
+	Null bool
+}
+
+func (c JSONColumn) NewVar() interface{} {
+	return new(RawJSON)
+}
+
+// IsValidValue accepts any JSON document, SQL NULL is only stored for nullable columns
+func (c JSONColumn) IsValidValue(val interface{}) bool {
+	if val == nil {
+		return c.Null
+	}
+	return true
+}
+
+func (c JSONColumn) DBValue(val interface{}) interface{} {
+	if val == nil {
+		return nil
+	}
+	data, _ := json.Marshal(val)
+	return string(data)
+}
+
+func (c JSONColumn) ZeroValue() interface{} {
+	return "null"
+}
+
+// Blob is a BLOB value, encoded to JSON as base64 like encoding/json does for []byte.
+// sqlite returns an empty blob as a nil slice, it's still "", only NULL is null
+type Blob []byte
+
+func (b *Blob) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case nil:
+		*b = nil
+	case []byte:
+		*b = append(Blob{}, v...)
+	case string:
+		*b = Blob(v)
+	default:
+		return fmt.Errorf("can't scan %T into Blob", src)
+	}
+	return nil
+}
+
+func (b Blob) MarshalJSON() ([]byte, error) {
+	if b == nil {
+		return []byte("null"), nil
+	}
+	return json.Marshal([]byte(b))
+}
+
+type BlobColumn struct {
+	Null bool
+}
+
+func (c BlobColumn) NewVar() interface{} {
+	return new(Blob)
+}
+
+func (c BlobColumn) IsValidValue(val interface{}) bool {
+	if val == nil {
+		return c.Null
+	}
+
+	s, ok := val.(string)
+	if !ok {
+		return false
+	}
+	_, err := base64.StdEncoding.DecodeString(s)
+	return err == nil
+}
+
+func (c BlobColumn) DBValue(val interface{}) interface{} {
+	data, _ := base64.StdEncoding.DecodeString(val.(string))
+	return data
+}
+
+func (c BlobColumn) ZeroValue() interface{} {
+	return []byte{}
+}
+
+type StringColumn struct {
+	Null bool
+	// Size is the maximum length in characters, 0 is unlimited
+	Size int
+	Enum []string
+}
+
+func (c StringColumn) NewVar() interface{} {
+	return newVar[string](c.Null)
+}
+
+func (c StringColumn) IsValidValue(val interface{}) bool {
+	if val == nil {
+		return c.Null
+	}
+
+	s, ok := val.(string)
+	if !ok {
+		return false
+	}
+	if c.Size > 0 && utf8.RuneCountInString(s) > c.Size {
+		return false
+	}
+	if len(c.Enum) > 0 {
+		for _, v := range c.Enum {
+			if v == s {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+func (c StringColumn) DBValue(val interface{}) interface{} {
+	return val
+}
+
+func (c StringColumn) ZeroValue() interface{} {
+	if len(c.Enum) > 0 {
+		return c.Enum[0]
+	}
+	return ""
+}
@@ -0,0 +1,253 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseSQLType(t *testing.T) {
+	cases := map[string]SQLType{
+		"int(11)":                     {Name: "int", Args: []string{"11"}},
+		"bigint(20) unsigned":         {Name: "bigint", Args: []string{"20"}, Unsigned: true},
+		"DECIMAL(10,2)":               {Name: "decimal", Args: []string{"10", "2"}},
+		"enum('a,b','c')":             {Name: "enum", Args: []string{"a,b", "c"}},
+		"timestamp without time zone": {Name: "timestamp without time zone"},
+		"INTEGER":                     {Name: "integer"},
+	}
+	for dataType, expected := range cases {
+		if got := ParseSQLType(dataType); !reflect.DeepEqual(got, expected) {
+			t.Errorf("[%s] expected %+v, got %+v", dataType, expected, got)
+		}
+	}
+}
+
+func TestColumnTypeValidation(t *testing.T) {
+	registry := NewColumnTypeRegistry()
+
+	cases := []struct {
+		dataType string
+		null     bool
+		value    interface{}
+		valid    bool
+	}{
+		{"int(11)", false, json.Number("42"), true},
+		{"int(11)", false, json.Number("4.2"), false},
+		{"int(11)", false, json.Number("2147483648"), false},
+		{"int(11)", false, nil, false},
+		{"int(11)", true, nil, true},
+		{"tinyint(4)", false, json.Number("-128"), true},
+		{"tinyint(3) unsigned", false, json.Number("-1"), false},
+		{"bigint(20) unsigned", false, json.Number("18446744073709551615"), true},
+		{"tinyint(1)", false, true, true},
+		{"tinyint(1)", false, json.Number("1"), false},
+		{"double", false, json.Number("1.5e3"), true},
+		{"double", false, "1.5", false},
+		{"decimal(5,2)", false, json.Number("999.99"), true},
+		{"decimal(5,2)", false, json.Number("1000"), false},
+		{"decimal(5,2)", false, json.Number("1.005"), false},
+		{"decimal(5,2)", false, "12.5", true},
+		{"datetime", false, "2021-05-06 07:08:09", true},
+		{"datetime", false, "2021-05-06T07:08:09Z", true},
+		{"date", false, "yesterday", false},
+		{"json", false, map[string]interface{}{"a": json.Number("1")}, true},
+		{"blob", false, "aGVsbG8=", true},
+		{"blob", false, "not base64!", false},
+		{"varchar(5)", false, "hello", true},
+		{"varchar(5)", false, "hello!", false},
+		{"enum('user','admin')", false, "admin", true},
+		{"enum('user','admin')", false, "root", false},
+		{"text", false, json.Number("42"), false},
+	}
+	for _, c := range cases {
+		if got := registry.Lookup(c.dataType, c.null).IsValidValue(c.value); got != c.valid {
+			t.Errorf("[%s null=%v] %#v: expected valid=%v, got %v", c.dataType, c.null, c.value, c.valid, got)
+		}
+	}
+}
+
+func TestColumnTypeJSON(t *testing.T) {
+	decimal := Decimal("12345678901234567890.12")
+	price, _ := json.Marshal(&decimal)
+	if string(price) != "12345678901234567890.12" {
+		t.Errorf("decimal lost precision: %s", price)
+	}
+
+	var born Time
+	born.Layout = DateLayout
+	if err := born.Scan([]byte("2020-01-02 03:04:05")); err != nil {
+		t.Fatalf("cant scan time: %v", err)
+	}
+	data, _ := json.Marshal(born)
+	if string(data) != `"2020-01-02"` {
+		t.Errorf("expected date, got %s", data)
+	}
+}
+
+// в items колонки всех типов, которые не строки и не целые
+var createColumnTypesTable = map[string]string{
+	"mysql": `CREATE TABLE items (
+  id int(11) NOT NULL AUTO_INCREMENT,
+  price decimal(22,2) NOT NULL,
+  weight double NOT NULL,
+  created datetime DEFAULT NULL,
+  meta json NOT NULL,
+  photo blob NOT NULL,
+  active tinyint(1) NOT NULL,
+  PRIMARY KEY (id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;`,
+	"postgres": `CREATE TABLE items (
+  id serial PRIMARY KEY,
+  price numeric(22,2) NOT NULL,
+  weight double precision NOT NULL,
+  created timestamp DEFAULT NULL,
+  meta json NOT NULL,
+  photo bytea NOT NULL,
+  active boolean NOT NULL
+);`,
+	"sqlite": `CREATE TABLE items (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  price DECIMAL(22,2) NOT NULL,
+  weight DOUBLE NOT NULL,
+  created DATETIME DEFAULT NULL,
+  meta JSON NOT NULL,
+  photo BLOB NOT NULL,
+  active BOOLEAN NOT NULL
+);`,
+}
+
+func PrepareColumnTypesTable(db *sql.DB) {
+	qs := []string{
+		`DROP TABLE IF EXISTS items;`,
+		createColumnTypesTable[Driver],
+	}
+	for _, q := range qs {
+		_, err := db.Exec(q)
+		if err != nil {
+			panic(err)
+		}
+	}
+}
+
+// значения проходят через базу туда и обратно, decimal при этом не теряет знаков:
+// в float32 9999999999999.99 не влезает, а sqlite хранит decimal как REAL,
+// поэтому больше 15 знаков проверяется только на mysql и postgres
+func TestColumnTypesHTTP(t *testing.T) {
+	ts, _, db := newTestServer(t, PrepareColumnTypesTable)
+
+	do := func(method, path, body string) (int, string) {
+		req, err := http.NewRequest(method, ts.URL+path, strings.NewReader(body))
+		if err != nil {
+			panic(err)
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("[%s %s] request error: %v", method, path, err)
+		}
+		defer resp.Body.Close()
+		data, _ := io.ReadAll(resp.Body)
+
+		if db.Stats().OpenConnections != 1 {
+			t.Fatalf("[%s %s] you have %d open connections, must be 1", method, path, db.Stats().OpenConnections)
+		}
+		return resp.StatusCode, string(data)
+	}
+
+	status, body := do(http.MethodPut, "/items", `{
+		"price": 9999999999999.99,
+		"weight": 1.5,
+		"created": "2021-05-06T07:08:09Z",
+		"meta": {"tags": ["a", "b"], "n": 1},
+		"photo": "aGVsbG8=",
+		"active": true
+	}`)
+	if status != http.StatusOK || body != `{"response":{"id":1}}` {
+		t.Fatalf("create: got %d %s", status, body)
+	}
+	status, body = do(http.MethodGet, "/items/1", "")
+	expected := `{"response":{"record":{"active":true,"created":"2021-05-06 07:08:09","id":1,` +
+		`"meta":{"n":1,"tags":["a","b"]},"photo":"aGVsbG8=","price":9999999999999.99,"weight":1.5}}}`
+	if status != http.StatusOK || body != expected {
+		t.Fatalf("get created:\nGot : %d %s\nWant: %s", status, body, expected)
+	}
+
+	// незаданные NOT NULL колонки получают нулевые значения, created остается null
+	status, body = do(http.MethodPut, "/items", `{"price": "-0.5"}`)
+	if status != http.StatusOK || body != `{"response":{"id":2}}` {
+		t.Fatalf("create with defaults: got %d %s", status, body)
+	}
+	status, body = do(http.MethodGet, "/items/2", "")
+	expected = `{"response":{"record":{"active":false,"created":null,"id":2,` +
+		`"meta":null,"photo":"","price":-0.50,"weight":0}}}`
+	if status != http.StatusOK || body != expected {
+		t.Fatalf("get defaults:\nGot : %d %s\nWant: %s", status, body, expected)
+	}
+
+	status, body = do(http.MethodPost, "/items/1", `{
+		"price": 0.1,
+		"weight": 0.1,
+		"created": "2022-01-02 03:04:05",
+		"meta": [1, "x", null],
+		"photo": "",
+		"active": false
+	}`)
+	if status != http.StatusOK || body != `{"response":{"updated":1}}` {
+		t.Fatalf("update: got %d %s", status, body)
+	}
+	status, body = do(http.MethodGet, "/items/1", "")
+	expected = `{"response":{"record":{"active":false,"created":"2022-01-02 03:04:05","id":1,` +
+		`"meta":[1,"x",null],"photo":"","price":0.10,"weight":0.1}}}`
+	if status != http.StatusOK || body != expected {
+		t.Fatalf("get updated:\nGot : %d %s\nWant: %s", status, body, expected)
+	}
+
+	if Driver != "sqlite" {
+		status, body = do(http.MethodPost, "/items/2", `{"price": 12345678901234567890.12}`)
+		if status != http.StatusOK || body != `{"response":{"updated":1}}` {
+			t.Fatalf("update of a long decimal: got %d %s", status, body)
+		}
+		status, body = do(http.MethodGet, "/items/2", "")
+		if status != http.StatusOK || !strings.Contains(body, `"price":12345678901234567890.12,`) {
+			t.Fatalf("long decimal lost precision: got %d %s", status, body)
+		}
+	}
+
+	bad := []struct {
+		field string
+		value string
+	}{
+		{"price", `"abc"`},
+		{"price", `1.005`},
+		{"price", `123456789012345678901.1`},
+		{"weight", `"1.5"`},
+		{"created", `"yesterday"`},
+		{"created", `20210506`},
+		{"meta", `null`},
+		{"photo", `"not base64!"`},
+		{"active", `1`},
+	}
+	for _, b := range bad {
+		record := fmt.Sprintf(`{"%s": %s}`, b.field, b.value)
+		errBody := fmt.Sprintf(`{"error":"field %s have invalid type"}`, b.field)
+		for _, r := range []struct{ method, path string }{
+			{http.MethodPut, "/items"},
+			{http.MethodPost, "/items/1"},
+		} {
+			status, body := do(r.method, r.path, record)
+			if status != http.StatusBadRequest || body != errBody {
+				t.Fatalf("[%s %s] %s: got %d %s", r.method, r.path, record, status, body)
+			}
+		}
+	}
+
+	// после отвергнутых запросов запись не изменилась
+	status, body = do(http.MethodGet, "/items/1", "")
+	if status != http.StatusOK || body != expected {
+		t.Fatalf("get after bad values:\nGot : %d %s\nWant: %s", status, body, expected)
+	}
+}
@@ -3,8 +3,8 @@ package main
 import (
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"io/ioutil"
 	"net/http"
 	"sort"
 	"strconv"
@@ -13,55 +13,9 @@ import (
 
 const defaultLimit = 5
 
-type IntColumn struct {
-	Null bool
-}
-
-type StringColumn struct {
-	Null bool
-}
-
-type ColumnType interface {
-	NewVar() interface{}
-	IsValidValue(val interface{}) bool
-}
-
-func (c IntColumn) NewVar() interface{} {
-	if c.Null {
-		return new(*int64)
-	} else {
-		return new(int64)
-	}
-}
-
-func (c IntColumn) IsValidValue(val interface{}) bool {
-	if val == nil {
-		return c.Null
-	}
-
-	_, ok := val.(int64)
-	return ok
-}
-
-func (c StringColumn) NewVar() interface{} {
-	if c.Null {
-		return new(*string)
-	} else {
-		return new(string)
-	}
-}
-
-func (c StringColumn) IsValidValue(val interface{}) bool {
-	if val == nil {
-		return c.Null
-	}
-
-	_, ok := val.(string)
-	return ok
-}
-
 type TableColumn struct {
 	Field      string
+	DataType   string
 	Type       ColumnType
 	Collation  interface{}
 	Null       bool
@@ -73,23 +27,52 @@ type TableColumn struct {
 }
 
 type Table struct {
-	Name    string
-	Pk      string
-	Columns []TableColumn
+	Name        string
+	Pk          string
+	Columns     []TableColumn
+	ForeignKeys []ForeignKey
+	Relations   map[string]Relation
 }
 
 type DbExplorer struct {
-	db     *sql.DB
-	tables map[string]Table
-}
-
+	db *sql.DB
+	// conn runs the queries of requests, it's the transaction inside /_batch
+	conn        Querier
+	dialect     Dialect
+	columnTypes *ColumnTypeRegistry
+	schema      *schemaState
+	access      *AccessConfig
+	readOnly    bool
+	// versionColumn is the column tables may have for ETags, see SetVersionColumn
+	versionColumn string
+	// current is the schema snapshot of the request being handled, tables are
+	// its tables as seen by the api key
+	current *Schema
+	tables  map[string]Table
+	// key is the access of the request's api key, nil if access control is off
+	key *KeyAccess
+}
+
+// NewDbExplorer detects the dialect by the driver db was opened with
 func NewDbExplorer(db *sql.DB) (*DbExplorer, error) {
-	dbExplorer := &DbExplorer{db: db}
-	tables, err := dbExplorer.GetTables()
+	dialect, err := DetectDialect(db)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create DbExplorer: %s", err)
 	}
-	dbExplorer.tables = tables
+	return NewDbExplorerWithDialect(db, dialect)
+}
+
+func NewDbExplorerWithDialect(db *sql.DB, dialect Dialect) (*DbExplorer, error) {
+	dbExplorer := &DbExplorer{
+		db:          db,
+		conn:        db,
+		dialect:     dialect,
+		columnTypes: NewColumnTypeRegistry(),
+		schema:      &schemaState{},
+	}
+	if _, _, err := dbExplorer.ReloadSchema(); err != nil {
+		return nil, fmt.Errorf("failed to create DbExplorer: %s", err)
+	}
 	return dbExplorer, nil
 }
 
@@ -106,9 +89,20 @@ func (d *DbExplorer) GetTables() (map[string]Table, error) {
 			return nil, fmt.Errorf("failed to get tables: %s", err)
 		}
 
+		fks, err := d.dialect.ForeignKeys(d.db, name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get foreign keys for table '%s': %s", name, err)
+		}
+		// relation names depend on the order, the databases list keys differently
+		sort.Slice(fks, func(i, j int) bool {
+			return fks[i].Column < fks[j].Column
+		})
+
 		table := Table{
-			Name:    name,
-			Columns: columns,
+			Name:        name,
+			Columns:     columns,
+			ForeignKeys: fks,
+			Relations:   map[string]Relation{},
 		}
 
 		for _, col := range columns {
@@ -119,68 +113,43 @@ func (d *DbExplorer) GetTables() (map[string]Table, error) {
 		}
 		tables[name] = table
 	}
+	linkRelations(tables)
 	return tables, nil
 }
 
-func (d *DbExplorer) GetTableNames() (tables []string, err error) {
-	rows, err := d.db.Query("SHOW TABLES")
-	if err != nil {
-		return nil, fmt.Errorf("failed to fetch table names: %s", err)
+func sortedTableNames(tables map[string]Table) []string {
+	names := make([]string, 0, len(tables))
+	for name := range tables {
+		names = append(names, name)
 	}
-	defer rows.Close()
+	sort.Strings(names)
+	return names
+}
 
-	var t string
-	for rows.Next() {
-		rows.Scan(&t)
-		tables = append(tables, t)
+func (d *DbExplorer) GetTableNames() ([]string, error) {
+	tables, err := d.dialect.TableNames(d.db)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch table names: %s", err)
 	}
-
-	return
+	return tables, nil
 }
 
-func (d *DbExplorer) GetTableColumns(table string) (columns []TableColumn, err error) {
-	rows, err := d.db.Query("SHOW FULL COLUMNS FROM " + table)
+func (d *DbExplorer) GetTableColumns(table string) ([]TableColumn, error) {
+	columns, err := d.dialect.TableColumns(d.db, table)
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch columns for table '%s': %s", table, err)
 	}
-	defer rows.Close()
-
-	var (
-		colType string
-		colNull string
-		isNull  bool
-	)
-
-	for rows.Next() {
-		col := TableColumn{}
-		rows.Scan(
-			&col.Field,
-			&colType,
-			&col.Collation,
-			&colNull,
-			&col.Key,
-			&col.Default,
-			&col.Extra,
-			&col.Privileges,
-			&col.Comment,
-		)
-
-		isNull = colNull == "YES"
-		if strings.Contains(colType, "int") {
-			col.Type = IntColumn{isNull}
-		} else {
-			col.Type = StringColumn{isNull}
-		}
 
-		columns = append(columns, col)
+	for i, col := range columns {
+		columns[i].Type = d.columnTypes.Lookup(col.DataType, col.Null)
 	}
-
-	return
+	return columns, nil
 }
 
 type Response struct {
-	Data  interface{} `json:"response,omitempty"`
-	Error string      `json:"error,omitempty"`
+	Data    interface{} `json:"response,omitempty"`
+	Error   string      `json:"error,omitempty"`
+	Details interface{} `json:"details,omitempty"`
 }
 
 type ResponseError struct {
@@ -192,10 +161,22 @@ func (e ResponseError) Error() string {
 	return e.Text
 }
 
+// DetailedResponseError puts Details into the response next to the error text
+type DetailedResponseError struct {
+	ResponseError
+	Details interface{}
+}
+
+func (e DetailedResponseError) Unwrap() error {
+	return e.ResponseError
+}
+
 type Request struct {
 	request  *http.Request
 	Table    *Table
 	RecordId *int
+	// Relation is set for /$table/$id/$relation
+	Relation *Relation
 }
 
 func (e *DbExplorer) newRequest(r *http.Request) (*Request, error) {
@@ -223,6 +204,14 @@ func (e *DbExplorer) newRequest(r *http.Request) (*Request, error) {
 		}
 	}
 
+	if len(urlParts) >= 3 && req.RecordId != nil {
+		rel, ok := req.Table.Relations[urlParts[2]]
+		if !ok || !rel.Many {
+			return nil, ResponseError{"unknown relation", http.StatusNotFound}
+		}
+		req.Relation = &rel
+	}
+
 	return req, nil
 }
 
@@ -231,15 +220,8 @@ type GetTablesResponse struct {
 }
 
 func (e *DbExplorer) handleGetTables() (*GetTablesResponse, error) {
-	tables := make([]string, 0, len(e.tables))
-	for table, _ := range e.tables {
-		tables = append(tables, table)
-	}
-
-	sort.Strings(tables)
-
 	return &GetTablesResponse{
-		Tables: tables,
+		Tables: sortedTableNames(e.tables),
 	}, nil
 }
 
@@ -288,9 +270,75 @@ func (t *Table) NewRecord(row TableRow) TableRecord {
 	return record
 }
 
-func (e *DbExplorer) handleGetTableRecords(table Table, limit int, offset int) (*GetTableRecordsResponse, error) {
-	q := fmt.Sprintf("SELECT * FROM %s  LIMIT ? OFFSET ?", table.Name)
-	rows, err := e.db.Query(q, limit, offset)
+// quote returns a quoted identifier for the SQL dialect of the database
+func (e *DbExplorer) quote(name string) string {
+	return e.dialect.QuoteIdent(name)
+}
+
+// selectList names table columns explicitly, so rows are scanned in the introspected order
+func (e *DbExplorer) selectList(table Table) string {
+	cols := make([]string, len(table.Columns))
+	for i, col := range table.Columns {
+		cols[i] = e.quote(col.Field)
+	}
+	return strings.Join(cols, ", ")
+}
+
+func (e *DbExplorer) handleGetTableRecords(table Table, lq *ListQuery) (*GetTableRecordsResponse, error) {
+	selected, err := table.Select(lq.Fields)
+	if err != nil {
+		return nil, err
+	}
+
+	// embedded records are matched by columns which may be left out by ?fields
+	var linkColumns []string
+	for _, rel := range lq.Embed {
+		if _, ok := selected.Column(rel.Column); !ok {
+			c, _ := table.Column(rel.Column)
+			selected.Columns = append(selected.Columns, c)
+			linkColumns = append(linkColumns, rel.Column)
+		}
+	}
+
+	q, args := e.selectQuery(table, selected, lq)
+	records, err := e.queryRecords(selected, q, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := e.embed(records, lq.Embed); err != nil {
+		return nil, err
+	}
+	for _, record := range records {
+		for _, name := range linkColumns {
+			delete(record, name)
+		}
+	}
+
+	return &GetTableRecordsResponse{
+		Records: records,
+	}, nil
+}
+
+// selectQuery builds the SELECT of the selected columns of the table for lq
+func (e *DbExplorer) selectQuery(table Table, selected Table, lq *ListQuery) (string, []interface{}) {
+	where, args := e.where(lq.Filters, nil)
+	args = append(args, lq.Limit, lq.Offset)
+	q := fmt.Sprintf(
+		"SELECT %s FROM %s%s%s LIMIT %s OFFSET %s",
+		e.selectList(selected),
+		e.quote(table.Name),
+		where,
+		e.orderBy(table, lq.Order),
+		e.dialect.Placeholder(len(args)-1),
+		e.dialect.Placeholder(len(args)),
+	)
+	return q, args
+}
+
+// queryRecords runs a SELECT of the table columns and scans the rows into records
+func (e *DbExplorer) queryRecords(table Table, q string, args ...interface{}) ([]TableRecord, error) {
+	rows, err := e.conn.Query(q, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -305,46 +353,75 @@ func (e *DbExplorer) handleGetTableRecords(table Table, limit int, offset int) (
 
 		records = append(records, table.NewRecord(row))
 	}
-
-	return &GetTableRecordsResponse{
-		Records: records,
-	}, nil
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return records, nil
 }
 
 type GetTableRecordResponse struct {
 	Record TableRecord `json:"record"`
+	etag   string
 }
 
 func (e *DbExplorer) handleGetTableRecord(table Table, id int) (*GetTableRecordResponse, error) {
-	q := fmt.Sprintf("SELECT * FROM %s WHERE %s = ?", table.Name, table.Pk)
-	row := e.db.QueryRow(q, id)
+	full := e.fullTable(table)
+	record, err := e.loadRecord(full, id, false)
+	if err != nil {
+		return nil, err
+	}
+	etag, err := e.recordETag(full, record)
+	if err != nil {
+		return nil, err
+	}
 
-	r := table.NewRow()
-	if err := row.Scan(r...); err != nil {
-		return nil, ResponseError{"record not found", http.StatusNotFound}
+	// the record is read with all columns for the version, the key gets its own
+	for field := range record {
+		if _, ok := table.Column(field); !ok {
+			delete(record, field)
+		}
 	}
 
 	return &GetTableRecordResponse{
-		Record: table.NewRecord(r),
+		Record: record,
+		etag:   etag,
 	}, nil
 }
 
+// GetRecordData decodes numbers as json.Number, so column types
+// can check them without losing precision
 func (r *Request) GetRecordData() (record TableRecord, err error) {
-	body, err := ioutil.ReadAll(r.request.Body)
-	if err != nil {
-		return
+	decoder := json.NewDecoder(r.request.Body)
+	decoder.UseNumber()
+	if err := decoder.Decode(&record); err != nil {
+		return nil, ResponseError{"invalid json body", http.StatusBadRequest}
 	}
-
-	err = json.Unmarshal(body, &record)
-	return
+	return record, nil
 }
 
 type PutTableRecordResponse map[string]int
 
 func (e *DbExplorer) handlePutTableRecord(table Table, data TableRecord) (*PutTableRecordResponse, error) {
+	if err := table.ValidateNewRecord(data); err != nil {
+		return nil, err
+	}
+
+	id, err := e.insertRecord(table, data)
+	if err != nil {
+		return nil, err
+	}
+
+	return &PutTableRecordResponse{
+		table.Pk: int(id),
+	}, nil
+}
+
+// insertRecord inserts a validated record, the primary key is left to the database
+func (e *DbExplorer) insertRecord(table Table, data TableRecord) (int64, error) {
 	var (
-		inCols []string
-		inVals []interface{}
+		inCols         []string
+		inPlaceholders []string
+		inVals         []interface{}
 	)
 
 	for _, col := range table.Columns {
@@ -352,40 +429,38 @@ func (e *DbExplorer) handlePutTableRecord(table Table, data TableRecord) (*PutTa
 			continue
 		}
 
-		inCols = append(inCols, col.Field)
-		if val, ok := data[col.Field]; ok {
-			inVals = append(inVals, val)
-		} else {
-			inVals = append(inVals, col.Type.NewVar())
+		val, ok := data[col.Field]
+		switch {
+		case ok:
+			val = col.DBValue(val)
+		case col.HasDefault():
+			// let the database fill it in
+			continue
+		default:
+			val = col.Type.ZeroValue()
 		}
+
+		inCols = append(inCols, e.quote(col.Field))
+		inPlaceholders = append(inPlaceholders, e.dialect.Placeholder(len(inCols)))
+		inVals = append(inVals, val)
 	}
 
 	q := fmt.Sprintf(
 		"INSERT INTO %s (%s) VALUES (%s)",
-		table.Name,
+		e.quote(table.Name),
 		strings.Join(inCols, ", "),
-		strings.Join(strings.Split(strings.Repeat("?", len(inCols)), ""), ", "),
+		strings.Join(inPlaceholders, ", "),
 	)
 
-	res, err := e.db.Exec(q, inVals...)
-	if err != nil {
-		return nil, err
-	}
-
-	id, err := res.LastInsertId()
-	if err != nil {
-		return nil, err
-	}
-
-	return &PutTableRecordResponse{
-		table.Pk: int(id),
-	}, nil
+	return e.dialect.Insert(e.conn, q, table.Pk, inVals...)
 }
 
 type PostTableRecordResponse struct {
 	Updated int `json:"updated"`
+	etag    string
 }
 
+// ValidateRecord checks values for an update, the primary key can't be changed
 func (t *Table) ValidateRecord(record TableRecord) error {
 	for _, c := range t.Columns {
 		if v, ok := record[c.Field]; ok {
@@ -398,6 +473,32 @@ func (t *Table) ValidateRecord(record TableRecord) error {
 	return nil
 }
 
+// ValidateNewRecord checks values for an insert, the primary key is ignored
+func (t *Table) ValidateNewRecord(record TableRecord) error {
+	for _, c := range t.Columns {
+		if v, ok := record[c.Field]; ok && c.Field != t.Pk {
+			if !c.Type.IsValidValue(v) {
+				return NewValidationError(c.Field)
+			}
+		}
+	}
+
+	return nil
+}
+
+// DBValue converts a valid value from a request body to a query argument
+func (c TableColumn) DBValue(val interface{}) interface{} {
+	if val == nil {
+		return nil
+	}
+	return c.Type.DBValue(val)
+}
+
+// HasDefault reports whether the database fills the column in when it's omitted in INSERT
+func (c TableColumn) HasDefault() bool {
+	return c.Null || c.Default != nil || c.Extra == "auto_increment"
+}
+
 func NewValidationError(field string) ResponseError {
 	return ResponseError{
 		Text:       fmt.Sprintf("field %s have invalid type", field),
@@ -409,26 +510,41 @@ func (e *DbExplorer) handlePostTableRecord(table Table, id int, data TableRecord
 	if err := table.ValidateRecord(data); err != nil {
 		return nil, err
 	}
+	// the version is changed only by the database
+	version, hasVersion := e.versionOf(e.fullTable(table))
+	if _, set := data[version.Field]; hasVersion && set {
+		return nil, NewValidationError(version.Field)
+	}
 
 	var (
 		uSets []string
 		uVals []interface{}
 	)
 
-	for k, v := range data {
-		uSets = append(uSets, fmt.Sprintf("%s = ?", k))
-		uVals = append(uVals, v)
+	// unknown fields are ignored, only introspected columns get into the query
+	for _, col := range table.Columns {
+		if v, ok := data[col.Field]; ok {
+			uVals = append(uVals, col.DBValue(v))
+			uSets = append(uSets, fmt.Sprintf("%s = %s", e.quote(col.Field), e.dialect.Placeholder(len(uVals))))
+		}
+	}
+	if len(uSets) == 0 {
+		return &PostTableRecordResponse{}, nil
+	}
+	if hasVersion {
+		uSets = append(uSets, fmt.Sprintf("%s = %s + 1", e.quote(version.Field), e.quote(version.Field)))
 	}
 	uVals = append(uVals, id)
 
 	q := fmt.Sprintf(
-		"UPDATE %s SET %s WHERE %s = ?",
-		table.Name,
+		"UPDATE %s SET %s WHERE %s = %s",
+		e.quote(table.Name),
 		strings.Join(uSets, ", "),
-		table.Pk,
+		e.quote(table.Pk),
+		e.dialect.Placeholder(len(uVals)),
 	)
 
-	res, err := e.db.Exec(q, uVals...)
+	res, err := e.conn.Exec(q, uVals...)
 	if err != nil {
 		return nil, err
 	}
@@ -448,8 +564,13 @@ type DeleteTableRecordResponse struct {
 }
 
 func (e *DbExplorer) handleDeleteTableRecord(table Table, id int) (*DeleteTableRecordResponse, error) {
-	q := fmt.Sprintf("DELETE FROM %s WHERE %s = ?", table.Name, table.Pk)
-	res, err := e.db.Exec(q, id)
+	q := fmt.Sprintf(
+		"DELETE FROM %s WHERE %s = %s",
+		e.quote(table.Name),
+		e.quote(table.Pk),
+		e.dialect.Placeholder(1),
+	)
+	res, err := e.conn.Exec(q, id)
 	if err != nil {
 		return nil, err
 	}
@@ -465,6 +586,31 @@ func (e *DbExplorer) handleDeleteTableRecord(table Table, id int) (*DeleteTableR
 }
 
 func (e *DbExplorer) handleRequest(r *http.Request) (interface{}, error) {
+	access, err := e.keyAccess(r)
+	if err != nil {
+		return nil, err
+	}
+
+	if strings.HasPrefix(r.URL.Path, "/_schema") {
+		if access != nil && !access.Admin {
+			return nil, ResponseError{"access denied", http.StatusForbidden}
+		}
+		return e.handleSchema(r)
+	}
+	if r.URL.Path == "/graphql" {
+		// fields are authorized by the resolvers
+		return e.handleGraphQL(access, r)
+	}
+	if r.URL.Path == "/_batch" {
+		// operations are authorized one by one
+		return e.handleBatch(r)
+	}
+
+	e, err = e.authorize(access, r)
+	if err != nil {
+		return nil, err
+	}
+
 	req, err := e.newRequest(r)
 	if err != nil {
 		return nil, err
@@ -476,32 +622,108 @@ func (e *DbExplorer) handleRequest(r *http.Request) (interface{}, error) {
 			return e.handleGetTables()
 		}
 
+		format, err := req.GetFormat()
+		if err != nil {
+			return nil, err
+		}
+
+		if req.Relation != nil {
+			if format != "" {
+				return nil, NewUnsupportedFormatError(format)
+			}
+			lq, err := req.GetListQuery(e.tables[req.Relation.Table])
+			if err != nil {
+				return nil, err
+			}
+			return e.handleGetRelatedRecords(*req.Table, *req.RecordId, *req.Relation, lq)
+		}
+
 		if req.RecordId == nil {
-			limit, offset := req.GetLimitOffset()
-			return e.handleGetTableRecords(*req.Table, limit, offset)
+			lq, err := req.GetListQuery(*req.Table)
+			if err != nil {
+				return nil, err
+			}
+			if format != "" {
+				return e.handleExportTableRecords(*req.Table, lq, format)
+			}
+			return e.handleGetTableRecords(*req.Table, lq)
+		}
+		if format != "" {
+			return nil, NewUnsupportedFormatError(format)
 		}
 
-		return e.handleGetTableRecord(*req.Table, *req.RecordId)
+		embed, err := req.GetEmbed(*req.Table)
+		if err != nil {
+			return nil, err
+		}
+		res, err := e.handleGetTableRecord(*req.Table, *req.RecordId)
+		if err != nil {
+			return nil, err
+		}
+		if err := e.embed([]TableRecord{res.Record}, embed); err != nil {
+			return nil, err
+		}
+		return res, nil
 	case http.MethodPut:
-		if req.Table != nil {
+		if req.Table != nil && req.Relation == nil {
+			format, err := req.GetFormat()
+			if err != nil {
+				return nil, err
+			}
+			if format != "" {
+				var check func(TableRecord) error
+				if access != nil {
+					check = func(record TableRecord) error {
+						return access.CheckRecord(req.Table.Name, PermissionInsert, record)
+					}
+				}
+				return e.handleImportTableRecords(*req.Table, format, r.Body, check)
+			}
+
 			data, err := req.GetRecordData()
 			if err != nil {
 				return nil, err
 			}
+			if access != nil {
+				if err := access.CheckRecord(req.Table.Name, PermissionInsert, data); err != nil {
+					return nil, err
+				}
+			}
 
 			return e.handlePutTableRecord(*req.Table, data)
 		}
 	case http.MethodPost:
-		if req.Table != nil && req.RecordId != nil {
+		if req.Table != nil && req.RecordId != nil && req.Relation == nil {
 			data, err := req.GetRecordData()
 			if err != nil {
 				return nil, err
 			}
+			if access != nil {
+				if err := access.CheckRecord(req.Table.Name, PermissionUpdate, data); err != nil {
+					return nil, err
+				}
+			}
 
+			if tags := req.GetIfMatch(); tags != nil {
+				return e.handleIfMatch(*req.Table, *req.RecordId, tags, func(e *DbExplorer) (interface{}, error) {
+					res, err := e.handlePostTableRecord(*req.Table, *req.RecordId, data)
+					if err != nil {
+						return nil, err
+					}
+					// the client can go on editing with the new ETag
+					res.etag, err = e.currentETag(*req.Table, *req.RecordId)
+					return res, err
+				})
+			}
 			return e.handlePostTableRecord(*req.Table, *req.RecordId, data)
 		}
 	case http.MethodDelete:
-		if req.Table != nil && req.RecordId != nil {
+		if req.Table != nil && req.RecordId != nil && req.Relation == nil {
+			if tags := req.GetIfMatch(); tags != nil {
+				return e.handleIfMatch(*req.Table, *req.RecordId, tags, func(e *DbExplorer) (interface{}, error) {
+					return e.handleDeleteTableRecord(*req.Table, *req.RecordId)
+				})
+			}
 			return e.handleDeleteTableRecord(*req.Table, *req.RecordId)
 		}
 	}
@@ -512,13 +734,26 @@ func (e *DbExplorer) handleRequest(r *http.Request) (interface{}, error) {
 func (e *DbExplorer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	res := Response{}
 
-	data, err := e.handleRequest(r)
+	data, err := e.snapshot().handleRequest(r)
+	// exports and GraphQL write responses of their own
+	if handler, ok := data.(http.Handler); ok && err == nil {
+		handler.ServeHTTP(w, r)
+		return
+	}
 	if err == nil {
 		res.Data = data
+		if tagged, ok := data.(taggedResponse); ok && tagged.ETag() != "" {
+			w.Header().Set("ETag", tagged.ETag())
+		}
 	} else {
-		if re, ok := err.(ResponseError); ok {
+		var re ResponseError
+		if errors.As(err, &re) {
 			w.WriteHeader(re.StatusCode)
 		}
+		var de DetailedResponseError
+		if errors.As(err, &de) {
+			res.Details = de.Details
+		}
 
 		res.Error = err.Error()
 	}
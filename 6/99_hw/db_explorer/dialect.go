@@ -0,0 +1,360 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Querier is implemented by both *sql.DB and *sql.Tx
+type Querier interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// Dialect hides what differs between the databases DbExplorer works with:
+// schema introspection, placeholder style and identifier quoting
+type Dialect interface {
+	Name() string
+	TableNames(db Querier) ([]string, error)
+	TableColumns(db Querier, table string) ([]TableColumn, error)
+	ForeignKeys(db Querier, table string) ([]ForeignKey, error)
+	// Placeholder returns the bind parameter for the n-th argument, n starts from 1
+	Placeholder(n int) string
+	QuoteIdent(name string) string
+	// LockClause is appended to SELECT to lock the rows till the end of the transaction
+	LockClause() string
+	// Insert executes an INSERT statement and returns the primary key of the new row
+	Insert(db Querier, query string, pk string, args ...interface{}) (int64, error)
+}
+
+var (
+	MySQL      Dialect = mysqlDialect{}
+	PostgreSQL Dialect = postgresDialect{}
+	SQLite     Dialect = sqliteDialect{}
+)
+
+// DetectDialect picks the dialect by the driver db was opened with
+func DetectDialect(db *sql.DB) (Dialect, error) {
+	driver := fmt.Sprintf("%T", db.Driver())
+	switch {
+	case strings.Contains(driver, "mysql"):
+		return MySQL, nil
+	case strings.HasPrefix(driver, "*pq."), strings.HasPrefix(driver, "*stdlib."):
+		return PostgreSQL, nil
+	case strings.Contains(driver, "sqlite"):
+		return SQLite, nil
+	}
+	return nil, fmt.Errorf("unsupported database driver %s", driver)
+}
+
+func scanNames(rows *sql.Rows) (names []string, err error) {
+	defer rows.Close()
+
+	var name string
+	for rows.Next() {
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		names = append(names, name)
+	}
+	return names, rows.Err()
+}
+
+func scanForeignKeys(rows *sql.Rows) (fks []ForeignKey, err error) {
+	defer rows.Close()
+
+	for rows.Next() {
+		fk := ForeignKey{}
+		if err := rows.Scan(&fk.Column, &fk.RefTable, &fk.RefColumn); err != nil {
+			return nil, err
+		}
+		fks = append(fks, fk)
+	}
+	return fks, rows.Err()
+}
+
+func lastInsertId(db Querier, query string, args ...interface{}) (int64, error) {
+	res, err := db.Exec(query, args...)
+	if err != nil {
+		return 0, err
+	}
+	return res.LastInsertId()
+}
+
+type mysqlDialect struct{}
+
+func (mysqlDialect) Name() string {
+	return "mysql"
+}
+
+func (mysqlDialect) TableNames(db Querier) ([]string, error) {
+	rows, err := db.Query(`SELECT table_name FROM information_schema.tables
+		WHERE table_schema = DATABASE() AND table_type = 'BASE TABLE'
+		ORDER BY table_name`)
+	if err != nil {
+		return nil, err
+	}
+	return scanNames(rows)
+}
+
+func (mysqlDialect) TableColumns(db Querier, table string) (columns []TableColumn, err error) {
+	rows, err := db.Query(`SELECT column_name, column_type, collation_name, is_nullable,
+			column_key, column_default, extra, privileges, column_comment
+		FROM information_schema.columns
+		WHERE table_schema = DATABASE() AND table_name = ?
+		ORDER BY ordinal_position`, table)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var colNull string
+	for rows.Next() {
+		col := TableColumn{}
+		err := rows.Scan(
+			&col.Field,
+			&col.DataType,
+			&col.Collation,
+			&colNull,
+			&col.Key,
+			&col.Default,
+			&col.Extra,
+			&col.Privileges,
+			&col.Comment,
+		)
+		if err != nil {
+			return nil, err
+		}
+		col.Null = colNull == "YES"
+		columns = append(columns, col)
+	}
+	return columns, rows.Err()
+}
+
+func (mysqlDialect) ForeignKeys(db Querier, table string) ([]ForeignKey, error) {
+	rows, err := db.Query(`SELECT column_name, referenced_table_name, referenced_column_name
+		FROM information_schema.key_column_usage
+		WHERE table_schema = DATABASE() AND table_name = ? AND referenced_table_name IS NOT NULL
+		ORDER BY constraint_name, ordinal_position`, table)
+	if err != nil {
+		return nil, err
+	}
+	return scanForeignKeys(rows)
+}
+
+func (mysqlDialect) Placeholder(int) string {
+	return "?"
+}
+
+func (mysqlDialect) QuoteIdent(name string) string {
+	return "`" + strings.ReplaceAll(name, "`", "``") + "`"
+}
+
+func (mysqlDialect) LockClause() string {
+	return " FOR UPDATE"
+}
+
+func (mysqlDialect) Insert(db Querier, query string, _ string, args ...interface{}) (int64, error) {
+	return lastInsertId(db, query, args...)
+}
+
+type postgresDialect struct{}
+
+func (postgresDialect) Name() string {
+	return "postgres"
+}
+
+func (postgresDialect) TableNames(db Querier) ([]string, error) {
+	rows, err := db.Query(`SELECT table_name FROM information_schema.tables
+		WHERE table_schema = current_schema() AND table_type = 'BASE TABLE'
+		ORDER BY table_name`)
+	if err != nil {
+		return nil, err
+	}
+	return scanNames(rows)
+}
+
+func (postgresDialect) TableColumns(db Querier, table string) (columns []TableColumn, err error) {
+	rows, err := db.Query(`SELECT c.column_name, c.data_type, c.collation_name, c.is_nullable,
+			COALESCE((
+				SELECT 'PRI' FROM information_schema.table_constraints tc
+				JOIN information_schema.key_column_usage k
+					ON k.constraint_name = tc.constraint_name AND k.table_schema = tc.table_schema
+				WHERE tc.constraint_type = 'PRIMARY KEY' AND tc.table_schema = c.table_schema
+					AND tc.table_name = c.table_name AND k.column_name = c.column_name
+			), '') AS column_key,
+			c.column_default, c.is_identity
+		FROM information_schema.columns c
+		WHERE c.table_schema = current_schema() AND c.table_name = $1
+		ORDER BY c.ordinal_position`, table)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var (
+		colNull     string
+		colDefault  sql.NullString
+		colIdentity string
+	)
+	for rows.Next() {
+		col := TableColumn{}
+		err := rows.Scan(
+			&col.Field,
+			&col.DataType,
+			&col.Collation,
+			&colNull,
+			&col.Key,
+			&colDefault,
+			&colIdentity,
+		)
+		if err != nil {
+			return nil, err
+		}
+		col.Null = colNull == "YES"
+		if colDefault.Valid {
+			col.Default = colDefault.String
+		}
+		if colIdentity == "YES" || strings.HasPrefix(colDefault.String, "nextval(") {
+			col.Extra = "auto_increment"
+		}
+		columns = append(columns, col)
+	}
+	return columns, rows.Err()
+}
+
+func (postgresDialect) ForeignKeys(db Querier, table string) ([]ForeignKey, error) {
+	rows, err := db.Query(`SELECT k.column_name, c.table_name, c.column_name
+		FROM information_schema.table_constraints tc
+		JOIN information_schema.key_column_usage k
+			ON k.constraint_name = tc.constraint_name AND k.table_schema = tc.table_schema
+		JOIN information_schema.constraint_column_usage c
+			ON c.constraint_name = tc.constraint_name AND c.table_schema = tc.table_schema
+		WHERE tc.constraint_type = 'FOREIGN KEY' AND tc.table_schema = current_schema()
+			AND tc.table_name = $1
+		ORDER BY tc.constraint_name, k.ordinal_position`, table)
+	if err != nil {
+		return nil, err
+	}
+	return scanForeignKeys(rows)
+}
+
+func (postgresDialect) Placeholder(n int) string {
+	return "$" + strconv.Itoa(n)
+}
+
+func (postgresDialect) QuoteIdent(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+func (postgresDialect) LockClause() string {
+	return " FOR UPDATE"
+}
+
+// Insert uses RETURNING, postgres drivers don't support LastInsertId
+func (d postgresDialect) Insert(db Querier, query string, pk string, args ...interface{}) (int64, error) {
+	if pk == "" {
+		_, err := db.Exec(query, args...)
+		return 0, err
+	}
+
+	var id int64
+	err := db.QueryRow(query+" RETURNING "+d.QuoteIdent(pk), args...).Scan(&id)
+	return id, err
+}
+
+type sqliteDialect struct{}
+
+func (sqliteDialect) Name() string {
+	return "sqlite"
+}
+
+func (sqliteDialect) TableNames(db Querier) ([]string, error) {
+	rows, err := db.Query(`SELECT name FROM sqlite_master
+		WHERE type = 'table' AND name NOT LIKE 'sqlite_%'
+		ORDER BY name`)
+	if err != nil {
+		return nil, err
+	}
+	return scanNames(rows)
+}
+
+// TableColumns reads PRAGMA table_info, sqlite has no information_schema
+func (d sqliteDialect) TableColumns(db Querier, table string) (columns []TableColumn, err error) {
+	rows, err := db.Query("PRAGMA table_info(" + d.QuoteIdent(table) + ")")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var (
+		cid        int
+		notNull    bool
+		colDefault sql.NullString
+		pk         int
+	)
+	for rows.Next() {
+		col := TableColumn{}
+		if err := rows.Scan(&cid, &col.Field, &col.DataType, &notNull, &colDefault, &pk); err != nil {
+			return nil, err
+		}
+		col.Null = !notNull && pk == 0
+		if colDefault.Valid {
+			col.Default = colDefault.String
+		}
+		if pk > 0 {
+			col.Key = "PRI"
+			// INTEGER PRIMARY KEY is an alias for rowid
+			if strings.EqualFold(col.DataType, "integer") {
+				col.Extra = "auto_increment"
+			}
+		}
+		columns = append(columns, col)
+	}
+	return columns, rows.Err()
+}
+
+// ForeignKeys reads PRAGMA foreign_key_list, the referenced column is empty
+// when the key points to the primary key implicitly
+func (d sqliteDialect) ForeignKeys(db Querier, table string) (fks []ForeignKey, err error) {
+	rows, err := db.Query("PRAGMA foreign_key_list(" + d.QuoteIdent(table) + ")")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var (
+		id, seq                   int
+		to                        sql.NullString
+		onUpdate, onDelete, match string
+	)
+	for rows.Next() {
+		fk := ForeignKey{}
+		if err := rows.Scan(&id, &seq, &fk.RefTable, &fk.Column, &to, &onUpdate, &onDelete, &match); err != nil {
+			return nil, err
+		}
+		fk.RefColumn = to.String
+		fks = append(fks, fk)
+	}
+	return fks, rows.Err()
+}
+
+func (sqliteDialect) Placeholder(int) string {
+	return "?"
+}
+
+func (sqliteDialect) QuoteIdent(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+// a write transaction locks the whole database, there are no row locks
+func (sqliteDialect) LockClause() string {
+	return ""
+}
+
+func (sqliteDialect) Insert(db Querier, query string, _ string, args ...interface{}) (int64, error) {
+	return lastInsertId(db, query, args...)
+}
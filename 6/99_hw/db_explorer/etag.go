@@ -0,0 +1,201 @@
+package main
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// ETags let editors of a record not to overwrite each other: GET /$table/$id
+// returns the ETag of the record, POST and DELETE with If-Match are done only
+// if the record still has it
+
+func NewPreconditionFailedError() ResponseError {
+	return ResponseError{"record has been changed", http.StatusPreconditionFailed}
+}
+
+// SetVersionColumn makes tables with an integer column of the name use it
+// for ETags instead of hashing the record. Every update increments the
+// version, clients can't set it
+func (e *DbExplorer) SetVersionColumn(name string) {
+	e.versionColumn = name
+}
+
+// versionOf returns the version column of the table if it has one
+func (e *DbExplorer) versionOf(table Table) (TableColumn, bool) {
+	if e.versionColumn == "" || e.versionColumn == table.Pk {
+		return TableColumn{}, false
+	}
+	c, ok := table.Column(e.versionColumn)
+	if !ok {
+		return TableColumn{}, false
+	}
+	_, isInt := c.Type.(IntColumn)
+	return c, isInt
+}
+
+// fullTable returns the table with all its columns: versions don't depend on
+// what columns the api key can see, ETags are hashed over the readable ones
+func (e *DbExplorer) fullTable(table Table) Table {
+	if e.current != nil {
+		if full, ok := e.current.Tables[table.Name]; ok {
+			return full
+		}
+	}
+	return table
+}
+
+// loadRecord reads all columns of the record, lock keeps the row from being
+// changed by others till the end of the transaction
+func (e *DbExplorer) loadRecord(table Table, id int, lock bool) (TableRecord, error) {
+	q := fmt.Sprintf(
+		"SELECT %s FROM %s WHERE %s = %s",
+		e.selectList(table),
+		e.quote(table.Name),
+		e.quote(table.Pk),
+		e.dialect.Placeholder(1),
+	)
+	if lock {
+		q += e.dialect.LockClause()
+	}
+
+	r := table.NewRow()
+	if err := e.conn.QueryRow(q, id).Scan(r...); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ResponseError{"record not found", http.StatusNotFound}
+		}
+		return nil, err
+	}
+	return table.NewRecord(r), nil
+}
+
+// recordETag is the version if the table has a version column, otherwise
+// a hash of the record columns the api key can read. The same record has
+// different ETags for keys seeing different columns, but a hash over hidden
+// columns would tell when they change and let their values be guessed.
+// table has all the columns, the view of the key depends on the request method
+func (e *DbExplorer) recordETag(table Table, record TableRecord) (string, error) {
+	if c, ok := e.versionOf(table); ok {
+		data, err := json.Marshal(record[c.Field])
+		if err != nil {
+			return "", err
+		}
+		return `"v` + string(data) + `"`, nil
+	}
+
+	if e.key != nil {
+		ta, _ := e.key.table(table.Name)
+		readable := make(TableRecord, len(record))
+		for field, val := range record {
+			if ta.sees(table, field, PermissionRead) {
+				readable[field] = val
+			}
+		}
+		record = readable
+	}
+
+	// map keys are sorted, so the same record always gives the same JSON
+	data, err := json.Marshal(record)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`, nil
+}
+
+// GetIfMatch returns ETags of If-Match headers, nil if there are none
+func (r *Request) GetIfMatch() []string {
+	var tags []string
+	for _, header := range r.request.Header.Values("If-Match") {
+		for _, tag := range strings.Split(header, ",") {
+			if tag = strings.TrimSpace(tag); tag != "" {
+				tags = append(tags, tag)
+			}
+		}
+	}
+	return tags
+}
+
+// etagMatches compares tags strongly, a weak ETag never matches. "*" matches
+// any existing record
+func etagMatches(tags []string, etag string) bool {
+	for _, tag := range tags {
+		if tag == "*" || tag == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// handleIfMatch runs fn in a transaction with the record locked if the record
+// exists and has one of the tags
+func (e *DbExplorer) handleIfMatch(table Table, id int, tags []string, fn func(e *DbExplorer) (interface{}, error)) (interface{}, error) {
+	// inside /_batch the batch transaction is used
+	conditional := *e
+	var tx *sql.Tx
+	if _, inTx := e.conn.(*sql.Tx); !inTx {
+		var err error
+		tx, err = e.db.Begin()
+		if err != nil {
+			return nil, err
+		}
+		defer tx.Rollback()
+		conditional.conn = tx
+	}
+
+	full := e.fullTable(table)
+	record, err := conditional.loadRecord(full, id, true)
+	var re ResponseError
+	if errors.As(err, &re) && re.StatusCode == http.StatusNotFound {
+		return nil, NewPreconditionFailedError()
+	}
+	if err != nil {
+		return nil, err
+	}
+	etag, err := e.recordETag(full, record)
+	if err != nil {
+		return nil, err
+	}
+	if !etagMatches(tags, etag) {
+		return nil, NewPreconditionFailedError()
+	}
+
+	res, err := fn(&conditional)
+	if err != nil {
+		return nil, err
+	}
+	if tx != nil {
+		if err := tx.Commit(); err != nil {
+			return nil, err
+		}
+	}
+	return res, nil
+}
+
+// currentETag is the ETag of the record after it's updated
+func (e *DbExplorer) currentETag(table Table, id int) (string, error) {
+	full := e.fullTable(table)
+	record, err := e.loadRecord(full, id, false)
+	if err != nil {
+		return "", err
+	}
+	return e.recordETag(full, record)
+}
+
+// taggedResponse is a response with the ETag header
+type taggedResponse interface {
+	ETag() string
+}
+
+func (r *GetTableRecordResponse) ETag() string {
+	return r.etag
+}
+
+func (r *PostTableRecordResponse) ETag() string {
+	return r.etag
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestETag(t *testing.T) {
+	ts, handler, db := newTestServer(t, PrepareTestApis)
+
+	do := func(method, path, ifMatch, body string) (int, string, string) {
+		req, err := http.NewRequest(method, ts.URL+path, strings.NewReader(body))
+		if err != nil {
+			panic(err)
+		}
+		if ifMatch != "" {
+			req.Header.Set("If-Match", ifMatch)
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("[%s %s] request error: %v", method, path, err)
+		}
+		defer resp.Body.Close()
+		data, _ := io.ReadAll(resp.Body)
+
+		if db.Stats().OpenConnections != 1 {
+			t.Fatalf("[%s %s] you have %d open connections, must be 1", method, path, db.Stats().OpenConnections)
+		}
+		return resp.StatusCode, resp.Header.Get("ETag"), string(data)
+	}
+
+	_, etag, _ := do(http.MethodGet, "/items/1", "", "")
+	if etag == "" {
+		t.Fatalf("no ETag of a record")
+	}
+	if _, again, _ := do(http.MethodGet, "/items/1", "", ""); again != etag {
+		t.Fatalf("ETag of the same record changed: %s != %s", again, etag)
+	}
+	if _, other, _ := do(http.MethodGet, "/items/2", "", ""); other == etag {
+		t.Fatalf("different records have the same ETag %s", etag)
+	}
+
+	// второй редактор прочитал запись до первого
+	status, newEtag, body := do(http.MethodPost, "/items/1", etag, `{"title": "first"}`)
+	if status != http.StatusOK || body != `{"response":{"updated":1}}` || newEtag == "" || newEtag == etag {
+		t.Fatalf("update with a matching ETag: got %d %s %s", status, newEtag, body)
+	}
+	status, _, body = do(http.MethodPost, "/items/1", etag, `{"title": "second"}`)
+	if status != http.StatusPreconditionFailed || body != `{"error":"record has been changed"}` {
+		t.Fatalf("update with a stale ETag: got %d %s", status, body)
+	}
+	if _, current, _ := do(http.MethodGet, "/items/1", "", ""); current != newEtag {
+		t.Fatalf("ETag after update: got %s, want %s", current, newEtag)
+	}
+
+	status, _, _ = do(http.MethodDelete, "/items/1", `"0", `+etag, "")
+	if status != http.StatusPreconditionFailed {
+		t.Fatalf("delete with stale ETags: got %d", status)
+	}
+	status, _, body = do(http.MethodDelete, "/items/1", `"0", `+newEtag, "")
+	if status != http.StatusOK || body != `{"response":{"deleted":1}}` {
+		t.Fatalf("delete with a matching ETag: got %d %s", status, body)
+	}
+	status, _, _ = do(http.MethodDelete, "/items/1", "*", "")
+	if status != http.StatusPreconditionFailed {
+		t.Fatalf("delete of a missing record with If-Match *: got %d", status)
+	}
+
+	// колонка версии увеличивается при каждом изменении
+	if _, err := db.Exec(`ALTER TABLE items ADD COLUMN version INTEGER NOT NULL DEFAULT 1`); err != nil {
+		panic(err)
+	}
+	if _, _, err := handler.ReloadSchema(); err != nil {
+		panic(err)
+	}
+	handler.SetVersionColumn("version")
+
+	_, etag, _ = do(http.MethodGet, "/items/2", "", "")
+	if etag != `"v1"` {
+		t.Fatalf("ETag of a version: got %s", etag)
+	}
+	status, etag, _ = do(http.MethodPost, "/items/2", `"v1"`, `{"updated": "me"}`)
+	if status != http.StatusOK || etag != `"v2"` {
+		t.Fatalf("update of a version: got %d %s", status, etag)
+	}
+	// без If-Match версия тоже растет
+	do(http.MethodPost, "/items/2", "", `{"updated": "someone"}`)
+	status, _, _ = do(http.MethodPost, "/items/2", `"v2"`, `{"updated": "me again"}`)
+	if status != http.StatusPreconditionFailed {
+		t.Fatalf("update of a stale version: got %d", status)
+	}
+	status, _, body = do(http.MethodPost, "/items/2", "", `{"version": 10}`)
+	if status != http.StatusBadRequest || body != `{"error":"field version have invalid type"}` {
+		t.Fatalf("update of the version itself: got %d %s", status, body)
+	}
+
+	status, _, body = do(http.MethodPost, "/_batch", "", `{"operations": [
+		{"method": "POST", "path": "/items/2", "body": {"title": "batch"}, "if_match": "\"v3\""},
+		{"method": "POST", "path": "/items/2", "body": {"title": "batch"}, "if_match": "\"v3\""}
+	]}`)
+	if status != http.StatusPreconditionFailed || body != `{"error":"operation 1: record has been changed"}` {
+		t.Fatalf("batch with If-Match: got %d %s", status, body)
+	}
+}
+
+// ETag считается только по колонкам, которые ключ может читать,
+// иначе по нему видно, что поменялась скрытая колонка
+func TestETagHiddenColumns(t *testing.T) {
+	ts, handler, db := newTestServer(t, PrepareTestApis)
+
+	config := &AccessConfig{}
+	if err := json.Unmarshal([]byte(testAccessConfig), config); err != nil {
+		panic(err)
+	}
+	handler.SetAccessConfig(config)
+
+	do := func(key, method, path, ifMatch, body string) (int, string) {
+		req, err := http.NewRequest(method, ts.URL+path, strings.NewReader(body))
+		if err != nil {
+			panic(err)
+		}
+		req.Header.Set("X-Api-Key", key)
+		if ifMatch != "" {
+			req.Header.Set("If-Match", ifMatch)
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("[%s %s] request error: %v", method, path, err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode, resp.Header.Get("ETag")
+	}
+
+	_, etag := do("editor", http.MethodGet, "/users/1", "", "")
+	_, adminEtag := do("admin", http.MethodGet, "/users/1", "", "")
+	if _, err := db.Exec(`UPDATE users SET password = 'hate', email = 'new@example.com' WHERE user_id = 1`); err != nil {
+		panic(err)
+	}
+	if _, again := do("editor", http.MethodGet, "/users/1", "", ""); again != etag {
+		t.Fatalf("ETag changed with hidden columns: %s != %s", again, etag)
+	}
+	if _, again := do("admin", http.MethodGet, "/users/1", "", ""); again == adminEtag {
+		t.Fatalf("ETag of a key seeing all columns must change")
+	}
+	if _, err := db.Exec(`UPDATE users SET info = 'some' WHERE user_id = 1`); err != nil {
+		panic(err)
+	}
+	if status, _ := do("editor", http.MethodPost, "/users/1", etag, `{"login": "new"}`); status != http.StatusPreconditionFailed {
+		t.Fatalf("update with a stale ETag: got %d", status)
+	}
+
+	// updated редактор может читать, но не писать: ETag из GET подходит к POST
+	_, etag = do("editor", http.MethodGet, "/items/1", "", "")
+	status, newEtag := do("editor", http.MethodPost, "/items/1", etag, `{"title": "edited"}`)
+	if status != http.StatusOK || newEtag == "" || newEtag == etag {
+		t.Fatalf("update with a matching ETag: got %d %s", status, newEtag)
+	}
+	if _, current := do("editor", http.MethodGet, "/items/1", "", ""); current != newEtag {
+		t.Fatalf("ETag after update: got %s, want %s", current, newEtag)
+	}
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"net/http"
+)
+
+const (
+	FormatCSV    = "csv"
+	FormatNDJSON = "ndjson"
+)
+
+// GetFormat parses ?format=csv|ndjson, an empty format means the usual JSON response
+func (r *Request) GetFormat() (string, error) {
+	switch format := r.request.URL.Query().Get("format"); format {
+	case "", "json":
+		return "", nil
+	case FormatCSV, FormatNDJSON:
+		return format, nil
+	default:
+		return "", ResponseError{fmt.Sprintf("unknown format %s", format), http.StatusBadRequest}
+	}
+}
+
+func NewUnsupportedFormatError(format string) ResponseError {
+	return ResponseError{
+		Text:       fmt.Sprintf("format %s is supported only for tables", format),
+		StatusCode: http.StatusBadRequest,
+	}
+}
+
+// RecordStream writes records while they are read from the database,
+// so exporting a table takes the same memory whatever its size is
+type RecordStream struct {
+	table  Table
+	format string
+	rows   *sql.Rows
+}
+
+// handleExportTableRecords exports all records unless ?limit is set
+func (e *DbExplorer) handleExportTableRecords(table Table, lq *ListQuery, format string) (*RecordStream, error) {
+	if len(lq.Embed) > 0 {
+		return nil, ResponseError{fmt.Sprintf("embed is not supported with format %s", format), http.StatusBadRequest}
+	}
+	if !lq.LimitSet {
+		lq.Limit = math.MaxInt64
+	}
+
+	selected, err := table.Select(lq.Fields)
+	if err != nil {
+		return nil, err
+	}
+
+	q, args := e.selectQuery(table, selected, lq)
+	rows, err := e.conn.Query(q, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &RecordStream{
+		table:  selected,
+		format: format,
+		rows:   rows,
+	}, nil
+}
+
+func (s *RecordStream) Close() error {
+	return s.rows.Close()
+}
+
+// ServeHTTP writes the records. The status is already sent when an error
+// happens in the middle, so the response is aborted to show it's incomplete
+func (s *RecordStream) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	defer s.rows.Close()
+
+	var err error
+	switch s.format {
+	case FormatCSV:
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		err = s.writeCSV(w)
+	case FormatNDJSON:
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		err = s.writeNDJSON(w)
+	}
+	if err != nil {
+		log.Printf("export of %s failed: %s", s.table.Name, err)
+		panic(http.ErrAbortHandler)
+	}
+}
+
+func (s *RecordStream) writeNDJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	row := s.table.NewRow()
+	for s.rows.Next() {
+		if err := s.rows.Scan(row...); err != nil {
+			return err
+		}
+		if err := encoder.Encode(s.table.NewRecord(row)); err != nil {
+			return err
+		}
+	}
+	return s.rows.Err()
+}
+
+// writeCSV writes a header line with column names and a line per record
+func (s *RecordStream) writeCSV(w io.Writer) error {
+	writer := csv.NewWriter(w)
+	fields := make([]string, len(s.table.Columns))
+	for i, c := range s.table.Columns {
+		fields[i] = c.Field
+	}
+	if err := writer.Write(fields); err != nil {
+		return err
+	}
+
+	row := s.table.NewRow()
+	for s.rows.Next() {
+		if err := s.rows.Scan(row...); err != nil {
+			return err
+		}
+		for i, val := range row {
+			field, err := csvField(val)
+			if err != nil {
+				return err
+			}
+			fields[i] = field
+		}
+		if err := writer.Write(fields); err != nil {
+			return err
+		}
+	}
+	if err := s.rows.Err(); err != nil {
+		return err
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+// csvField formats a scanned value as it's written in JSON, strings without
+// quotes. NULL is an empty field
+func csvField(val interface{}) (string, error) {
+	// a JSON document is written as is, quotes of a string included
+	if v, ok := val.(*RawJSON); ok {
+		return string(*v), nil
+	}
+
+	data, err := json.Marshal(val)
+	if err != nil {
+		return "", err
+	}
+	switch {
+	case string(data) == "null":
+		return "", nil
+	case data[0] == '"':
+		var s string
+		err := json.Unmarshal(data, &s)
+		return s, err
+	}
+	return string(data), nil
+}
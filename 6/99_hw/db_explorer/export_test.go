@@ -0,0 +1,113 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestExportImport(t *testing.T) {
+	ts, _, db := newTestServer(t, PrepareTestApis)
+
+	do := func(method, path, body string) (int, string, string) {
+		req, err := http.NewRequest(method, ts.URL+path, strings.NewReader(body))
+		if err != nil {
+			panic(err)
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("[%s %s] request error: %v", method, path, err)
+		}
+		defer resp.Body.Close()
+		data, _ := io.ReadAll(resp.Body)
+
+		if db.Stats().OpenConnections != 1 {
+			t.Fatalf("[%s %s] you have %d open connections, must be 1", method, path, db.Stats().OpenConnections)
+		}
+		return resp.StatusCode, resp.Header.Get("Content-Type"), string(data)
+	}
+
+	status, contentType, body := do(http.MethodGet, "/items?format=ndjson&fields=id,title,updated", "")
+	expected := `{"id":1,"title":"database/sql","updated":"rvasily"}
+{"id":2,"title":"memcache","updated":null}
+`
+	if status != http.StatusOK || contentType != "application/x-ndjson" || body != expected {
+		t.Fatalf("ndjson export: got %d %s\n%s", status, contentType, body)
+	}
+
+	// NULL и пустая строка в csv неотличимы, пустое поле nullable колонки - это NULL
+	status, contentType, body = do(http.MethodGet, "/items?format=csv&fields=id,title,updated&order=-id", "")
+	expected = "id,title,updated\n2,memcache,\n1,database/sql,rvasily\n"
+	if status != http.StatusOK || contentType != "text/csv; charset=utf-8" || body != expected {
+		t.Fatalf("csv export: got %d %s\n%s", status, contentType, body)
+	}
+
+	// без limit выгружается вся таблица
+	for i := 0; i < defaultLimit; i++ {
+		db.Exec(`INSERT INTO items (title, description) VALUES ('more', '')`)
+	}
+	_, _, body = do(http.MethodGet, "/items?format=ndjson&fields=id", "")
+	if lines := strings.Count(body, "\n"); lines != defaultLimit+2 {
+		t.Fatalf("export without limit: expected %d lines, got %d", defaultLimit+2, lines)
+	}
+	db.Exec(`DELETE FROM items WHERE id > 2`)
+
+	status, _, body = do(http.MethodPut, "/items?format=csv", "id,title,description,updated\n"+
+		"100,csv,\"Рассказать, про csv\",\n"+
+		"101,\"csv \"\"quoted\"\"\",,someone\n")
+	if status != http.StatusOK || body != `{"response":{"inserted":2}}` {
+		t.Fatalf("csv import: got %d %s", status, body)
+	}
+
+	status, _, body = do(http.MethodPut, "/items?format=ndjson", `{"title": "ndjson", "description": "ok"}
+
+{"title": 42, "description": "bad"}
+not json
+{"title": "ndjson", "description": "ok", "updated": null}
+`)
+	var res struct {
+		Error   string        `json:"error"`
+		Details []ImportError `json:"details"`
+	}
+	json.Unmarshal([]byte(body), &res)
+	expectedErrors := []ImportError{
+		{Line: 3, Error: "field title have invalid type"},
+		{Line: 4, Error: "invalid json object"},
+	}
+	if status != http.StatusBadRequest || res.Error != "invalid records, nothing is imported" || !reflect.DeepEqual(res.Details, expectedErrors) {
+		t.Fatalf("ndjson import with errors: got %d %s", status, body)
+	}
+
+	status, _, body = do(http.MethodPut, "/items?format=csv", "id,title,secret\n1,x,y\n")
+	if status != http.StatusBadRequest || !strings.Contains(body, `"details":[{"line":1,"error":"unknown field secret"}]`) {
+		t.Fatalf("csv import with unknown column: got %d %s", status, body)
+	}
+
+	status, _, body = do(http.MethodPut, "/items?format=csv", "title,description\nx,y\nz\n")
+	if status != http.StatusBadRequest || !strings.Contains(body, `"details":[{"line":3,"error":"wrong number of fields"}]`) {
+		t.Fatalf("csv import with a short line: got %d %s", status, body)
+	}
+
+	status, _, body = do(http.MethodGet, "/items?format=ndjson&fields=title,description,updated", "")
+	expected = `{"description":"Рассказать про базы данных","title":"database/sql","updated":"rvasily"}
+{"description":"Рассказать про мемкеш с примером использования","title":"memcache","updated":null}
+{"description":"Рассказать, про csv","title":"csv","updated":null}
+{"description":"","title":"csv \"quoted\"","updated":"someone"}
+`
+	if status != http.StatusOK || body != expected {
+		t.Fatalf("export after import: got %d\n%s", status, body)
+	}
+
+	status, _, body = do(http.MethodGet, "/items/1?format=csv", "")
+	if status != http.StatusBadRequest || body != `{"error":"format csv is supported only for tables"}` {
+		t.Fatalf("record export: got %d %s", status, body)
+	}
+
+	status, _, body = do(http.MethodGet, "/items?format=xml", "")
+	if status != http.StatusBadRequest || body != `{"error":"unknown format xml"}` {
+		t.Fatalf("unknown format: got %d %s", status, body)
+	}
+}
@@ -0,0 +1,772 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/graphql-go/graphql/language/parser"
+)
+
+var graphqlName = regexp.MustCompile(`^[_A-Za-z][_0-9A-Za-z]*$`)
+
+// graphqlReserved are names tables can't have as GraphQL types
+var graphqlReserved = map[string]bool{
+	"Query": true, "Mutation": true, "String": true, "Int": true, "Float": true,
+	"Boolean": true, "ID": true, "Int64": true, "JSON": true,
+}
+
+// Int64 is for integer columns wider than the 32 bits of GraphQL Int
+var Int64 = graphql.NewScalar(graphql.ScalarConfig{
+	Name:        "Int64",
+	Description: "64-bit integer",
+	Serialize: func(value interface{}) interface{} {
+		switch v := value.(type) {
+		case int64, uint64:
+			return v
+		case int:
+			return int64(v)
+		case json.Number:
+			if n, err := v.Int64(); err == nil {
+				return n
+			}
+		}
+		return nil
+	},
+	ParseValue: func(value interface{}) interface{} {
+		switch v := value.(type) {
+		case int:
+			return int64(v)
+		case int64:
+			return v
+		case float64:
+			if v == float64(int64(v)) {
+				return int64(v)
+			}
+		case string:
+			if n, err := strconv.ParseInt(v, 10, 64); err == nil {
+				return n
+			}
+		}
+		return nil
+	},
+	ParseLiteral: func(value ast.Value) interface{} {
+		if v, ok := value.(*ast.IntValue); ok {
+			if n, err := strconv.ParseInt(v.Value, 10, 64); err == nil {
+				return n
+			}
+		}
+		return nil
+	},
+})
+
+// JSON is for JSON columns, any value goes as is
+var JSON = graphql.NewScalar(graphql.ScalarConfig{
+	Name:        "JSON",
+	Description: "JSON document",
+	Serialize:   func(value interface{}) interface{} { return value },
+	ParseValue:  func(value interface{}) interface{} { return value },
+	ParseLiteral: func(value ast.Value) interface{} {
+		return astValue(value)
+	},
+})
+
+func astValue(value ast.Value) interface{} {
+	switch v := value.(type) {
+	case *ast.StringValue:
+		return v.Value
+	case *ast.IntValue:
+		return json.Number(v.Value)
+	case *ast.FloatValue:
+		return json.Number(v.Value)
+	case *ast.BooleanValue:
+		return v.Value
+	case *ast.EnumValue:
+		return v.Value
+	case *ast.ListValue:
+		list := make([]interface{}, len(v.Values))
+		for i, item := range v.Values {
+			list[i] = astValue(item)
+		}
+		return list
+	case *ast.ObjectValue:
+		object := make(map[string]interface{}, len(v.Fields))
+		for _, field := range v.Fields {
+			object[field.Name.Value] = astValue(field.Value)
+		}
+		return object
+	}
+	return nil
+}
+
+// graphqlScalar picks the GraphQL type of a column, DECIMAL goes as String
+// to keep all digits
+func graphqlScalar(c TableColumn) graphql.Type {
+	switch t := c.Type.(type) {
+	case IntColumn:
+		if t.Bits != 0 && t.Bits <= 32 && !(t.Bits == 32 && t.Unsigned) {
+			return graphql.Int
+		}
+		return Int64
+	case FloatColumn:
+		return graphql.Float
+	case BoolColumn:
+		return graphql.Boolean
+	case JSONColumn:
+		return JSON
+	}
+	return graphql.String
+}
+
+// graphqlOutput converts a scanned value to the value of the GraphQL type of the column
+func graphqlOutput(c TableColumn, val interface{}) (interface{}, error) {
+	data, err := json.Marshal(val)
+	if err != nil || string(data) == "null" {
+		return nil, err
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var out interface{}
+	if err := decoder.Decode(&out); err != nil {
+		return nil, err
+	}
+
+	switch graphqlScalar(c) {
+	case graphql.Int, Int64:
+		return out.(json.Number).Int64()
+	case graphql.Float:
+		return out.(json.Number).Float64()
+	case graphql.String:
+		if n, ok := out.(json.Number); ok {
+			// DECIMAL is encoded as a JSON number
+			return string(n), nil
+		}
+	}
+	return out, nil
+}
+
+// graphqlInput converts an argument to what a JSON body would have, so
+// it's validated and converted by column types the same way
+func graphqlInput(val interface{}) interface{} {
+	switch v := val.(type) {
+	case int:
+		return json.Number(strconv.Itoa(v))
+	case int64:
+		return json.Number(strconv.FormatInt(v, 10))
+	case float64:
+		return json.Number(strconv.FormatFloat(v, 'g', -1, 64))
+	}
+	return val
+}
+
+// graphqlRequest is what resolvers need from the HTTP request, the schema
+// itself is shared by all requests with the same api key
+type graphqlRequest struct {
+	explorer *DbExplorer
+	access   *KeyAccess
+	request  *http.Request
+}
+
+type graphqlRequestKey struct{}
+
+func graphqlRequestFrom(ctx context.Context) *graphqlRequest {
+	return ctx.Value(graphqlRequestKey{}).(*graphqlRequest)
+}
+
+// authorize checks an operation like a REST request of the method to the table
+func (g *graphqlRequest) authorize(method, table string) (*DbExplorer, Table, error) {
+	r, err := http.NewRequestWithContext(g.request.Context(), method, "/"+table, nil)
+	if err != nil {
+		return nil, Table{}, err
+	}
+	e, err := g.explorer.authorize(g.access, r)
+	if err != nil {
+		return nil, Table{}, err
+	}
+	t, ok := e.tables[table]
+	if !ok {
+		return nil, Table{}, ResponseError{"unknown table", http.StatusNotFound}
+	}
+	return e, t, nil
+}
+
+// graphqlBuilder makes a GraphQL schema of the tables visible to an api key:
+// object types from what it can read, mutations from what it can write
+type graphqlBuilder struct {
+	read, insert, update, delete map[string]Table
+
+	objects     map[string]*graphql.Object
+	comparisons map[graphql.Type]*graphql.InputObject
+}
+
+func newGraphQLSchema(tables map[string]Table, access *KeyAccess) (graphql.Schema, error) {
+	b := &graphqlBuilder{
+		read:        tables,
+		insert:      tables,
+		update:      tables,
+		delete:      tables,
+		objects:     map[string]*graphql.Object{},
+		comparisons: map[graphql.Type]*graphql.InputObject{},
+	}
+	if access != nil {
+		b.read = access.View(tables, PermissionRead)
+		b.insert = access.View(tables, PermissionInsert)
+		b.update = access.View(tables, PermissionUpdate)
+		b.delete = access.View(tables, PermissionDelete)
+	}
+
+	query := graphql.Fields{}
+	for _, name := range sortedTableNames(b.read) {
+		if !graphqlTableName(name) {
+			continue
+		}
+		b.objects[name] = b.object(b.read[name])
+	}
+	for _, name := range sortedTableNames(b.read) {
+		object, ok := b.objects[name]
+		if !ok {
+			continue
+		}
+		table := b.read[name]
+		query[name] = b.listField(table, object)
+		if table.Pk != "" {
+			query[name+"_by_pk"] = b.byPkField(table, object)
+		}
+	}
+	if len(query) == 0 {
+		// a schema must have a query type with fields
+		query["_tables"] = &graphql.Field{
+			Type:    graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(graphql.String))),
+			Resolve: func(graphql.ResolveParams) (interface{}, error) { return []string{}, nil },
+		}
+	}
+
+	mutation := graphql.Fields{}
+	b.mutations(mutation)
+
+	config := graphql.SchemaConfig{
+		Query: graphql.NewObject(graphql.ObjectConfig{Name: "Query", Fields: query}),
+	}
+	if len(mutation) > 0 {
+		config.Mutation = graphql.NewObject(graphql.ObjectConfig{Name: "Mutation", Fields: mutation})
+	}
+	return graphql.NewSchema(config)
+}
+
+func graphqlTableName(name string) bool {
+	return graphqlName.MatchString(name) && !strings.HasPrefix(name, "__") && !graphqlReserved[name]
+}
+
+func graphqlColumns(table Table) []TableColumn {
+	var columns []TableColumn
+	for _, c := range table.Columns {
+		if graphqlName.MatchString(c.Field) && !strings.HasPrefix(c.Field, "__") {
+			columns = append(columns, c)
+		}
+	}
+	return columns
+}
+
+// object has a field per column and per relation, relations are added
+// by a thunk as tables refer to each other
+func (b *graphqlBuilder) object(table Table) *graphql.Object {
+	return graphql.NewObject(graphql.ObjectConfig{
+		Name: table.Name,
+		Fields: graphql.FieldsThunk(func() graphql.Fields {
+			fields := graphql.Fields{}
+			for _, c := range graphqlColumns(table) {
+				c := c
+				fieldType := graphqlScalar(c)
+				if !c.Null {
+					fieldType = graphql.NewNonNull(fieldType)
+				}
+				fields[c.Field] = &graphql.Field{
+					Type: fieldType,
+					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+						return graphqlOutput(c, p.Source.(TableRecord)[c.Field])
+					},
+				}
+			}
+
+			for name, rel := range table.Relations {
+				related, ok := b.objects[rel.Table]
+				if !ok || !graphqlName.MatchString(name) {
+					continue
+				}
+				var relType graphql.Output = related
+				if rel.Many {
+					relType = graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(related)))
+				}
+				fields[name] = &graphql.Field{
+					Type:    relType,
+					Resolve: b.resolveRelation(table.Name, name),
+				}
+			}
+			return fields
+		}),
+	})
+}
+
+// resolveRelation takes records embedded by the parent query, records of
+// deeper levels are fetched one parent at a time
+func (b *graphqlBuilder) resolveRelation(tableName, relName string) graphql.FieldResolveFn {
+	return func(p graphql.ResolveParams) (interface{}, error) {
+		record := p.Source.(TableRecord)
+		if related, ok := record[relName]; ok {
+			return related, nil
+		}
+
+		e, table, err := graphqlRequestFrom(p.Context).authorize(http.MethodGet, tableName)
+		if err != nil {
+			return nil, err
+		}
+		rel, ok := table.Relations[relName]
+		if !ok {
+			return nil, NewUnknownRelationError(relName)
+		}
+		if err := e.embed([]TableRecord{record}, []Relation{rel}); err != nil {
+			return nil, err
+		}
+		return record[relName], nil
+	}
+}
+
+// selectedRelations finds relation fields asked for the records of the field
+func selectedRelations(table Table, p graphql.ResolveParams) []Relation {
+	var relations []Relation
+	seen := map[string]bool{}
+	var walk func(set *ast.SelectionSet)
+	walk = func(set *ast.SelectionSet) {
+		if set == nil {
+			return
+		}
+		for _, selection := range set.Selections {
+			switch s := selection.(type) {
+			case *ast.Field:
+				rel, ok := table.Relations[s.Name.Value]
+				if ok && !seen[rel.Name] {
+					seen[rel.Name] = true
+					relations = append(relations, rel)
+				}
+			case *ast.InlineFragment:
+				walk(s.SelectionSet)
+			}
+		}
+	}
+	for _, field := range p.Info.FieldASTs {
+		walk(field.SelectionSet)
+	}
+	return relations
+}
+
+func (b *graphqlBuilder) comparison(scalar graphql.Type) *graphql.InputObject {
+	if comparison, ok := b.comparisons[scalar]; ok {
+		return comparison
+	}
+
+	fields := graphql.InputObjectConfigFieldMap{
+		"is_null": &graphql.InputObjectFieldConfig{Type: graphql.Boolean},
+		"in":      &graphql.InputObjectFieldConfig{Type: graphql.NewList(graphql.NewNonNull(scalar))},
+	}
+	for _, op := range []string{"eq", "neq", "gt", "gte", "lt", "lte"} {
+		fields[op] = &graphql.InputObjectFieldConfig{Type: scalar}
+	}
+	if scalar == graphql.String {
+		fields["like"] = &graphql.InputObjectFieldConfig{Type: graphql.String}
+	}
+
+	comparison := graphql.NewInputObject(graphql.InputObjectConfig{
+		Name:   scalar.Name() + "_comparison",
+		Fields: fields,
+	})
+	b.comparisons[scalar] = comparison
+	return comparison
+}
+
+func (b *graphqlBuilder) listField(table Table, object *graphql.Object) *graphql.Field {
+	filterFields := graphql.InputObjectConfigFieldMap{}
+	for _, c := range graphqlColumns(table) {
+		if scalar := graphqlScalar(c); scalar != JSON {
+			filterFields[c.Field] = &graphql.InputObjectFieldConfig{Type: b.comparison(scalar)}
+		}
+	}
+
+	args := graphql.FieldConfigArgument{
+		"limit":  &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: defaultLimit},
+		"offset": &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: 0},
+		"order": &graphql.ArgumentConfig{
+			Type:        graphql.NewList(graphql.NewNonNull(graphql.String)),
+			Description: `columns to sort by, "-" sorts descending`,
+		},
+	}
+	if len(filterFields) > 0 {
+		args["where"] = &graphql.ArgumentConfig{
+			Type: graphql.NewInputObject(graphql.InputObjectConfig{
+				Name:   table.Name + "_filter",
+				Fields: filterFields,
+			}),
+		}
+	}
+
+	return &graphql.Field{
+		Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(object))),
+		Args: args,
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			e, table, err := graphqlRequestFrom(p.Context).authorize(http.MethodGet, table.Name)
+			if err != nil {
+				return nil, err
+			}
+
+			lq := &ListQuery{
+				Limit:  p.Args["limit"].(int),
+				Offset: p.Args["offset"].(int),
+				Embed:  selectedRelations(table, p),
+			}
+			if where, ok := p.Args["where"].(map[string]interface{}); ok {
+				if lq.Filters, err = graphqlFilters(table, where); err != nil {
+					return nil, err
+				}
+			}
+			if order, ok := p.Args["order"].([]interface{}); ok {
+				for _, field := range order {
+					field := field.(string)
+					o := OrderBy{Column: field}
+					if strings.HasPrefix(field, "-") {
+						o = OrderBy{Column: field[1:], Desc: true}
+					}
+					if _, ok := table.Column(o.Column); !ok {
+						return nil, NewUnknownFieldError(o.Column)
+					}
+					lq.Order = append(lq.Order, o)
+				}
+			}
+
+			res, err := e.handleGetTableRecords(table, lq)
+			if err != nil {
+				return nil, err
+			}
+			if res.Records == nil {
+				return []TableRecord{}, nil
+			}
+			return res.Records, nil
+		},
+	}
+}
+
+// graphqlFilters converts where: {column: {op: value}} to the filters of REST list queries
+func graphqlFilters(table Table, where map[string]interface{}) ([]Filter, error) {
+	var filters []Filter
+	for _, c := range table.Columns {
+		ops, ok := where[c.Field].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		for op, val := range ops {
+			filter := Filter{Column: c, Op: op}
+			switch op {
+			case "is_null":
+				filter.Op = "is"
+				if val == true {
+					filter.Values = []interface{}{"null"}
+				} else {
+					filter.Values = []interface{}{"notnull"}
+				}
+			case "in":
+				items, _ := val.([]interface{})
+				if len(items) == 0 {
+					return nil, NewInvalidFilterError(c.Field + " in []")
+				}
+				for _, item := range items {
+					v, err := graphqlFilterValue(c, item)
+					if err != nil {
+						return nil, err
+					}
+					filter.Values = append(filter.Values, v)
+				}
+			default:
+				v, err := graphqlFilterValue(c, val)
+				if err != nil {
+					return nil, err
+				}
+				filter.Values = []interface{}{v}
+			}
+			filters = append(filters, filter)
+		}
+	}
+	return filters, nil
+}
+
+func graphqlFilterValue(c TableColumn, val interface{}) (interface{}, error) {
+	val = graphqlInput(val)
+	if val == nil || !c.Type.IsValidValue(val) {
+		return nil, NewValidationError(c.Field)
+	}
+	return c.DBValue(val), nil
+}
+
+func (b *graphqlBuilder) byPkField(table Table, object *graphql.Object) *graphql.Field {
+	pk, _ := table.Column(table.Pk)
+	return &graphql.Field{
+		Type: object,
+		Args: graphql.FieldConfigArgument{
+			"id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphqlScalar(pk))},
+		},
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			e, table, err := graphqlRequestFrom(p.Context).authorize(http.MethodGet, table.Name)
+			if err != nil {
+				return nil, err
+			}
+
+			res, err := e.handleGetTableRecord(table, graphqlID(p.Args["id"]))
+			if err != nil {
+				// a missing record is null, not an error
+				return nil, nil
+			}
+			if err := e.embed([]TableRecord{res.Record}, selectedRelations(table, p)); err != nil {
+				return nil, err
+			}
+			return res.Record, nil
+		},
+	}
+}
+
+func graphqlID(val interface{}) int {
+	switch v := val.(type) {
+	case int64:
+		return int(v)
+	case int:
+		return v
+	}
+	return 0
+}
+
+// mutations mirror PUT, POST and DELETE, they return what REST does:
+// the primary key of the new record and the number of changed records
+func (b *graphqlBuilder) mutations(fields graphql.Fields) {
+	input := func(table Table, suffix string) *graphql.InputObject {
+		inputFields := graphql.InputObjectConfigFieldMap{}
+		for _, c := range graphqlColumns(table) {
+			if c.Field != table.Pk {
+				inputFields[c.Field] = &graphql.InputObjectFieldConfig{Type: graphqlScalar(c)}
+			}
+		}
+		if len(inputFields) == 0 {
+			return nil
+		}
+		return graphql.NewInputObject(graphql.InputObjectConfig{
+			Name:   table.Name + suffix,
+			Fields: inputFields,
+		})
+	}
+	record := func(p graphql.ResolveParams, arg string) TableRecord {
+		record := TableRecord{}
+		for field, val := range p.Args[arg].(map[string]interface{}) {
+			record[field] = graphqlInput(val)
+		}
+		return record
+	}
+
+	for _, name := range sortedTableNames(b.insert) {
+		name := name
+		table := b.insert[name]
+		recordInput := input(table, "_insert_input")
+		if !graphqlTableName(name) || table.Pk == "" || recordInput == nil {
+			continue
+		}
+		pk, _ := table.Column(table.Pk)
+		fields["insert_"+name] = &graphql.Field{
+			Type: graphql.NewNonNull(graphqlScalar(pk)),
+			Args: graphql.FieldConfigArgument{
+				"record": &graphql.ArgumentConfig{Type: graphql.NewNonNull(recordInput)},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				g := graphqlRequestFrom(p.Context)
+				e, table, err := g.authorize(http.MethodPut, name)
+				if err != nil {
+					return nil, err
+				}
+				data := record(p, "record")
+				if g.access != nil {
+					if err := g.access.CheckRecord(name, PermissionInsert, data); err != nil {
+						return nil, err
+					}
+				}
+
+				res, err := e.handlePutTableRecord(table, data)
+				if err != nil {
+					return nil, err
+				}
+				return int64((*res)[table.Pk]), nil
+			},
+		}
+	}
+
+	for _, name := range sortedTableNames(b.update) {
+		name := name
+		table := b.update[name]
+		setInput := input(table, "_set_input")
+		if !graphqlTableName(name) || table.Pk == "" || setInput == nil {
+			continue
+		}
+		pk, _ := table.Column(table.Pk)
+		fields["update_"+name] = &graphql.Field{
+			Type: graphql.NewNonNull(graphql.Int),
+			Args: graphql.FieldConfigArgument{
+				"id":  &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphqlScalar(pk))},
+				"set": &graphql.ArgumentConfig{Type: graphql.NewNonNull(setInput)},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				g := graphqlRequestFrom(p.Context)
+				e, table, err := g.authorize(http.MethodPost, name)
+				if err != nil {
+					return nil, err
+				}
+				data := record(p, "set")
+				if g.access != nil {
+					if err := g.access.CheckRecord(name, PermissionUpdate, data); err != nil {
+						return nil, err
+					}
+				}
+
+				res, err := e.handlePostTableRecord(table, graphqlID(p.Args["id"]), data)
+				if err != nil {
+					return nil, err
+				}
+				return res.Updated, nil
+			},
+		}
+	}
+
+	for _, name := range sortedTableNames(b.delete) {
+		name := name
+		table := b.delete[name]
+		if !graphqlTableName(name) || table.Pk == "" {
+			continue
+		}
+		pk, _ := table.Column(table.Pk)
+		fields["delete_"+name] = &graphql.Field{
+			Type: graphql.NewNonNull(graphql.Int),
+			Args: graphql.FieldConfigArgument{
+				"id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphqlScalar(pk))},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				e, table, err := graphqlRequestFrom(p.Context).authorize(http.MethodDelete, name)
+				if err != nil {
+					return nil, err
+				}
+
+				res, err := e.handleDeleteTableRecord(table, graphqlID(p.Args["id"]))
+				if err != nil {
+					return nil, err
+				}
+				return res.Deleted, nil
+			},
+		}
+	}
+}
+
+type GraphQLRequest struct {
+	Query         string                 `json:"query"`
+	Variables     map[string]interface{} `json:"variables"`
+	OperationName string                 `json:"operationName"`
+}
+
+// graphqlResponse is written as is, without the REST envelope
+type graphqlResponse struct {
+	result *graphql.Result
+}
+
+func (g graphqlResponse) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(g.result)
+}
+
+// graphqlSchema builds the schema for the api key once per schema snapshot
+func (e *DbExplorer) graphqlSchema(r *http.Request, access *KeyAccess) (graphql.Schema, error) {
+	key := "*"
+	if access != nil {
+		key = "key:" + r.Header.Get("X-Api-Key")
+	}
+	if schema, ok := e.current.graphql.Load(key); ok {
+		return schema.(graphql.Schema), nil
+	}
+
+	schema, err := newGraphQLSchema(e.tables, access)
+	if err != nil {
+		return graphql.Schema{}, err
+	}
+	e.current.graphql.Store(key, schema)
+	return schema, nil
+}
+
+// handleGraphQL serves GET /graphql?query=... and POST /graphql with a JSON body
+func (e *DbExplorer) handleGraphQL(access *KeyAccess, r *http.Request) (interface{}, error) {
+	var req GraphQLRequest
+	switch r.Method {
+	case http.MethodGet:
+		q := r.URL.Query()
+		req.Query = q.Get("query")
+		req.OperationName = q.Get("operationName")
+		if variables := q.Get("variables"); variables != "" {
+			if err := json.Unmarshal([]byte(variables), &req.Variables); err != nil {
+				return nil, ResponseError{"invalid variables", http.StatusBadRequest}
+			}
+		}
+		if isMutation(req.Query, req.OperationName) {
+			return nil, ResponseError{"mutations are allowed only with POST", http.StatusMethodNotAllowed}
+		}
+	case http.MethodPost:
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			return nil, ResponseError{"invalid json body", http.StatusBadRequest}
+		}
+	default:
+		return nil, ResponseError{"method not found", http.StatusNotFound}
+	}
+
+	schema, err := e.graphqlSchema(r, access)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build graphql schema: %s", err)
+	}
+
+	ctx := context.WithValue(r.Context(), graphqlRequestKey{}, &graphqlRequest{
+		explorer: e,
+		access:   access,
+		request:  r,
+	})
+	return graphqlResponse{graphql.Do(graphql.Params{
+		Schema:         schema,
+		RequestString:  req.Query,
+		VariableValues: req.Variables,
+		OperationName:  req.OperationName,
+		Context:        ctx,
+	})}, nil
+}
+
+// isMutation tells if the operation a GET request would run is a mutation,
+// without an operation name every operation of the document counts: with
+// more than one graphql refuses to run it anyway. Documents which don't
+// parse are left to graphql to report
+func isMutation(query, operationName string) bool {
+	doc, err := parser.Parse(parser.ParseParams{Source: query})
+	if err != nil {
+		return false
+	}
+	for _, def := range doc.Definitions {
+		op, ok := def.(*ast.OperationDefinition)
+		if !ok || op.Operation != ast.OperationTypeMutation {
+			continue
+		}
+		if operationName == "" || op.Name != nil && op.Name.Value == operationName {
+			return true
+		}
+	}
+	return false
+}
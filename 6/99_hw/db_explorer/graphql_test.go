@@ -0,0 +1,276 @@
+package main
+
+import (
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestGraphQL(t *testing.T) {
+	ts, _, db := newTestServer(t, PrepareRelationTables)
+
+	query := func(q string, variables CR) GraphQLRequest {
+		return GraphQLRequest{Query: q, Variables: variables}
+	}
+
+	cases := []Case{
+		Case{
+			Method: http.MethodPost,
+			Path:   "/graphql",
+			Body:   query(`{ items(where: {title: {like: "%e%"}}, order: ["-id"]) { id title author { login } } }`, nil),
+			Result: CR{
+				"data": CR{
+					"items": []CR{
+						CR{"id": 2, "title": "memcache", "author": CR{"login": "rvasily"}},
+						CR{"id": 1, "title": "database/sql", "author": CR{"login": "rvasily"}},
+					},
+				},
+			},
+		},
+		Case{
+			Method: http.MethodPost,
+			Path:   "/graphql",
+			Body:   query(`{ items(limit: 1, offset: 1, where: {author_id: {is_null: false}}) { title reviewer { login } } }`, nil),
+			Result: CR{
+				"data": CR{
+					"items": []CR{
+						CR{"title": "memcache", "reviewer": nil},
+					},
+				},
+			},
+		},
+		Case{
+			Method: http.MethodPost,
+			Path:   "/graphql",
+			Body:   query(`{ items(where: {id: {in: [1, 3]}}) { title } }`, nil),
+			Result: CR{
+				"data": CR{
+					"items": []CR{
+						CR{"title": "database/sql"},
+						CR{"title": "grpc"},
+					},
+				},
+			},
+		},
+		// связи вложенных записей тоже раскрываются
+		Case{
+			Method: http.MethodPost,
+			Path:   "/graphql",
+			Body: query(`query user($id: Int64!) { users_by_pk(id: $id) { login items { title author { login } } } }`,
+				CR{"id": 1}),
+			Result: CR{
+				"data": CR{
+					"users_by_pk": CR{
+						"login": "rvasily",
+						"items": []CR{
+							CR{"title": "database/sql", "author": CR{"login": "rvasily"}},
+							CR{"title": "memcache", "author": CR{"login": "rvasily"}},
+						},
+					},
+				},
+			},
+		},
+		Case{
+			Path:  "/graphql",
+			Query: "query=%7B%20users_by_pk(id%3A%2042)%20%7B%20login%20%7D%20%7D",
+			Result: CR{
+				"data": CR{
+					"users_by_pk": nil,
+				},
+			},
+		},
+		Case{
+			Method: http.MethodPost,
+			Path:   "/graphql",
+			Body:   query(`mutation { insert_users(record: {login: "petya"}) }`, nil),
+			Result: CR{
+				"data": CR{
+					"insert_users": 3,
+				},
+			},
+		},
+		Case{
+			Method: http.MethodPost,
+			Path:   "/graphql",
+			Body:   query(`mutation { update_items(id: 3, set: {author_id: 3}) delete_items(id: 2) }`, nil),
+			Result: CR{
+				"data": CR{
+					"update_items": 1,
+					"delete_items": 1,
+				},
+			},
+		},
+		Case{
+			Method: http.MethodPost,
+			Path:   "/graphql",
+			Body:   query(`{ users(order: ["user_id"]) { login items { id } } }`, nil),
+			Result: CR{
+				"data": CR{
+					"users": []CR{
+						CR{"login": "rvasily", "items": []CR{CR{"id": 1}}},
+						CR{"login": "ivan", "items": []CR{}},
+						CR{"login": "petya", "items": []CR{CR{"id": 3}}},
+					},
+				},
+			},
+		},
+		Case{
+			Method: http.MethodPost,
+			Path:   "/graphql",
+			Body:   query(`{ items(order: ["nothing"]) { id } }`, nil),
+			Result: CR{
+				"data": nil,
+				"errors": []CR{
+					CR{
+						"message":   "unknown field nothing",
+						"locations": []CR{CR{"line": 1, "column": 3}},
+						"path":      []string{"items"},
+					},
+				},
+			},
+		},
+		Case{
+			Path:   "/graphql",
+			Query:  "query=mutation%20%7B%20delete_items(id%3A%201)%20%7D",
+			Status: http.StatusMethodNotAllowed,
+			Result: CR{
+				"error": "mutations are allowed only with POST",
+			},
+		},
+		// the mutation is picked by operationName, not by the start of the query
+		Case{
+			Path:   "/graphql",
+			Query:  url.Values{"query": {`query A { items(where: {id: {eq: 1}}) { id } } mutation B { delete_items(id: 1) }`}, "operationName": {"B"}}.Encode(),
+			Status: http.StatusMethodNotAllowed,
+			Result: CR{
+				"error": "mutations are allowed only with POST",
+			},
+		},
+		Case{
+			Path:   "/graphql",
+			Query:  url.Values{"query": {`{ items { id } } mutation B { delete_items(id: 1) }`}, "operationName": {"B"}}.Encode(),
+			Status: http.StatusMethodNotAllowed,
+			Result: CR{
+				"error": "mutations are allowed only with POST",
+			},
+		},
+		Case{
+			Path:   "/graphql",
+			Query:  url.Values{"query": {"# comment\nmutation { delete_items(id: 1) }"}}.Encode(),
+			Status: http.StatusMethodNotAllowed,
+			Result: CR{
+				"error": "mutations are allowed only with POST",
+			},
+		},
+		// the query of the same document still runs, item 1 is still there
+		Case{
+			Path:  "/graphql",
+			Query: url.Values{"query": {`query A { items(where: {id: {eq: 1}}) { id } } mutation B { delete_items(id: 1) }`}, "operationName": {"A"}}.Encode(),
+			Result: CR{
+				"data": CR{
+					"items": []CR{
+						CR{"id": 1},
+					},
+				},
+			},
+		},
+		Case{
+			Method: http.MethodPut,
+			Path:   "/graphql",
+			Status: http.StatusNotFound,
+			Result: CR{
+				"error": "method not found",
+			},
+		},
+	}
+
+	runCases(t, ts, db, cases)
+}
+
+// у ключа в схеме есть только то, что ему разрешено
+func TestGraphQLAccess(t *testing.T) {
+	ts, handler, db := newTestServer(t, PrepareTestApis)
+
+	path := filepath.Join(t.TempDir(), "access.json")
+	if err := os.WriteFile(path, []byte(testAccessConfig), 0o600); err != nil {
+		panic(err)
+	}
+	config, err := LoadAccessConfig(path)
+	if err != nil {
+		t.Fatalf("cant load access config: %v", err)
+	}
+
+	handler.SetAccessConfig(config)
+
+	editor := http.Header{"X-Api-Key": {"editor"}}
+
+	cases := []Case{
+		Case{
+			Method: http.MethodPost,
+			Path:   "/graphql",
+			Body:   GraphQLRequest{Query: `{ items(limit: 1) { id title } }`},
+			Result: CR{
+				"data": CR{
+					"items": []CR{
+						CR{"id": 1, "title": "database/sql"},
+					},
+				},
+			},
+		},
+		Case{
+			Method: http.MethodPost,
+			Path:   "/graphql",
+			Body:   GraphQLRequest{Query: `{ items(limit: 1) { description } }`},
+			Status: http.StatusOK,
+			Result: CR{
+				"data": nil,
+				"errors": []CR{
+					CR{
+						"message":   `Cannot query field "description" on type "items".`,
+						"locations": []CR{CR{"line": 1, "column": 21}},
+					},
+				},
+			},
+		},
+		Case{
+			Method: http.MethodPost,
+			Path:   "/graphql",
+			Header: editor,
+			Body:   GraphQLRequest{Query: `mutation { update_users(id: 1, set: {login: "admin"}) }`},
+			Result: CR{
+				"data": CR{
+					"update_users": 1,
+				},
+			},
+		},
+		Case{
+			Method: http.MethodPost,
+			Path:   "/graphql",
+			Header: editor,
+			Body:   GraphQLRequest{Query: `mutation { update_items(id: 1, set: {updated: "me"}) }`},
+			Result: CR{
+				"data": nil,
+				"errors": []CR{
+					CR{
+						"message":   "Argument \"set\" has invalid value {updated: \"me\"}.\nIn field \"updated\": Unknown field.",
+						"locations": []CR{CR{"line": 1, "column": 37}},
+					},
+				},
+			},
+		},
+		Case{
+			Method: http.MethodPost,
+			Path:   "/graphql",
+			Header: http.Header{"X-Api-Key": {"nobody"}},
+			Body:   GraphQLRequest{Query: `{ items { id } }`},
+			Status: http.StatusUnauthorized,
+			Result: CR{
+				"error": "unauthorized",
+			},
+		},
+	}
+
+	runCases(t, ts, db, cases)
+}
@@ -0,0 +1,209 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+const (
+	// maxImportErrors bounds the response, the rest of the lines are still checked
+	maxImportErrors = 100
+	maxImportLine   = 16 << 20
+)
+
+type ImportError struct {
+	Line  int    `json:"line"`
+	Error string `json:"error"`
+}
+
+type ImportTableRecordsResponse struct {
+	Inserted int `json:"inserted"`
+}
+
+// importHandler gets a record or an error of the line, lines are numbered from 1
+type importHandler func(line int, record TableRecord, err error)
+
+// handleImportTableRecords inserts records from CSV with a header line or from
+// NDJSON. Every record is validated like an update, the primary key is ignored
+// like on PUT. Nothing is inserted if any line is invalid
+func (e *DbExplorer) handleImportTableRecords(table Table, format string, body io.Reader, check func(TableRecord) error) (*ImportTableRecordsResponse, error) {
+	// inside /_batch the batch transaction is used
+	importer := *e
+	var tx *sql.Tx
+	if _, inTx := e.conn.(*sql.Tx); !inTx {
+		var err error
+		tx, err = e.db.Begin()
+		if err != nil {
+			return nil, err
+		}
+		defer tx.Rollback()
+		importer.conn = tx
+	}
+
+	var (
+		res        = &ImportTableRecordsResponse{}
+		lineErrors []ImportError
+		failed     bool
+	)
+	handle := func(line int, record TableRecord, err error) {
+		if err == nil {
+			delete(record, table.Pk)
+			if check != nil {
+				err = check(record)
+			}
+		}
+		if err == nil {
+			err = table.ValidateRecord(record)
+		}
+		// after the first error the rest is only validated, it'll be rolled back anyway
+		if err == nil && !failed {
+			_, err = importer.insertRecord(table, record)
+		}
+
+		if err != nil {
+			failed = true
+			if len(lineErrors) < maxImportErrors {
+				lineErrors = append(lineErrors, ImportError{Line: line, Error: err.Error()})
+			}
+			return
+		}
+		res.Inserted++
+	}
+
+	var err error
+	switch format {
+	case FormatCSV:
+		err = readCSVRecords(table, body, handle)
+	case FormatNDJSON:
+		err = readNDJSONRecords(body, handle)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if failed {
+		return nil, DetailedResponseError{
+			ResponseError: ResponseError{"invalid records, nothing is imported", http.StatusBadRequest},
+			Details:       lineErrors,
+		}
+	}
+	if tx != nil {
+		if err := tx.Commit(); err != nil {
+			return nil, err
+		}
+	}
+	return res, nil
+}
+
+// readCSVRecords reads a header line with column names, then records. An empty
+// field is NULL for nullable columns
+func readCSVRecords(table Table, body io.Reader, handle importHandler) error {
+	reader := csv.NewReader(body)
+	reader.ReuseRecord = true
+
+	header, err := reader.Read()
+	switch {
+	case err == io.EOF:
+		return nil
+	case err != nil:
+		return csvLineError(err, handle)
+	}
+
+	columns := make([]TableColumn, len(header))
+	for i, name := range header {
+		c, ok := table.Column(name)
+		if !ok {
+			handle(1, nil, NewUnknownFieldError(name))
+			return nil
+		}
+		columns[i] = c
+	}
+
+	for {
+		fields, err := reader.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			if errors.Is(err, csv.ErrFieldCount) {
+				handle(err.(*csv.ParseError).StartLine, nil, csv.ErrFieldCount)
+				continue
+			}
+			return csvLineError(err, handle)
+		}
+
+		line, _ := reader.FieldPos(0)
+		record := make(TableRecord, len(fields))
+		for i, field := range fields {
+			record[columns[i].Field] = csvValue(columns[i], field)
+		}
+		handle(line, record, nil)
+	}
+}
+
+// csvLineError reports a broken line, the reader can't go on after it
+func csvLineError(err error, handle importHandler) error {
+	var parseErr *csv.ParseError
+	if !errors.As(err, &parseErr) {
+		return err
+	}
+	handle(parseErr.StartLine, nil, parseErr.Err)
+	return nil
+}
+
+// csvValue converts a field back to what the export wrote, see csvField
+func csvValue(c TableColumn, field string) interface{} {
+	if field == "" && c.Null {
+		return nil
+	}
+	if _, ok := c.Type.(JSONColumn); ok {
+		var val interface{}
+		decoder := json.NewDecoder(bytes.NewReader([]byte(field)))
+		decoder.UseNumber()
+		if err := decoder.Decode(&val); err == nil {
+			return val
+		}
+	}
+	val, _ := textValue(c, field)
+	return val
+}
+
+// readNDJSONRecords reads a JSON object per line, empty lines are skipped
+func readNDJSONRecords(body io.Reader, handle importHandler) error {
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(nil, maxImportLine)
+
+	line := 0
+	for scanner.Scan() {
+		line++
+		data := bytes.TrimSpace(scanner.Bytes())
+		if len(data) == 0 {
+			continue
+		}
+
+		var record TableRecord
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.UseNumber()
+		if err := decoder.Decode(&record); err != nil || record == nil {
+			handle(line, nil, errors.New("invalid json object"))
+			continue
+		}
+		handle(line, record, nil)
+	}
+
+	if err := scanner.Err(); err != nil {
+		if err == bufio.ErrTooLong {
+			handle(line+1, nil, fmt.Errorf("line is longer than %d bytes", maxImportLine))
+			return nil
+		}
+		return err
+	}
+	return nil
+}
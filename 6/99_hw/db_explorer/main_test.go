@@ -11,9 +11,9 @@ import (
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"time"
-
-	_ "github.com/go-sql-driver/mysql"
 )
 
 // CaseResponse
@@ -26,16 +26,33 @@ type Case struct {
 	Status int
 	Result interface{}
 	Body   interface{}
+	Header http.Header
 }
 
 var (
 	client = &http.Client{Timeout: time.Second}
 )
 
-func PrepareTestApis(db *sql.DB) {
-	qs := []string{
-		`DROP TABLE IF EXISTS items;`,
+// без DB_DRIVER тесты идут на sqlite во временном файле, докер не нужен
+// make test-mysql гоняет их на mysql, как в грейдере
+func TestMain(m *testing.M) {
+	if os.Getenv("DB_DRIVER") == "" {
+		dir, err := os.MkdirTemp("", "db_explorer")
+		if err != nil {
+			panic(err)
+		}
+		Driver, DSN = "sqlite", "file:"+filepath.Join(dir, "golang.db")
+
+		code := m.Run()
+		os.RemoveAll(dir)
+		os.Exit(code)
+	}
+	os.Exit(m.Run())
+}
 
+// схема одна и та же, но DDL у каждой базы свой
+var createTestTables = map[string][]string{
+	"mysql": {
 		`CREATE TABLE items (
   id int(11) NOT NULL AUTO_INCREMENT,
   title varchar(255) NOT NULL,
@@ -43,13 +60,6 @@ func PrepareTestApis(db *sql.DB) {
   updated varchar(255) DEFAULT NULL,
   PRIMARY KEY (id)
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8;`,
-
-		`INSERT INTO items (id, title, description, updated) VALUES
-(1,	'database/sql',	'Рассказать про базы данных',	'rvasily'),
-(2,	'memcache',	'Рассказать про мемкеш с примером использования',	NULL);`,
-
-		`DROP TABLE IF EXISTS users;`,
-
 		`CREATE TABLE users (
 			user_id int(11) NOT NULL AUTO_INCREMENT,
   login varchar(255) NOT NULL,
@@ -59,10 +69,63 @@ func PrepareTestApis(db *sql.DB) {
   updated varchar(255) DEFAULT NULL,
   PRIMARY KEY (user_id)
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8;`,
+	},
+	"postgres": {
+		`CREATE TABLE items (
+  id serial PRIMARY KEY,
+  title varchar(255) NOT NULL,
+  description text NOT NULL,
+  updated varchar(255) DEFAULT NULL
+);`,
+		`CREATE TABLE users (
+  user_id serial PRIMARY KEY,
+  login varchar(255) NOT NULL,
+  password varchar(255) NOT NULL,
+  email varchar(255) NOT NULL,
+  info text NOT NULL,
+  updated varchar(255) DEFAULT NULL
+);`,
+	},
+	"sqlite": {
+		`CREATE TABLE items (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  title varchar(255) NOT NULL,
+  description text NOT NULL,
+  updated varchar(255) DEFAULT NULL
+);`,
+		`CREATE TABLE users (
+  user_id INTEGER PRIMARY KEY AUTOINCREMENT,
+  login varchar(255) NOT NULL,
+  password varchar(255) NOT NULL,
+  email varchar(255) NOT NULL,
+  info text NOT NULL,
+  updated varchar(255) DEFAULT NULL
+);`,
+	},
+}
+
+func PrepareTestApis(db *sql.DB) {
+	qs := []string{
+		`DROP TABLE IF EXISTS items;`,
+		createTestTables[Driver][0],
+
+		`INSERT INTO items (id, title, description, updated) VALUES
+(1,	'database/sql',	'Рассказать про базы данных',	'rvasily'),
+(2,	'memcache',	'Рассказать про мемкеш с примером использования',	NULL);`,
+
+		`DROP TABLE IF EXISTS users;`,
+		createTestTables[Driver][1],
 
 		`INSERT INTO users (user_id, login, password, email, info, updated) VALUES
 (1,	'rvasily',	'love',	'rvasily@example.com',	'none',	NULL);`,
 	}
+	if Driver == "postgres" {
+		// в отличие от auto_increment sequence не сдвигается при вставке явных id
+		qs = append(qs,
+			`SELECT setval('items_id_seq', (SELECT MAX(id) FROM items));`,
+			`SELECT setval('users_user_id_seq', (SELECT MAX(user_id) FROM users));`,
+		)
+	}
 
 	for _, q := range qs {
 		_, err := db.Exec(q)
@@ -85,8 +148,37 @@ func CleanupTestApis(db *sql.DB) {
 	}
 }
 
+// newTestServer создает таблицы через prepare и поднимает DbExplorer поверх них,
+// сервер, таблицы и коннект к базе убираются в t.Cleanup
+func newTestServer(t *testing.T, prepare func(*sql.DB)) (*httptest.Server, *DbExplorer, *sql.DB) {
+	t.Helper()
+	db, err := sql.Open(Driver, DSN)
+	if err != nil {
+		t.Fatalf("cant open db: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if err := db.Ping(); err != nil {
+		t.Fatalf("cant connect to db: %v", err)
+	}
+
+	prepare(db)
+	t.Cleanup(func() { CleanupTestApis(db) })
+
+	handler, err := NewDbExplorer(db)
+	if err != nil {
+		t.Fatalf("cant create explorer: %v", err)
+	}
+
+	ts := httptest.NewServer(handler)
+	t.Cleanup(ts.Close)
+	return ts, handler, db
+}
+
 func TestApis(t *testing.T) {
-	db, err := sql.Open("mysql", DSN)
+	db, err := sql.Open(Driver, DSN)
+	if err != nil {
+		panic(err)
+	}
 	err = db.Ping()
 	if err != nil {
 		panic(err)
@@ -500,6 +592,127 @@ func TestApis(t *testing.T) {
 	runCases(t, ts, db, cases)
 }
 
+func TestListQuery(t *testing.T) {
+	ts, _, db := newTestServer(t, PrepareTestApis)
+
+	cases := []Case{
+		Case{
+			Path:  "/items",
+			Query: "where=id>1",
+			Result: CR{
+				"response": CR{
+					"records": []CR{
+						CR{
+							"id":          2,
+							"title":       "memcache",
+							"description": "Рассказать про мемкеш с примером использования",
+							"updated":     nil,
+						},
+					},
+				},
+			},
+		},
+		Case{
+			Path:  "/items",
+			Query: "fields=id,title&order=-id",
+			Result: CR{
+				"response": CR{
+					"records": []CR{
+						CR{"id": 2, "title": "memcache"},
+						CR{"id": 1, "title": "database/sql"},
+					},
+				},
+			},
+		},
+		Case{
+			Path:  "/items",
+			Query: "fields=title&updated=eq.rvasily",
+			Result: CR{
+				"response": CR{
+					"records": []CR{
+						CR{"title": "database/sql"},
+					},
+				},
+			},
+		},
+		Case{
+			Path:  "/items",
+			Query: "fields=id&updated=is.null",
+			Result: CR{
+				"response": CR{
+					"records": []CR{
+						CR{"id": 2},
+					},
+				},
+			},
+		},
+		Case{
+			Path:  "/items",
+			Query: "fields=id&id=in.(1,2)&where=title~mem%25&limit=10",
+			Result: CR{
+				"response": CR{
+					"records": []CR{
+						CR{"id": 2},
+					},
+				},
+			},
+		},
+		// значения всегда передаются плейсхолдерами
+		Case{
+			Path:  "/items",
+			Query: "title=eq.x'%20OR%20'1'%3D'1",
+			Result: CR{
+				"response": CR{
+					"records": nil,
+				},
+			},
+		},
+		// ошибки
+		Case{
+			Path:   "/items",
+			Query:  "where=id>abc",
+			Status: http.StatusBadRequest,
+			Result: CR{
+				"error": "field id have invalid type",
+			},
+		},
+		Case{
+			Path:   "/items",
+			Query:  "where=password=love",
+			Status: http.StatusBadRequest,
+			Result: CR{
+				"error": "unknown field password",
+			},
+		},
+		Case{
+			Path:   "/items",
+			Query:  "order=id%20DESC",
+			Status: http.StatusBadRequest,
+			Result: CR{
+				"error": "unknown field id DESC",
+			},
+		},
+		Case{
+			Path:   "/items",
+			Query:  "fields=id,secret",
+			Status: http.StatusBadRequest,
+			Result: CR{
+				"error": "unknown field secret",
+			},
+		},
+		Case{
+			Path:   "/items",
+			Query:  "title=contains.sql",
+			Status: http.StatusBadRequest,
+			Result: CR{
+				"error": "invalid filter title=contains.sql",
+			},
+		},
+	}
+
+	runCases(t, ts, db, cases)
+}
+
 func runCases(t *testing.T, ts *httptest.Server, db *sql.DB, cases []Case) {
 	for idx, item := range cases {
 		var (
@@ -529,6 +742,10 @@ func runCases(t *testing.T, ts *httptest.Server, db *sql.DB, cases []Case) {
 			req.Header.Add("Content-Type", "application/json")
 		}
 
+		for key, values := range item.Header {
+			req.Header[key] = values
+		}
+
 		resp, err := client.Do(req)
 		if err != nil {
 			t.Fatalf("[%s] request error: %v", caseName, err)
@@ -0,0 +1,333 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// listParams are query parameters of GET /$table which are not column filters
+var listParams = map[string]bool{
+	"limit":  true,
+	"offset": true,
+	"where":  true,
+	"order":  true,
+	"fields": true,
+	"embed":  true,
+	"format": true,
+}
+
+// filterOps maps operators of ?column=op.value filters to SQL
+var filterOps = map[string]string{
+	"eq":   "=",
+	"neq":  "<>",
+	"gt":   ">",
+	"gte":  ">=",
+	"lt":   "<",
+	"lte":  "<=",
+	"like": "LIKE",
+	"in":   "IN",
+	"is":   "IS",
+}
+
+// whereOps are operators of ?where=column<op>value, longer ones go first
+var whereOps = []struct {
+	token string
+	op    string
+}{
+	{">=", "gte"},
+	{"<=", "lte"},
+	{"!=", "neq"},
+	{"<>", "neq"},
+	{"=", "eq"},
+	{">", "gt"},
+	{"<", "lt"},
+	{"~", "like"},
+}
+
+// Filter is a condition on a column, values are always bound as query arguments
+type Filter struct {
+	Column TableColumn
+	Op     string
+	Values []interface{}
+}
+
+type OrderBy struct {
+	Column string
+	Desc   bool
+}
+
+// ListQuery is what GET /$table may ask for besides limit and offset:
+//
+//	?where=age>30&where=title~%sql%  conditions, joined by AND
+//	?status=eq.active&id=in.(1,2,3)  the same, one filter per column
+//	?order=-id,name                  "-" sorts descending
+//	?fields=id,title                 columns to return
+//	?embed=author                    related records to inline
+type ListQuery struct {
+	Filters []Filter
+	Order   []OrderBy
+	Fields  []string
+	Embed   []Relation
+	Limit   int
+	Offset  int
+	// LimitSet is false when the default limit is used
+	LimitSet bool
+}
+
+func NewUnknownFieldError(field string) ResponseError {
+	return ResponseError{
+		Text:       fmt.Sprintf("unknown field %s", field),
+		StatusCode: http.StatusBadRequest,
+	}
+}
+
+func NewInvalidFilterError(filter string) ResponseError {
+	return ResponseError{
+		Text:       fmt.Sprintf("invalid filter %s", filter),
+		StatusCode: http.StatusBadRequest,
+	}
+}
+
+func (t *Table) Column(name string) (TableColumn, bool) {
+	for _, c := range t.Columns {
+		if c.Field == name {
+			return c, true
+		}
+	}
+	return TableColumn{}, false
+}
+
+// Select returns the table with only the given columns, in the given order
+func (t *Table) Select(fields []string) (Table, error) {
+	if len(fields) == 0 {
+		return *t, nil
+	}
+
+	selected := *t
+	selected.Columns = make([]TableColumn, 0, len(fields))
+	for _, field := range fields {
+		c, ok := t.Column(field)
+		if !ok {
+			return Table{}, NewUnknownFieldError(field)
+		}
+		selected.Columns = append(selected.Columns, c)
+	}
+	return selected, nil
+}
+
+func (r *Request) GetListQuery(table Table) (*ListQuery, error) {
+	q := r.request.URL.Query()
+	lq := &ListQuery{}
+	lq.Limit, lq.Offset = r.GetLimitOffset()
+	lq.LimitSet = q.Get("limit") != ""
+
+	for _, where := range q["where"] {
+		filter, err := parseWhere(table, where)
+		if err != nil {
+			return nil, err
+		}
+		lq.Filters = append(lq.Filters, filter)
+	}
+
+	// map order is random, columns are walked instead to keep the query stable
+	for _, c := range table.Columns {
+		if listParams[c.Field] {
+			continue
+		}
+		for _, value := range q[c.Field] {
+			op, arg, ok := strings.Cut(value, ".")
+			if !ok || filterOps[op] == "" {
+				return nil, NewInvalidFilterError(c.Field + "=" + value)
+			}
+			filter, err := newFilter(c, op, arg)
+			if err != nil {
+				return nil, err
+			}
+			lq.Filters = append(lq.Filters, filter)
+		}
+	}
+	// op.value filters on something which is not a column are most likely typos
+	for param, values := range q {
+		if _, ok := table.Column(param); ok || listParams[param] {
+			continue
+		}
+		for _, value := range values {
+			if op, _, ok := strings.Cut(value, "."); ok && filterOps[op] != "" {
+				return nil, NewUnknownFieldError(param)
+			}
+		}
+	}
+
+	if order := q.Get("order"); order != "" {
+		for _, field := range strings.Split(order, ",") {
+			o := OrderBy{Column: strings.TrimPrefix(field, "+")}
+			if strings.HasPrefix(field, "-") {
+				o = OrderBy{Column: field[1:], Desc: true}
+			}
+			if _, ok := table.Column(o.Column); !ok {
+				return nil, NewUnknownFieldError(o.Column)
+			}
+			lq.Order = append(lq.Order, o)
+		}
+	}
+
+	if fields := q.Get("fields"); fields != "" {
+		lq.Fields = strings.Split(fields, ",")
+		if _, err := table.Select(lq.Fields); err != nil {
+			return nil, err
+		}
+	}
+
+	embed, err := r.GetEmbed(table)
+	if err != nil {
+		return nil, err
+	}
+	lq.Embed = embed
+
+	return lq, nil
+}
+
+// parseWhere parses column<op>value, e.g. age>30
+func parseWhere(table Table, where string) (Filter, error) {
+	i := strings.IndexAny(where, "=!<>~")
+	if i <= 0 {
+		return Filter{}, NewInvalidFilterError(where)
+	}
+
+	name, rest := where[:i], where[i:]
+	c, ok := table.Column(name)
+	if !ok {
+		return Filter{}, NewUnknownFieldError(name)
+	}
+
+	for _, w := range whereOps {
+		if value, ok := strings.CutPrefix(rest, w.token); ok {
+			// where=updated=null is the same as updated=is.null
+			if value == "null" && (w.op == "eq" || w.op == "neq") {
+				if w.op == "eq" {
+					return newFilter(c, "is", "null")
+				}
+				return newFilter(c, "is", "notnull")
+			}
+			return newFilter(c, w.op, value)
+		}
+	}
+	return Filter{}, NewInvalidFilterError(where)
+}
+
+func newFilter(c TableColumn, op, arg string) (Filter, error) {
+	filter := Filter{Column: c, Op: op}
+
+	switch op {
+	case "is":
+		if arg != "null" && arg != "notnull" {
+			return Filter{}, NewInvalidFilterError(c.Field + "=is." + arg)
+		}
+		filter.Values = []interface{}{arg}
+		return filter, nil
+	case "like":
+		if _, ok := c.Type.(StringColumn); !ok {
+			return Filter{}, NewInvalidFilterError(c.Field + "=like." + arg)
+		}
+		filter.Values = []interface{}{arg}
+		return filter, nil
+	case "in":
+		if !strings.HasPrefix(arg, "(") || !strings.HasSuffix(arg, ")") || len(arg) == 2 {
+			return Filter{}, NewInvalidFilterError(c.Field + "=in." + arg)
+		}
+		for _, item := range strings.Split(arg[1:len(arg)-1], ",") {
+			value, err := parseQueryValue(c, item)
+			if err != nil {
+				return Filter{}, err
+			}
+			filter.Values = append(filter.Values, value)
+		}
+		return filter, nil
+	}
+
+	value, err := parseQueryValue(c, arg)
+	if err != nil {
+		return Filter{}, err
+	}
+	filter.Values = []interface{}{value}
+	return filter, nil
+}
+
+// textValue converts a value given as text, in the query string or in CSV,
+// to what a JSON body would have: numbers and booleans are tried first, then
+// the string itself. ok is false if the column type accepts neither
+func textValue(c TableColumn, s string) (val interface{}, ok bool) {
+	if json.Valid([]byte(s)) && s != "null" {
+		switch {
+		case s == "true" || s == "false":
+			val = s == "true"
+		case s[0] == '-' || '0' <= s[0] && s[0] <= '9':
+			val = json.Number(s)
+		}
+		if val != nil && c.Type.IsValidValue(val) {
+			return val, true
+		}
+	}
+	return s, c.Type.IsValidValue(s)
+}
+
+// parseQueryValue converts a value from the query string to a query argument of the column type
+func parseQueryValue(c TableColumn, s string) (interface{}, error) {
+	val, ok := textValue(c, s)
+	if !ok {
+		return nil, NewValidationError(c.Field)
+	}
+	return c.Type.DBValue(val), nil
+}
+
+// where builds the WHERE clause, placeholders are numbered after args
+func (e *DbExplorer) where(filters []Filter, args []interface{}) (string, []interface{}) {
+	if len(filters) == 0 {
+		return "", args
+	}
+
+	conds := make([]string, 0, len(filters))
+	for _, f := range filters {
+		col := e.quote(f.Column.Field)
+		switch f.Op {
+		case "is":
+			if f.Values[0] == "null" {
+				conds = append(conds, col+" IS NULL")
+			} else {
+				conds = append(conds, col+" IS NOT NULL")
+			}
+		case "in":
+			placeholders := make([]string, len(f.Values))
+			for i, v := range f.Values {
+				args = append(args, v)
+				placeholders[i] = e.dialect.Placeholder(len(args))
+			}
+			conds = append(conds, fmt.Sprintf("%s IN (%s)", col, strings.Join(placeholders, ", ")))
+		default:
+			args = append(args, f.Values[0])
+			conds = append(conds, fmt.Sprintf("%s %s %s", col, filterOps[f.Op], e.dialect.Placeholder(len(args))))
+		}
+	}
+	return " WHERE " + strings.Join(conds, " AND "), args
+}
+
+// orderBy sorts by the primary key unless asked otherwise, so pages are stable
+func (e *DbExplorer) orderBy(table Table, order []OrderBy) string {
+	if len(order) == 0 {
+		if table.Pk == "" {
+			return ""
+		}
+		order = []OrderBy{{Column: table.Pk}}
+	}
+
+	parts := make([]string, len(order))
+	for i, o := range order {
+		parts[i] = e.quote(o.Column)
+		if o.Desc {
+			parts[i] += " DESC"
+		}
+	}
+	return " ORDER BY " + strings.Join(parts, ", ")
+}
@@ -0,0 +1,221 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// maxInArgs limits the number of values in one IN (...), sqlite allows
+// only 999 bind parameters per statement in older versions
+const maxInArgs = 500
+
+// ForeignKey is a reference from a column to a column of another table
+type ForeignKey struct {
+	Column    string
+	RefTable  string
+	RefColumn string
+}
+
+// Relation connects records of a table with records of another one.
+// A foreign key items.author_id -> users.user_id gives items the relation
+// "author" to one user and users the reverse relation "items" to many items
+type Relation struct {
+	Name string
+	// Table is the related table
+	Table string
+	// Column of this table is matched against RefColumn of the related table
+	Column    string
+	RefColumn string
+	Many      bool
+}
+
+func NewUnknownRelationError(name string) ResponseError {
+	return ResponseError{
+		Text:       fmt.Sprintf("unknown relation %s", name),
+		StatusCode: http.StatusBadRequest,
+	}
+}
+
+// linkRelations builds relations of all tables from their foreign keys
+func linkRelations(tables map[string]Table) {
+	for _, name := range sortedTableNames(tables) {
+		table := tables[name]
+		for _, fk := range table.ForeignKeys {
+			ref, ok := tables[fk.RefTable]
+			if !ok {
+				continue
+			}
+			refColumn := fk.RefColumn
+			if refColumn == "" {
+				refColumn = ref.Pk
+			}
+			if _, ok := ref.Column(refColumn); !ok {
+				continue
+			}
+
+			short := strings.TrimSuffix(fk.Column, "_id")
+			if forward, ok := relationName(table, short, fk.RefTable, fk.Column+"_"+fk.RefTable); ok {
+				table.Relations[forward] = Relation{
+					Name:      forward,
+					Table:     ref.Name,
+					Column:    fk.Column,
+					RefColumn: refColumn,
+				}
+			}
+			if reverse, ok := relationName(ref, table.Name, table.Name+"_"+short); ok {
+				ref.Relations[reverse] = Relation{
+					Name:      reverse,
+					Table:     table.Name,
+					Column:    refColumn,
+					RefColumn: fk.Column,
+					Many:      true,
+				}
+			}
+		}
+	}
+}
+
+// relationName picks the first candidate which clashes neither with
+// a column nor with another relation, embedded records are put next to columns
+func relationName(table Table, candidates ...string) (string, bool) {
+	for _, name := range candidates {
+		if name == "" {
+			continue
+		}
+		if _, ok := table.Column(name); ok {
+			continue
+		}
+		if _, ok := table.Relations[name]; ok {
+			continue
+		}
+		return name, true
+	}
+	return "", false
+}
+
+// GetEmbed parses ?embed=author,items
+func (r *Request) GetEmbed(table Table) ([]Relation, error) {
+	embed := r.request.URL.Query().Get("embed")
+	if embed == "" {
+		return nil, nil
+	}
+
+	var relations []Relation
+	for _, name := range strings.Split(embed, ",") {
+		rel, ok := table.Relations[name]
+		if !ok {
+			return nil, NewUnknownRelationError(name)
+		}
+		relations = append(relations, rel)
+	}
+	return relations, nil
+}
+
+// valueKey identifies a scanned or decoded value regardless of its go type,
+// an empty key is returned for NULL
+func valueKey(val interface{}) string {
+	data, err := json.Marshal(val)
+	if err != nil || string(data) == "null" {
+		return ""
+	}
+	return string(data)
+}
+
+// keyValue converts a key back to a query argument of the column type
+func keyValue(c TableColumn, key string) (interface{}, bool) {
+	decoder := json.NewDecoder(bytes.NewReader([]byte(key)))
+	decoder.UseNumber()
+
+	var val interface{}
+	if err := decoder.Decode(&val); err != nil || !c.Type.IsValidValue(val) {
+		return nil, false
+	}
+	return c.DBValue(val), true
+}
+
+// embed attaches related records to records. Every relation takes one query
+// per maxInArgs distinct keys, however many records there are
+func (e *DbExplorer) embed(records []TableRecord, relations []Relation) error {
+	for _, rel := range relations {
+		related := e.tables[rel.Table]
+		refColumn, _ := related.Column(rel.RefColumn)
+
+		var (
+			args []interface{}
+			seen = map[string]bool{}
+		)
+		for _, record := range records {
+			key := valueKey(record[rel.Column])
+			if key == "" || seen[key] {
+				continue
+			}
+			seen[key] = true
+			if val, ok := keyValue(refColumn, key); ok {
+				args = append(args, val)
+			}
+		}
+
+		byKey := map[string][]TableRecord{}
+		for len(args) > 0 {
+			n := len(args)
+			if n > maxInArgs {
+				n = maxInArgs
+			}
+			filter := Filter{Column: refColumn, Op: "in", Values: args[:n]}
+			args = args[n:]
+
+			where, whereArgs := e.where([]Filter{filter}, nil)
+			q := fmt.Sprintf(
+				"SELECT %s FROM %s%s%s",
+				e.selectList(related),
+				e.quote(related.Name),
+				where,
+				e.orderBy(related, nil),
+			)
+			found, err := e.queryRecords(related, q, whereArgs...)
+			if err != nil {
+				return err
+			}
+			for _, record := range found {
+				key := valueKey(record[rel.RefColumn])
+				byKey[key] = append(byKey[key], record)
+			}
+		}
+
+		for _, record := range records {
+			found := byKey[valueKey(record[rel.Column])]
+			switch {
+			case rel.Many && found == nil:
+				record[rel.Name] = []TableRecord{}
+			case rel.Many:
+				record[rel.Name] = found
+			case len(found) > 0:
+				record[rel.Name] = found[0]
+			default:
+				record[rel.Name] = nil
+			}
+		}
+	}
+	return nil
+}
+
+// handleGetRelatedRecords lists records of a reverse relation, e.g. /users/1/items
+func (e *DbExplorer) handleGetRelatedRecords(table Table, id int, rel Relation, lq *ListQuery) (*GetTableRecordsResponse, error) {
+	parent, err := e.handleGetTableRecord(table, id)
+	if err != nil {
+		return nil, err
+	}
+
+	related := e.tables[rel.Table]
+	refColumn, _ := related.Column(rel.RefColumn)
+	val, ok := keyValue(refColumn, valueKey(parent.Record[rel.Column]))
+	if !ok {
+		return &GetTableRecordsResponse{}, nil
+	}
+
+	lq.Filters = append(lq.Filters, Filter{Column: refColumn, Op: "eq", Values: []interface{}{val}})
+	return e.handleGetTableRecords(related, lq)
+}
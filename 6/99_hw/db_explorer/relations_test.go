@@ -0,0 +1,197 @@
+package main
+
+import (
+	"database/sql"
+	"net/http"
+	"testing"
+)
+
+// у items две ссылки на users: автор и ревьюер
+var createRelationTables = map[string][]string{
+	"mysql": {
+		`CREATE TABLE users (
+  user_id int(11) NOT NULL AUTO_INCREMENT,
+  login varchar(255) NOT NULL,
+  PRIMARY KEY (user_id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;`,
+		`CREATE TABLE items (
+  id int(11) NOT NULL AUTO_INCREMENT,
+  title varchar(255) NOT NULL,
+  author_id int(11) DEFAULT NULL,
+  reviewer_id int(11) DEFAULT NULL,
+  PRIMARY KEY (id),
+  FOREIGN KEY (author_id) REFERENCES users (user_id),
+  FOREIGN KEY (reviewer_id) REFERENCES users (user_id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;`,
+	},
+	"postgres": {
+		`CREATE TABLE users (
+  user_id serial PRIMARY KEY,
+  login varchar(255) NOT NULL
+);`,
+		`CREATE TABLE items (
+  id serial PRIMARY KEY,
+  title varchar(255) NOT NULL,
+  author_id integer DEFAULT NULL REFERENCES users (user_id),
+  reviewer_id integer DEFAULT NULL REFERENCES users (user_id)
+);`,
+	},
+	"sqlite": {
+		`CREATE TABLE users (
+  user_id INTEGER PRIMARY KEY AUTOINCREMENT,
+  login varchar(255) NOT NULL
+);`,
+		`CREATE TABLE items (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  title varchar(255) NOT NULL,
+  author_id integer DEFAULT NULL REFERENCES users,
+  reviewer_id integer DEFAULT NULL REFERENCES users (user_id)
+);`,
+	},
+}
+
+func PrepareRelationTables(db *sql.DB) {
+	qs := []string{
+		`DROP TABLE IF EXISTS items;`,
+		`DROP TABLE IF EXISTS users;`,
+		createRelationTables[Driver][0],
+		createRelationTables[Driver][1],
+
+		`INSERT INTO users (user_id, login) VALUES (1, 'rvasily'), (2, 'ivan');`,
+		`INSERT INTO items (id, title, author_id, reviewer_id) VALUES
+(1,	'database/sql',	1,	2),
+(2,	'memcache',	1,	NULL),
+(3,	'grpc',	NULL,	1);`,
+	}
+	for _, q := range qs {
+		_, err := db.Exec(q)
+		if err != nil {
+			panic(err)
+		}
+	}
+}
+
+func TestRelations(t *testing.T) {
+	ts, _, db := newTestServer(t, PrepareRelationTables)
+
+	rvasily := CR{"user_id": 1, "login": "rvasily"}
+	ivan := CR{"user_id": 2, "login": "ivan"}
+
+	cases := []Case{
+		Case{
+			Path:  "/items",
+			Query: "fields=title&embed=author,reviewer",
+			Result: CR{
+				"response": CR{
+					"records": []CR{
+						CR{"title": "database/sql", "author": rvasily, "reviewer": ivan},
+						CR{"title": "memcache", "author": rvasily, "reviewer": nil},
+						CR{"title": "grpc", "author": nil, "reviewer": rvasily},
+					},
+				},
+			},
+		},
+		Case{
+			Path:  "/items/2",
+			Query: "embed=author",
+			Result: CR{
+				"response": CR{
+					"record": CR{
+						"id":          2,
+						"title":       "memcache",
+						"author_id":   1,
+						"reviewer_id": nil,
+						"author":      rvasily,
+					},
+				},
+			},
+		},
+		Case{
+			Path:  "/users",
+			Query: "embed=items",
+			Result: CR{
+				"response": CR{
+					"records": []CR{
+						CR{
+							"user_id": 1,
+							"login":   "rvasily",
+							"items": []CR{
+								CR{"id": 1, "title": "database/sql", "author_id": 1, "reviewer_id": 2},
+								CR{"id": 2, "title": "memcache", "author_id": 1, "reviewer_id": nil},
+							},
+						},
+						CR{
+							"user_id": 2,
+							"login":   "ivan",
+							"items":   []CR{},
+						},
+					},
+				},
+			},
+		},
+		Case{
+			Path:  "/users/1/items",
+			Query: "fields=id,title&order=-id",
+			Result: CR{
+				"response": CR{
+					"records": []CR{
+						CR{"id": 2, "title": "memcache"},
+						CR{"id": 1, "title": "database/sql"},
+					},
+				},
+			},
+		},
+		Case{
+			Path:  "/users/1/items_reviewer",
+			Query: "fields=title&embed=author",
+			Result: CR{
+				"response": CR{
+					"records": []CR{
+						CR{"title": "grpc", "author": nil},
+					},
+				},
+			},
+		},
+		Case{
+			Path: "/users/2/items",
+			Result: CR{
+				"response": CR{
+					"records": nil,
+				},
+			},
+		},
+		// ошибки
+		Case{
+			Path:   "/users/42/items",
+			Status: http.StatusNotFound,
+			Result: CR{
+				"error": "record not found",
+			},
+		},
+		Case{
+			Path:   "/items/1/author",
+			Status: http.StatusNotFound,
+			Result: CR{
+				"error": "unknown relation",
+			},
+		},
+		Case{
+			Path:   "/items",
+			Query:  "embed=comments",
+			Status: http.StatusBadRequest,
+			Result: CR{
+				"error": "unknown relation comments",
+			},
+		},
+		Case{
+			Method: http.MethodDelete,
+			Path:   "/users/1/items",
+			Status: http.StatusNotFound,
+			Result: CR{
+				"error": "method not found",
+			},
+		},
+	}
+
+	runCases(t, ts, db, cases)
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Schema is a snapshot of the database structure. It's never modified
+// after loading: a reload builds a new one and swaps the pointer
+type Schema struct {
+	Tables      map[string]Table
+	Version     int
+	Fingerprint string
+	LoadedAt    time.Time
+
+	// graphql caches GraphQL schemas of the snapshot by api key
+	graphql sync.Map
+}
+
+type schemaState struct {
+	current atomic.Pointer[Schema]
+	// reloads are serialized, so versions grow one by one
+	mu sync.Mutex
+}
+
+// Schema returns the current schema snapshot
+func (e *DbExplorer) Schema() *Schema {
+	return e.schema.current.Load()
+}
+
+// ReloadSchema introspects the database again and swaps the schema if the
+// DDL has changed. On error the old schema stays in use
+func (e *DbExplorer) ReloadSchema() (schema *Schema, changed bool, err error) {
+	e.schema.mu.Lock()
+	defer e.schema.mu.Unlock()
+
+	current := e.schema.current.Load()
+	tables, err := e.GetTables()
+	if err != nil {
+		return current, false, err
+	}
+
+	fingerprint := schemaFingerprint(tables)
+	if current != nil && current.Fingerprint == fingerprint {
+		return current, false, nil
+	}
+
+	schema = &Schema{
+		Tables:      tables,
+		Version:     1,
+		Fingerprint: fingerprint,
+		LoadedAt:    time.Now(),
+	}
+	if current != nil {
+		schema.Version = current.Version + 1
+	}
+	e.schema.current.Store(schema)
+	return schema, true, nil
+}
+
+// WatchSchema reloads the schema every interval until ctx is done
+func (e *DbExplorer) WatchSchema(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			schema, changed, err := e.ReloadSchema()
+			if err != nil {
+				log.Printf("schema reload failed: %s", err)
+				continue
+			}
+			if changed {
+				log.Printf("schema changed, version %d", schema.Version)
+			}
+		}
+	}
+}
+
+// snapshot returns a copy of the explorer bound to the current schema,
+// so a request sees the same tables from start to end even if a reload
+// happens meanwhile
+func (e *DbExplorer) snapshot() *DbExplorer {
+	s := *e
+	s.current = e.Schema()
+	s.tables = s.current.Tables
+	return &s
+}
+
+// schemaFingerprint changes whenever a table, a column or a foreign key does
+func schemaFingerprint(tables map[string]Table) string {
+	h := sha256.New()
+	for _, name := range sortedTableNames(tables) {
+		table := tables[name]
+		fmt.Fprintf(h, "table %q\n", table.Name)
+		for _, c := range table.Columns {
+			fmt.Fprintf(h, "column %q %q %v %q %v %q\n", c.Field, c.DataType, c.Null, c.Key, c.Default, c.Extra)
+		}
+		for _, fk := range table.ForeignKeys {
+			fmt.Fprintf(h, "fk %q %q %q\n", fk.Column, fk.RefTable, fk.RefColumn)
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+type GetSchemaResponse struct {
+	Version  int       `json:"version"`
+	LoadedAt time.Time `json:"loaded_at"`
+	Tables   []string  `json:"tables"`
+}
+
+type ReloadSchemaResponse struct {
+	GetSchemaResponse
+	Changed bool `json:"changed"`
+}
+
+func newGetSchemaResponse(schema *Schema) GetSchemaResponse {
+	return GetSchemaResponse{
+		Version:  schema.Version,
+		LoadedAt: schema.LoadedAt,
+		Tables:   sortedTableNames(schema.Tables),
+	}
+}
+
+// handleSchema serves GET /_schema and POST /_schema/reload, the latter only
+// with access control on, admin keys are checked by handleRequest
+func (e *DbExplorer) handleSchema(r *http.Request) (interface{}, error) {
+	switch {
+	case r.Method == http.MethodGet && r.URL.Path == "/_schema":
+		return newGetSchemaResponse(e.Schema()), nil
+	case r.Method == http.MethodPost && r.URL.Path == "/_schema/reload":
+		// a reload rescans the whole database, without access control anyone could
+		// make the explorer do it in a loop, so there the schema is reloaded by interval only
+		if e.access == nil {
+			return nil, ResponseError{"schema reload requires an admin key", http.StatusForbidden}
+		}
+		schema, changed, err := e.ReloadSchema()
+		if err != nil {
+			return nil, ResponseError{fmt.Sprintf("schema reload failed: %s", err), http.StatusInternalServerError}
+		}
+		return &ReloadSchemaResponse{
+			GetSchemaResponse: newGetSchemaResponse(schema),
+			Changed:           changed,
+		}, nil
+	}
+	return nil, ResponseError{"method not found", http.StatusNotFound}
+}
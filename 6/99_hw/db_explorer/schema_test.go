@@ -0,0 +1,158 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSchemaReload(t *testing.T) {
+	ts, handler, db := newTestServer(t, PrepareTestApis)
+
+	post := func(key string) *http.Response {
+		req, err := http.NewRequest(http.MethodPost, ts.URL+"/_schema/reload", nil)
+		if err != nil {
+			panic(err)
+		}
+		if key != "" {
+			req.Header.Set("X-Api-Key", key)
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("reload request error: %v", err)
+		}
+		return resp
+	}
+
+	// без настроек доступа перезагрузить схему может кто угодно, поэтому нельзя
+	resp := post("")
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("reload without access control: expected %d, got %d", http.StatusForbidden, resp.StatusCode)
+	}
+
+	handler.SetAccessConfig(&AccessConfig{
+		Keys: map[string]KeyAccess{
+			"admin": {Tables: map[string]TableAccess{"*": {Permissions: []string{PermissionRead}}}, Admin: true},
+		},
+		Anonymous: &KeyAccess{Tables: map[string]TableAccess{"*": {Permissions: []string{PermissionRead}}}},
+	})
+	resp = post("")
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("reload by anonymous: expected %d, got %d", http.StatusForbidden, resp.StatusCode)
+	}
+
+	reload := func() ReloadSchemaResponse {
+		resp := post("admin")
+		defer resp.Body.Close()
+
+		var res struct {
+			Response ReloadSchemaResponse `json:"response"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&res); err != nil {
+			t.Fatalf("cant unpack json: %v", err)
+		}
+		return res.Response
+	}
+
+	if res := reload(); res.Changed || res.Version != 1 {
+		t.Fatalf("reload without DDL changes: expected version 1 unchanged, got %+v", res)
+	}
+
+	// запрос, начатый до перезагрузки, доживает на старой схеме
+	before := handler.snapshot()
+
+	_, err := db.Exec(`ALTER TABLE items ADD COLUMN extra varchar(255) DEFAULT NULL`)
+	if err != nil {
+		panic(err)
+	}
+	_, err = db.Exec(`CREATE TABLE tags (id integer PRIMARY KEY, name varchar(255) NOT NULL)`)
+	if err != nil {
+		panic(err)
+	}
+	defer db.Exec(`DROP TABLE IF EXISTS tags`)
+
+	res := reload()
+	if !res.Changed || res.Version != 2 {
+		t.Fatalf("reload after DDL changes: expected version 2 changed, got %+v", res)
+	}
+	if expected := []string{"items", "tags", "users"}; !reflect.DeepEqual(res.Tables, expected) {
+		t.Fatalf("expected tables %v, got %v", expected, res.Tables)
+	}
+
+	items := before.tables["items"]
+	if _, ok := items.Column("extra"); ok {
+		t.Fatalf("old snapshot must not see the new column")
+	}
+	if _, ok := before.tables["tags"]; ok {
+		t.Fatalf("old snapshot must not see the new table")
+	}
+
+	runCases(t, ts, db, []Case{
+		Case{
+			Path: "/items/1",
+			Result: CR{
+				"response": CR{
+					"record": CR{
+						"id":          1,
+						"title":       "database/sql",
+						"description": "Рассказать про базы данных",
+						"updated":     "rvasily",
+						"extra":       nil,
+					},
+				},
+			},
+		},
+		Case{
+			Path: "/tags",
+			Result: CR{
+				"response": CR{
+					"records": nil,
+				},
+			},
+		},
+		Case{
+			Path:   "/_schema/drop",
+			Header: http.Header{"X-Api-Key": {"admin"}},
+			Status: http.StatusNotFound,
+			Result: CR{
+				"error": "method not found",
+			},
+		},
+	})
+
+	if res := reload(); res.Changed || res.Version != 2 {
+		t.Fatalf("second reload: expected version 2 unchanged, got %+v", res)
+	}
+}
+
+func TestWatchSchema(t *testing.T) {
+	_, handler, db := newTestServer(t, PrepareTestApis)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		handler.WatchSchema(ctx, 10*time.Millisecond)
+		close(done)
+	}()
+
+	_, err := db.Exec(`ALTER TABLE users ADD COLUMN extra varchar(255) DEFAULT NULL`)
+	if err != nil {
+		panic(err)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for handler.Schema().Version != 2 {
+		if time.Now().After(deadline) {
+			t.Fatalf("schema was not reloaded in time")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	cancel()
+	<-done
+}
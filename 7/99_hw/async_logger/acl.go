@@ -0,0 +1,234 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path"
+	"strings"
+	"sync/atomic"
+)
+
+// ACL пускает consumer к методу, если метод подходит под его allow и не
+// подходит под deny. Правила consumer "*" действуют для всех.
+//
+// Шаблон сравнивается с методом по сегментам между "/": в сегменте работают
+// шаблоны path.Match, "**" заменяет любое число сегментов.
+//
+// Старый формат - consumer и список разрешенных методов:
+//
+//	{"biz_user": ["/main.Biz/Check", "/main.Biz/Add"]}
+//
+// Новый формат - роли и consumer'ы с allow, deny и roles, список вместо
+// объекта означает allow:
+//
+//	{
+//		"roles": {"biz": ["/main.Biz/*"]},
+//		"consumers": {
+//			"biz_user": {"roles": ["biz"], "deny": ["/main.Biz/Test"]},
+//			"*": {"deny": ["/main.Admin/UpdateACL"]}
+//		},
+//		"limits": [{"consumer": "biz_user", "method": "/main.Biz/*", "rate": 10}]
+//	}
+//
+// Ограничения частоты вызовов описаны у aclLimit.
+type ACL struct {
+	consumers map[string]*aclRules
+	limits    []limitRule
+}
+
+type aclRules struct {
+	allow, deny []aclPattern
+}
+
+// aclPattern is a method pattern split by "/"
+type aclPattern []string
+
+func (p aclPattern) match(segments []string) bool {
+	if len(p) == 0 {
+		return len(segments) == 0
+	}
+	if p[0] == "**" {
+		for i := 0; i <= len(segments); i++ {
+			if p[1:].match(segments[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(segments) == 0 {
+		return false
+	}
+	ok, _ := path.Match(p[0], segments[0])
+	return ok && p[1:].match(segments[1:])
+}
+
+func matchesPatterns(patterns []aclPattern, segments []string) bool {
+	for _, p := range patterns {
+		if p.match(segments) {
+			return true
+		}
+	}
+	return false
+}
+
+func (acl *ACL) IsAllowed(consumer, method string) bool {
+	splitted := strings.Split(method, "/")
+
+	var rules []*aclRules
+	if r, ok := acl.consumers[consumer]; ok {
+		rules = append(rules, r)
+	}
+	if r, ok := acl.consumers["*"]; ok && consumer != "*" {
+		rules = append(rules, r)
+	}
+
+	allowed := false
+	for _, r := range rules {
+		if matchesPatterns(r.deny, splitted) {
+			return false
+		}
+		allowed = allowed || matchesPatterns(r.allow, splitted)
+	}
+	return allowed
+}
+
+// aclRuleSet is a consumer or a role in the JSON, a plain list means allow
+type aclRuleSet struct {
+	Allow []string `json:"allow"`
+	Deny  []string `json:"deny"`
+	Roles []string `json:"roles"`
+}
+
+func (r *aclRuleSet) UnmarshalJSON(data []byte) error {
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte("[")) {
+		return json.Unmarshal(data, &r.Allow)
+	}
+	type plain aclRuleSet
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	return decoder.Decode((*plain)(r))
+}
+
+type aclConfig struct {
+	Roles     map[string]aclRuleSet `json:"roles"`
+	Consumers map[string]aclRuleSet `json:"consumers"`
+	Limits    []aclLimit            `json:"limits"`
+}
+
+func parsePatterns(patterns []string) ([]aclPattern, error) {
+	parsed := make([]aclPattern, len(patterns))
+	for i, pattern := range patterns {
+		if !strings.HasPrefix(pattern, "/") {
+			return nil, fmt.Errorf("pattern %q must start with /", pattern)
+		}
+		parsed[i] = strings.Split(pattern, "/")
+		for _, segment := range parsed[i] {
+			if strings.Contains(segment, "**") && segment != "**" {
+				return nil, fmt.Errorf("pattern %q: ** must be a whole segment", pattern)
+			}
+			if _, err := path.Match(segment, ""); err != nil {
+				return nil, fmt.Errorf("pattern %q: %v", pattern, err)
+			}
+		}
+	}
+	return parsed, nil
+}
+
+func (r aclRuleSet) rules() (*aclRules, error) {
+	allow, err := parsePatterns(r.Allow)
+	if err != nil {
+		return nil, err
+	}
+	deny, err := parsePatterns(r.Deny)
+	if err != nil {
+		return nil, err
+	}
+	return &aclRules{allow: allow, deny: deny}, nil
+}
+
+func NewACL(data string) (*ACL, error) {
+	// map{key:[]values} в старом формате, там все значения - списки
+	top := make(map[string]json.RawMessage)
+	if err := json.Unmarshal([]byte(data), &top); err != nil {
+		return nil, err
+	}
+	config := aclConfig{Consumers: make(map[string]aclRuleSet)}
+	legacy := true
+	for _, value := range top {
+		legacy = legacy && bytes.HasPrefix(bytes.TrimSpace(value), []byte("["))
+	}
+	if legacy {
+		for consumer, value := range top {
+			var rules aclRuleSet
+			if err := json.Unmarshal(value, &rules); err != nil {
+				return nil, err
+			}
+			config.Consumers[consumer] = rules
+		}
+	} else {
+		decoder := json.NewDecoder(strings.NewReader(data))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&config); err != nil {
+			return nil, err
+		}
+	}
+
+	roles := make(map[string]*aclRules, len(config.Roles))
+	for name, role := range config.Roles {
+		if len(role.Roles) > 0 {
+			return nil, fmt.Errorf("role %s: roles can't include roles", name)
+		}
+		rules, err := role.rules()
+		if err != nil {
+			return nil, fmt.Errorf("role %s: %v", name, err)
+		}
+		roles[name] = rules
+	}
+
+	auth := &ACL{
+		consumers: make(map[string]*aclRules, len(config.Consumers)),
+	}
+	for consumer, set := range config.Consumers {
+		rules, err := set.rules()
+		if err != nil {
+			return nil, fmt.Errorf("consumer %s: %v", consumer, err)
+		}
+		for _, name := range set.Roles {
+			role, ok := roles[name]
+			if !ok {
+				return nil, fmt.Errorf("consumer %s: unknown role %s", consumer, name)
+			}
+			rules.allow = append(rules.allow, role.allow...)
+			rules.deny = append(rules.deny, role.deny...)
+		}
+		auth.consumers[consumer] = rules
+	}
+
+	limits, err := parseLimits(config.Limits)
+	if err != nil {
+		return nil, err
+	}
+	auth.limits = limits
+	return auth, nil
+}
+
+// ACLStore holds the ACL which is replaced while the server works. A call
+// is checked once when it starts, so open streams aren't affected by a swap
+type ACLStore struct {
+	current atomic.Value
+}
+
+func NewACLStore(acl *ACL) *ACLStore {
+	s := &ACLStore{}
+	s.current.Store(acl)
+	return s
+}
+
+func (s *ACLStore) Load() *ACL {
+	return s.current.Load().(*ACL)
+}
+
+func (s *ACLStore) Store(acl *ACL) {
+	s.current.Store(acl)
+}
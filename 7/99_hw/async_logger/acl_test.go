@@ -0,0 +1,141 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestACLRules(t *testing.T) {
+	acl, err := NewACL(`{
+		"roles": {
+			"biz": ["/main.Biz/*"],
+			"admin": {"allow": ["/main.Admin/**"], "deny": ["/main.Admin/UpdateACL"]}
+		},
+		"consumers": {
+			"biz_user": {"roles": ["biz"], "deny": ["/main.Biz/Test"]},
+			"ops": {"roles": ["biz", "admin"]},
+			"root": ["/**"],
+			"*": {"allow": ["/main.Biz/Check"], "deny": ["/main.Admin/Statistics"]}
+		}
+	}`)
+	if err != nil {
+		t.Fatalf("cant parse acl: %v", err)
+	}
+
+	for _, item := range []struct {
+		consumer, method string
+		allowed          bool
+	}{
+		{"biz_user", "/main.Biz/Check", true},
+		{"biz_user", "/main.Biz/Add", true},
+		{"biz_user", "/main.Biz/Test", false},
+		{"ops", "/main.Biz/Test", true},
+		{"ops", "/main.Admin/Logging", true},
+		{"ops", "/main.Admin/UpdateACL", false},
+		{"root", "/main.Admin/UpdateACL", true},
+		// deny для всех сильнее allow конкретного consumer
+		{"root", "/main.Admin/Statistics", false},
+		{"unknown", "/main.Biz/Check", true},
+		{"unknown", "/main.Biz/Add", false},
+		{"", "/main.Biz/Check", true},
+	} {
+		if allowed := acl.IsAllowed(item.consumer, item.method); allowed != item.allowed {
+			t.Errorf("%s %s: expected allowed=%v", item.consumer, item.method, item.allowed)
+		}
+	}
+
+	for _, data := range []string{
+		`{"consumers": {"x": {"roles": ["nothing"]}}}`,
+		`{"consumers": {"x": {"allow": ["main.Biz/Check"]}}}`,
+		`{"consumers": {"x": {"allow": ["/main.Biz/a**"]}}}`,
+		`{"consumers": {"x": {"allow": ["/main.Biz/["]}}}`,
+		`{"consumers": {"x": {"alow": ["/main.Biz/Check"]}}}`,
+		`{"roles": {"a": {"roles": ["b"]}}}`,
+	} {
+		if _, err := NewACL(data); err == nil {
+			t.Errorf("expected error on acl %s", data)
+		}
+	}
+}
+
+// ACL меняется на лету, открытые потоки при этом не рвутся
+func TestUpdateACL(t *testing.T) {
+	ctx, finish := context.WithCancel(context.Background())
+	srv, err := StartMyMicroservice(ctx, listenAddr, `{
+	"logger1":   ["/main.Admin/Logging"],
+	"acl_admin": ["/main.Admin/UpdateACL"],
+	"biz_user":  ["/main.Biz/Check"]
+}`)
+	if err != nil {
+		t.Fatalf("cant start server initial: %v", err)
+	}
+	wait(1)
+	defer func() {
+		finish()
+		srv.Wait()
+	}()
+
+	conn := getGrpcConn(t)
+	defer conn.Close()
+
+	biz := NewBizClient(conn)
+	adm := NewAdminClient(conn)
+
+	streamCtx, cancel := getConsumerCtxWithCancel("logger1")
+	defer cancel()
+	logStream, err := adm.Logging(streamCtx, &LoggingRequest{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	wait(1)
+
+	if _, err := biz.Add(getConsumerCtx("biz_user"), &Nothing{}); status.Code(err) != codes.Unauthenticated {
+		t.Fatalf("expected Unauthenticated before update, got %v", err)
+	}
+
+	_, err = adm.UpdateACL(getConsumerCtx("biz_user"), &ACLUpdate{Acl: `{"biz_user": ["/**"]}`})
+	if status.Code(err) != codes.Unauthenticated {
+		t.Fatalf("expected Unauthenticated on update by biz_user, got %v", err)
+	}
+	_, err = adm.UpdateACL(getConsumerCtx("acl_admin"), &ACLUpdate{Acl: `{.;`})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("expected InvalidArgument on bad acl, got %v", err)
+	}
+
+	_, err = adm.UpdateACL(getConsumerCtx("acl_admin"), &ACLUpdate{Acl: `{
+	"roles": {"biz": ["/main.Biz/*"]},
+	"consumers": {"biz_user": {"roles": ["biz"], "deny": ["/main.Biz/Check"]}}
+}`})
+	if err != nil {
+		t.Fatalf("unexpected error on update: %v", err)
+	}
+
+	if _, err := biz.Add(getConsumerCtx("biz_user"), &Nothing{}); err != nil {
+		t.Fatalf("expected Add to be allowed after update, got %v", err)
+	}
+	if _, err := biz.Check(getConsumerCtx("biz_user"), &Nothing{}); status.Code(err) != codes.Unauthenticated {
+		t.Fatalf("expected Check to be denied after update, got %v", err)
+	}
+
+	// logger1 больше нет в ACL, но его поток открыт раньше
+	expected := []string{
+		"/main.Biz/Add",
+		"/main.Admin/UpdateACL",
+		"/main.Admin/UpdateACL",
+		"/main.Admin/UpdateACL",
+		"/main.Biz/Add",
+		"/main.Biz/Check",
+	}
+	for _, method := range expected {
+		evt, err := logStream.Recv()
+		if err != nil {
+			t.Fatalf("unexpected error: %v, awaiting event", err)
+		}
+		if evt.Method != method {
+			t.Fatalf("expected %s event, got %s", method, evt.Method)
+		}
+	}
+}
@@ -0,0 +1,248 @@
+package main
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+// statEntry is a notice with the time it was published
+type statEntry struct {
+	at     time.Time
+	notice *Notice
+}
+
+// StatWindow is a Statistics stream registered in the aggregator. Every Slide
+// it gets the stat of the last Interval, which doesn't reach before the
+// stream was opened. Slide equal to Interval is a tumbling window
+type StatWindow struct {
+	Stats chan *Stat
+
+	interval, slide time.Duration
+	filter          *EventFilter
+	groupBy         []Dimension
+	percentiles     []float64
+	topN            int
+
+	since time.Time
+	next  time.Time
+}
+
+// StatAggregator keeps the calls of the longest registered window and
+// computes windows of all Statistics streams in one goroutine, so the calls
+// are stored once whatever the number of streams
+type StatAggregator struct {
+	mu      sync.Mutex
+	entries []statEntry
+	windows map[int]*StatWindow
+	lastID  int
+	stopped bool
+	// wake tells Run that a window is added and the next tick may be earlier
+	wake chan struct{}
+}
+
+func NewStatAggregator() *StatAggregator {
+	return &StatAggregator{
+		windows: make(map[int]*StatWindow),
+		wake:    make(chan struct{}, 1),
+	}
+}
+
+// Add stores the notice if any window may need it
+func (a *StatAggregator) Add(notice *Notice) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if len(a.windows) == 0 {
+		return
+	}
+	a.entries = append(a.entries, statEntry{at: time.Now(), notice: notice})
+}
+
+// Register opens a window for the request, its stats come to Stats. Calls
+// published before Register aren't counted
+func (a *StatAggregator) Register(req *StatInterval) (int, *StatWindow) {
+	interval := time.Duration(req.IntervalSeconds) * time.Second
+	slide := time.Duration(req.SlideSeconds) * time.Second
+	if slide == 0 {
+		slide = interval
+	}
+	now := time.Now()
+	window := &StatWindow{
+		// the stream reads only the last stat, older ones are replaced
+		Stats:       make(chan *Stat, 1),
+		interval:    interval,
+		slide:       slide,
+		filter:      req.GetFilter(),
+		groupBy:     req.GroupBy,
+		percentiles: req.Percentiles,
+		topN:        int(req.TopN),
+		since:       now,
+		next:        now.Add(slide),
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.lastID++
+	if a.stopped {
+		close(window.Stats)
+		return a.lastID, window
+	}
+	a.windows[a.lastID] = window
+	select {
+	case a.wake <- struct{}{}:
+	default:
+	}
+	return a.lastID, window
+}
+
+func (a *StatAggregator) Unregister(id int) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if window, ok := a.windows[id]; ok {
+		close(window.Stats)
+		delete(a.windows, id)
+	}
+}
+
+// Run sends stats of the windows when they are due till ctx is done, then
+// closes all windows
+func (a *StatAggregator) Run(ctx context.Context) {
+	timer := time.NewTimer(time.Hour)
+	defer timer.Stop()
+
+	for {
+		next, ok := a.tick(time.Now())
+		if !timer.Stop() {
+			select {
+			case <-timer.C:
+			default:
+			}
+		}
+		if ok {
+			timer.Reset(time.Until(next))
+		} else {
+			timer.Reset(time.Hour)
+		}
+
+		select {
+		case <-timer.C:
+		case <-a.wake:
+		case <-ctx.Done():
+			a.stop()
+			return
+		}
+	}
+}
+
+func (a *StatAggregator) stop() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.stopped = true
+	for id, window := range a.windows {
+		close(window.Stats)
+		delete(a.windows, id)
+	}
+	a.entries = nil
+}
+
+// tick sends the due windows and drops the calls no window needs anymore,
+// it returns when the next window is due
+func (a *StatAggregator) tick(now time.Time) (time.Time, bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	var next time.Time
+	var longest time.Duration
+	for _, window := range a.windows {
+		for !window.next.After(now) {
+			send(window.Stats, a.collect(window, window.next))
+			window.next = window.next.Add(window.slide)
+		}
+		if next.IsZero() || window.next.Before(next) {
+			next = window.next
+		}
+		if window.interval > longest {
+			longest = window.interval
+		}
+	}
+
+	// a window ending at its next tick needs calls since next-interval
+	keep := sort.Search(len(a.entries), func(i int) bool {
+		return !a.entries[i].at.Before(now.Add(-longest))
+	})
+	a.entries = append(a.entries[:0:0], a.entries[keep:]...)
+
+	return next, !next.IsZero()
+}
+
+// send replaces the stat the stream hasn't read yet
+func send(stats chan *Stat, stat *Stat) {
+	for {
+		select {
+		case stats <- stat:
+			return
+		default:
+		}
+		select {
+		case <-stats:
+		default:
+		}
+	}
+}
+
+// collect counts the calls of the window which ends at end
+func (a *StatAggregator) collect(window *StatWindow, end time.Time) *Stat {
+	start := end.Add(-window.interval)
+	if start.Before(window.since) {
+		start = window.since
+	}
+
+	collector := NewCollector(window.groupBy, window.percentiles)
+	from := sort.Search(len(a.entries), func(i int) bool {
+		return !a.entries[i].at.Before(start)
+	})
+	for _, entry := range a.entries[from:] {
+		if !entry.at.Before(end) {
+			break
+		}
+		if !window.filter.Matches(entry.notice.Event) {
+			continue
+		}
+		if entry.notice.Finished {
+			collector.Observe(entry.notice.Event, entry.notice.Duration)
+		} else {
+			collector.Update(entry.notice.Event)
+		}
+	}
+
+	stat := collector.Collect()
+	stat.Timestamp = end.Unix()
+	if window.topN > 0 {
+		stat.TopMethods = topCounts(stat.ByMethod, window.topN)
+		stat.TopConsumers = topCounts(stat.ByConsumer, window.topN)
+	}
+	return stat
+}
+
+// topCounts takes n keys with the biggest counts, equal counts go by key
+func topCounts(counts map[string]uint64, n int) []*StatCount {
+	top := make([]*StatCount, 0, len(counts))
+	for key, count := range counts {
+		top = append(top, &StatCount{Key: key, Count: count})
+	}
+	sort.Slice(top, func(i, j int) bool {
+		if top[i].Count != top[j].Count {
+			return top[i].Count > top[j].Count
+		}
+		return top[i].Key < top[j].Key
+	})
+	if len(top) > n {
+		top = top[:n]
+	}
+	return top
+}
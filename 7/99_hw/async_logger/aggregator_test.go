@@ -0,0 +1,149 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestStatAggregator(t *testing.T) {
+	agg := NewStatAggregator()
+	tumblingID, tumbling := agg.Register(&StatInterval{IntervalSeconds: 2, TopN: 1})
+	_, sliding := agg.Register(&StatInterval{
+		IntervalSeconds: 2,
+		SlideSeconds:    1,
+		Filter:          &EventFilter{Methods: []string{"/main.Biz/*"}},
+	})
+
+	// окна открыты в момент start, время подставляем руками
+	start := time.Now().Add(-time.Hour)
+	for _, window := range []*StatWindow{tumbling, sliding} {
+		window.since = start
+		window.next = start.Add(window.slide)
+	}
+	at := func(ms int, consumer, method string) statEntry {
+		return statEntry{
+			at:     start.Add(time.Duration(ms) * time.Millisecond),
+			notice: &Notice{Event: &Event{Consumer: consumer, Method: method}},
+		}
+	}
+	agg.entries = []statEntry{
+		at(-100, "stat", "/main.Admin/Statistics"),
+		at(100, "biz_user", "/main.Biz/Check"),
+		at(600, "biz_admin", "/main.Admin/Logging"),
+		at(1500, "biz_user", "/main.Biz/Add"),
+		at(2500, "biz_admin", "/main.Biz/Add"),
+	}
+
+	recv := func(window *StatWindow) *Stat {
+		select {
+		case stat := <-window.Stats:
+			return stat
+		default:
+			t.Fatalf("expected a stat")
+			return nil
+		}
+	}
+	check := func(name string, stat *Stat, byMethod map[string]uint64) {
+		if !reflect.DeepEqual(stat.ByMethod, byMethod) {
+			t.Fatalf("%s: expected %v, got %v", name, byMethod, stat.ByMethod)
+		}
+	}
+
+	if next, ok := agg.tick(start.Add(1100 * time.Millisecond)); !ok || !next.Equal(start.Add(2*time.Second)) {
+		t.Fatalf("unexpected next tick %v", next)
+	}
+	check("sliding 0-1", recv(sliding), map[string]uint64{"/main.Biz/Check": 1})
+
+	agg.tick(start.Add(2100 * time.Millisecond))
+	check("sliding 0-2", recv(sliding), map[string]uint64{"/main.Biz/Check": 1, "/main.Biz/Add": 1})
+	stat := recv(tumbling)
+	check("tumbling 0-2", stat, map[string]uint64{"/main.Biz/Check": 1, "/main.Admin/Logging": 1, "/main.Biz/Add": 1})
+	if len(stat.TopConsumers) != 1 || stat.TopConsumers[0].Key != "biz_user" || stat.TopConsumers[0].Count != 2 {
+		t.Fatalf("unexpected top consumers %v", stat.TopConsumers)
+	}
+
+	// не прочитанная статистика заменяется новой
+	agg.tick(start.Add(4100 * time.Millisecond))
+	check("sliding 2-4", recv(sliding), map[string]uint64{"/main.Biz/Add": 1})
+	check("tumbling 2-4", recv(tumbling), map[string]uint64{"/main.Biz/Add": 1})
+
+	// все вызовы старше самого длинного окна забыты
+	if len(agg.entries) != 1 {
+		t.Fatalf("expected only the last call to be kept, got %d", len(agg.entries))
+	}
+
+	agg.Unregister(tumblingID)
+	if _, ok := <-tumbling.Stats; ok {
+		t.Fatalf("expected unregistered window to be closed")
+	}
+}
+
+func TestStatisticsWindows(t *testing.T) {
+	ctx, finish := context.WithCancel(context.Background())
+	srv, err := StartMyMicroservice(ctx, listenAddr, ACLData)
+	if err != nil {
+		t.Fatalf("cant start server initial: %v", err)
+	}
+	wait(1)
+	defer func() {
+		finish()
+		srv.Wait()
+	}()
+
+	conn := getGrpcConn(t)
+	defer conn.Close()
+
+	biz := NewBizClient(conn)
+	adm := NewAdminClient(conn)
+
+	for _, req := range []*StatInterval{
+		{},
+		{IntervalSeconds: 1, SlideSeconds: 2},
+	} {
+		stream, err := adm.Statistics(getConsumerCtx("stat1"), req)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if _, err := stream.Recv(); status.Code(err) != codes.InvalidArgument {
+			t.Fatalf("expected InvalidArgument on %v, got %v", req, err)
+		}
+	}
+
+	statCtx, cancel := getConsumerCtxWithCancel("stat1")
+	defer cancel()
+	stream, err := adm.Statistics(statCtx, &StatInterval{IntervalSeconds: 2, SlideSeconds: 1, TopN: 1})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	wait(1)
+
+	biz.Check(getConsumerCtx("biz_user"), &Nothing{})
+	biz.Add(getConsumerCtx("biz_user"), &Nothing{})
+	biz.Test(getConsumerCtx("biz_admin"), &Nothing{})
+
+	// вызовы попадают в два окна подряд
+	for i := 0; i < 2; i++ {
+		stat, err := stream.Recv()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if stat.ByConsumer["biz_user"] != 2 || stat.ByConsumer["biz_admin"] != 1 {
+			t.Fatalf("[%d] unexpected stat %v", i, stat.ByConsumer)
+		}
+		if len(stat.TopConsumers) != 1 || stat.TopConsumers[0].Key != "biz_user" {
+			t.Fatalf("[%d] unexpected top consumers %v", i, stat.TopConsumers)
+		}
+	}
+	stat, err := stream.Recv()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(stat.ByConsumer) != 0 {
+		t.Fatalf("expected the calls to leave the window, got %v", stat.ByConsumer)
+	}
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+)
+
+// Authenticator finds out which consumer makes the call. The consumer goes
+// to ACL checks and to Event.Consumer. found is false if the call has no
+// credentials of this kind, then the next authenticator is tried
+type Authenticator interface {
+	Authenticate(ctx context.Context) (consumer string, found bool, err error)
+}
+
+// authenticate asks authenticators in order, the first one which finds its
+// credentials decides. A call without any credentials has an empty consumer
+func authenticate(ctx context.Context, authenticators []Authenticator) (string, error) {
+	for _, a := range authenticators {
+		consumer, found, err := a.Authenticate(ctx)
+		if found {
+			return consumer, err
+		}
+	}
+	return "", nil
+}
+
+func metadataValue(ctx context.Context, key string) string {
+	md, _ := metadata.FromIncomingContext(ctx)
+	return strings.Join(md.Get(key), "")
+}
+
+// MetadataAuthenticator trusts the consumer the client puts into metadata,
+// it's only for tests and trusted networks
+type MetadataAuthenticator struct{}
+
+func (MetadataAuthenticator) Authenticate(ctx context.Context) (string, bool, error) {
+	consumer := metadataValue(ctx, "consumer")
+	if consumer == "" {
+		return "", false, nil
+	}
+	return consumer, true, nil
+}
+
+// APIKeyAuthenticator maps the x-api-key metadata to consumers
+type APIKeyAuthenticator struct {
+	Keys map[string]string
+}
+
+func (a APIKeyAuthenticator) Authenticate(ctx context.Context) (string, bool, error) {
+	key := metadataValue(ctx, "x-api-key")
+	if key == "" {
+		return "", false, nil
+	}
+	for known, consumer := range a.Keys {
+		if subtle.ConstantTimeCompare([]byte(key), []byte(known)) == 1 {
+			return consumer, true, nil
+		}
+	}
+	return "", true, errors.New("unknown api key")
+}
+
+// HMACAuthenticator checks tokens of NewHMACToken sent as
+// "authorization: Bearer <token>"
+type HMACAuthenticator struct {
+	Secret []byte
+}
+
+// NewHMACToken signs the consumer name till expires:
+// base64(consumer).expires.base64(hmac-sha256)
+func NewHMACToken(secret []byte, consumer string, expires time.Time) string {
+	payload := base64.RawURLEncoding.EncodeToString([]byte(consumer)) + "." + strconv.FormatInt(expires.Unix(), 10)
+	return payload + "." + hmacSign(secret, payload)
+}
+
+func hmacSign(secret []byte, payload string) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func (a HMACAuthenticator) Authenticate(ctx context.Context) (string, bool, error) {
+	header := metadataValue(ctx, "authorization")
+	if !strings.HasPrefix(header, "Bearer ") {
+		return "", false, nil
+	}
+
+	parts := strings.Split(strings.TrimPrefix(header, "Bearer "), ".")
+	if len(parts) != 3 {
+		return "", true, errors.New("malformed token")
+	}
+	payload := parts[0] + "." + parts[1]
+	if !hmac.Equal([]byte(parts[2]), []byte(hmacSign(a.Secret, payload))) {
+		return "", true, errors.New("bad token signature")
+	}
+
+	expires, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return "", true, errors.New("malformed token")
+	}
+	if time.Now().Unix() > expires {
+		return "", true, errors.New("token expired")
+	}
+
+	consumer, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return "", true, errors.New("malformed token")
+	}
+	return string(consumer), true, nil
+}
+
+// CertAuthenticator takes the consumer from the common name of a verified
+// client certificate, the server has to be started with Config.TLS
+// requiring client certificates
+type CertAuthenticator struct {
+	// Consumers maps common names to consumers, nil means the common name is the consumer
+	Consumers map[string]string
+}
+
+func (a CertAuthenticator) Authenticate(ctx context.Context) (string, bool, error) {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return "", false, nil
+	}
+	info, ok := p.AuthInfo.(credentials.TLSInfo)
+	if !ok || len(info.State.VerifiedChains) == 0 || len(info.State.VerifiedChains[0]) == 0 {
+		return "", false, nil
+	}
+
+	cn := info.State.VerifiedChains[0][0].Subject.CommonName
+	if a.Consumers == nil {
+		return cn, true, nil
+	}
+	consumer, ok := a.Consumers[cn]
+	if !ok {
+		return "", true, errors.New("unknown certificate " + cn)
+	}
+	return consumer, true, nil
+}
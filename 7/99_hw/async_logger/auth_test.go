@@ -0,0 +1,193 @@
+package main
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const authACLData = `{
+	"alice":  ["/main.Biz/*"],
+	"bob":    ["/main.Biz/Check"],
+	"logger": ["/main.Admin/Logging"]
+}`
+
+func TestTokenAuth(t *testing.T) {
+	secret := []byte("secret")
+	ctx, finish := context.WithCancel(context.Background())
+	srv, err := StartMicroservice(ctx, Config{
+		Addr: listenAddr,
+		ACL:  authACLData,
+		Authenticators: []Authenticator{
+			HMACAuthenticator{Secret: secret},
+			APIKeyAuthenticator{Keys: map[string]string{"bob-key": "bob", "logger-key": "logger"}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("cant start server initial: %v", err)
+	}
+	wait(1)
+	defer func() {
+		finish()
+		srv.Wait()
+	}()
+
+	conn := getGrpcConn(t)
+	defer conn.Close()
+
+	biz := NewBizClient(conn)
+	adm := NewAdminClient(conn)
+
+	withMD := func(kv ...string) context.Context {
+		return metadata.NewOutgoingContext(context.Background(), metadata.Pairs(kv...))
+	}
+
+	streamCtx, cancel := context.WithCancel(withMD("x-api-key", "logger-key"))
+	defer cancel()
+	logStream, err := adm.Logging(streamCtx, &LoggingRequest{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	wait(1)
+
+	token := NewHMACToken(secret, "alice", time.Now().Add(time.Minute))
+	expired := NewHMACToken(secret, "alice", time.Now().Add(-time.Minute))
+	forged := NewHMACToken([]byte("other"), "alice", time.Now().Add(time.Minute))
+
+	for idx, item := range []struct {
+		ctx  context.Context
+		code codes.Code
+	}{
+		{withMD("authorization", "Bearer "+token), codes.OK},
+		{withMD("x-api-key", "bob-key"), codes.OK},
+		// метаданным больше не верим
+		{withMD("consumer", "alice"), codes.Unauthenticated},
+		{withMD("authorization", "Bearer "+expired), codes.Unauthenticated},
+		{withMD("authorization", "Bearer "+forged), codes.Unauthenticated},
+		{withMD("authorization", "Bearer nonsense"), codes.Unauthenticated},
+		{withMD("x-api-key", "unknown"), codes.Unauthenticated},
+	} {
+		if _, err := biz.Check(item.ctx, &Nothing{}); status.Code(err) != item.code {
+			t.Fatalf("[%d] expected %v, got %v", idx, item.code, err)
+		}
+	}
+
+	for _, consumer := range []string{"alice", "bob", "", "", "", "", ""} {
+		evt, err := logStream.Recv()
+		if err != nil {
+			t.Fatalf("unexpected error: %v, awaiting event", err)
+		}
+		if evt.Consumer != consumer {
+			t.Fatalf("expected event of %q, got %q", consumer, evt.Consumer)
+		}
+	}
+}
+
+// testCert выпускает сертификат, подписанный parent, или самоподписанный, если parent nil
+func testCert(t *testing.T, cn string, parent *tls.Certificate, template *x509.Certificate) tls.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("cant generate key: %v", err)
+	}
+	template.SerialNumber = big.NewInt(time.Now().UnixNano())
+	template.Subject = pkix.Name{CommonName: cn}
+	template.NotBefore = time.Now().Add(-time.Hour)
+	template.NotAfter = time.Now().Add(time.Hour)
+
+	signer, signerKey := template, interface{}(key)
+	if parent != nil {
+		signer, signerKey = parent.Leaf, parent.PrivateKey
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, signer, &key.PublicKey, signerKey)
+	if err != nil {
+		t.Fatalf("cant create certificate: %v", err)
+	}
+	leaf, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("cant parse certificate: %v", err)
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: leaf}
+}
+
+func TestCertAuth(t *testing.T) {
+	ca := testCert(t, "test ca", nil, &x509.Certificate{
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	})
+	serverCert := testCert(t, "server", &ca, &x509.Certificate{
+		IPAddresses: []net.IP{net.ParseIP("127.0.0.1")},
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	})
+	clientCert := func(cn string) tls.Certificate {
+		return testCert(t, cn, &ca, &x509.Certificate{
+			ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		})
+	}
+	pool := x509.NewCertPool()
+	pool.AddCert(ca.Leaf)
+
+	ctx, finish := context.WithCancel(context.Background())
+	srv, err := StartMicroservice(ctx, Config{
+		Addr: listenAddr,
+		ACL:  authACLData,
+		Authenticators: []Authenticator{
+			CertAuthenticator{Consumers: map[string]string{"client-bob": "bob"}},
+		},
+		TLS: &tls.Config{
+			Certificates: []tls.Certificate{serverCert},
+			ClientCAs:    pool,
+			ClientAuth:   tls.RequireAndVerifyClientCert,
+		},
+	})
+	if err != nil {
+		t.Fatalf("cant start server initial: %v", err)
+	}
+	wait(1)
+	defer func() {
+		finish()
+		srv.Wait()
+	}()
+
+	dial := func(cn string) *grpc.ClientConn {
+		conn, err := grpc.Dial(listenAddr, grpc.WithTransportCredentials(credentials.NewTLS(&tls.Config{
+			Certificates: []tls.Certificate{clientCert(cn)},
+			RootCAs:      pool,
+		})))
+		if err != nil {
+			t.Fatalf("cant connect to grpc: %v", err)
+		}
+		return conn
+	}
+
+	bobConn := dial("client-bob")
+	defer bobConn.Close()
+	bob := NewBizClient(bobConn)
+	if _, err := bob.Check(context.Background(), &Nothing{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// consumer из метаданных не важен
+	if _, err := bob.Add(getConsumerCtx("alice"), &Nothing{}); status.Code(err) != codes.Unauthenticated {
+		t.Fatalf("expected Unauthenticated, got %v", err)
+	}
+
+	eveConn := dial("client-eve")
+	defer eveConn.Close()
+	if _, err := NewBizClient(eveConn).Check(context.Background(), &Nothing{}); status.Code(err) != codes.Unauthenticated {
+		t.Fatalf("expected Unauthenticated for an unknown certificate, got %v", err)
+	}
+}
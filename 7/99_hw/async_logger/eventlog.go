@@ -0,0 +1,171 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"sync"
+)
+
+// EventLog keeps the last events in a ring buffer, so a subscriber can replay
+// what happened before it connected. With a file every event is appended to
+// it as a JSON line, the log is read back on start and survives restarts
+type EventLog struct {
+	mu sync.Mutex
+	// ring holds events in order starting from next once it's full
+	ring []*Event
+	next int
+	full bool
+	seq  uint64
+
+	path string
+	file *os.File
+}
+
+// NewEventLog keeps size last events in memory, path may be empty
+func NewEventLog(size int, path string) (*EventLog, error) {
+	if size <= 0 {
+		size = 1
+	}
+	l := &EventLog{
+		ring: make([]*Event, size),
+		path: path,
+	}
+	if path == "" {
+		return l, nil
+	}
+
+	err := l.readFile(func(event *Event) bool {
+		l.push(event)
+		l.seq = event.Seq
+		return true
+	})
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	l.file, err = os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+func (l *EventLog) push(event *Event) {
+	l.ring[l.next] = event
+	l.next++
+	if l.next == len(l.ring) {
+		l.next = 0
+		l.full = true
+	}
+}
+
+// Append numbers the event and stores it. The event is kept in memory even
+// if it can't be written to the file
+func (l *EventLog) Append(event *Event) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.seq++
+	event.Seq = l.seq
+	l.push(event)
+
+	if l.file == nil {
+		return nil
+	}
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	_, err = l.file.Write(append(data, '\n'))
+	return err
+}
+
+// Seq is the number of the last event
+func (l *EventLog) Seq() uint64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.seq
+}
+
+// Replay returns events the request asks for with numbers up to until.
+// Events which are out of the ring are read from the file if there is one
+func (l *EventLog) Replay(req *LoggingRequest, until uint64) ([]*Event, error) {
+	if req.GetFromSeq() == 0 && req.GetFromTimestamp() == 0 {
+		return nil, nil
+	}
+	inRange := func(event *Event) bool {
+		if req.GetFromSeq() > 0 {
+			return event.Seq >= req.GetFromSeq()
+		}
+		return event.Timestamp >= req.GetFromTimestamp()
+	}
+	match := func(event *Event) bool {
+		return inRange(event) && req.GetFilter().Matches(event)
+	}
+
+	l.mu.Lock()
+	var ring []*Event
+	if l.full {
+		ring = append(ring, l.ring[l.next:]...)
+	}
+	ring = append(ring, l.ring[:l.next]...)
+	l.mu.Unlock()
+
+	var events []*Event
+	// older events are only in the file
+	if l.path != "" && len(ring) > 0 && ring[0].Seq > 1 && inRange(ring[0]) {
+		oldest := ring[0].Seq
+		err := l.readFile(func(event *Event) bool {
+			if event.Seq >= oldest {
+				return false
+			}
+			if match(event) {
+				events = append(events, event)
+			}
+			return true
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for _, event := range ring {
+		if event.Seq > until {
+			break
+		}
+		if match(event) {
+			events = append(events, event)
+		}
+	}
+	return events, nil
+}
+
+// readFile calls fn for every event in the file until it returns false.
+// A broken line, like the last one after a crash, is skipped
+func (l *EventLog) readFile(fn func(*Event) bool) error {
+	file, err := os.Open(l.path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		event := &Event{}
+		if err := json.Unmarshal(scanner.Bytes(), event); err != nil {
+			continue
+		}
+		if !fn(event) {
+			return nil
+		}
+	}
+	return scanner.Err()
+}
+
+func (l *EventLog) Close() error {
+	if l.file == nil {
+		return nil
+	}
+	return l.file.Close()
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// подписчик, подключившийся позже, получает прошлые события, в том числе после перезапуска
+func TestLoggingReplay(t *testing.T) {
+	cfg := Config{
+		Addr: listenAddr,
+		ACL:  ACLData,
+		// в памяти только 2 события, остальные читаются из файла
+		EventLogSize: 2,
+		EventLogFile: filepath.Join(t.TempDir(), "events.log"),
+	}
+
+	ctx, finish := context.WithCancel(context.Background())
+	srv, err := StartMicroservice(ctx, cfg)
+	if err != nil {
+		t.Fatalf("cant start server initial: %v", err)
+	}
+	wait(1)
+
+	conn := getGrpcConn(t)
+	biz := NewBizClient(conn)
+	biz.Check(getConsumerCtx("biz_user"), &Nothing{})
+	biz.Add(getConsumerCtx("biz_user"), &Nothing{})
+	biz.Test(getConsumerCtx("biz_admin"), &Nothing{})
+	conn.Close()
+	finish()
+	srv.Wait()
+
+	ctx, finish = context.WithCancel(context.Background())
+	srv, err = StartMicroservice(ctx, cfg)
+	if err != nil {
+		t.Fatalf("cant start server again: %v", err)
+	}
+	wait(1)
+	defer func() {
+		finish()
+		srv.Wait()
+	}()
+
+	conn = getGrpcConn(t)
+	defer conn.Close()
+	biz = NewBizClient(conn)
+	adm := NewAdminClient(conn)
+
+	biz.Check(getConsumerCtx("biz_admin"), &Nothing{})
+
+	streamCtx, cancel := getConsumerCtxWithCancel("logger1")
+	defer cancel()
+	logStream, err := adm.Logging(streamCtx, &LoggingRequest{FromSeq: 2})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	type logged struct {
+		Seq      uint64
+		Consumer string
+		Method   string
+	}
+	expected := []logged{
+		{2, "biz_user", "/main.Biz/Add"},
+		{3, "biz_admin", "/main.Biz/Test"},
+		{4, "biz_admin", "/main.Biz/Check"},
+		{5, "logger1", "/main.Admin/Logging"},
+		{6, "biz_user", "/main.Biz/Check"},
+	}
+	wait(1)
+	biz.Check(getConsumerCtx("biz_user"), &Nothing{})
+
+	var got []logged
+	for range expected {
+		evt, err := logStream.Recv()
+		if err != nil {
+			t.Fatalf("unexpected error: %v, awaiting event", err)
+		}
+		got = append(got, logged{evt.Seq, evt.Consumer, evt.Method})
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Fatalf("replayed events dont match\nhave %+v\nwant %+v", got, expected)
+	}
+}
+
+func TestSlowSubscriber(t *testing.T) {
+	eventLog, err := NewEventLog(10, "")
+	if err != nil {
+		t.Fatalf("cant create event log: %v", err)
+	}
+
+	subs := NewEventSub(eventLog, 2, DropOldest)
+	_, sub := subs.NewSub(nil)
+	for i := 0; i < 3; i++ {
+		subs.Notify(&Event{Method: "/main.Biz/Check"})
+	}
+	if first := <-sub.Events; first.Event.Seq != 2 || sub.Dropped() != 1 {
+		t.Fatalf("expected the oldest event to be dropped, got seq %d, dropped %d", first.Event.Seq, sub.Dropped())
+	}
+
+	subs = NewEventSub(eventLog, 2, Disconnect)
+	_, sub = subs.NewSub(nil)
+	for i := 0; i < 3; i++ {
+		subs.Notify(&Event{Method: "/main.Biz/Check"})
+	}
+	for range sub.Events {
+	}
+	if code := status.Code(sub.Err()); code != codes.ResourceExhausted {
+		t.Fatalf("expected ResourceExhausted for a disconnected subscriber, got %v", code)
+	}
+}
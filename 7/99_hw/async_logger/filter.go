@@ -0,0 +1,44 @@
+package main
+
+import (
+	"fmt"
+	"path"
+)
+
+// Validate checks the patterns, so a bad one is reported instead of matching nothing
+func (f *EventFilter) Validate() error {
+	for _, patterns := range [][]string{f.GetMethods(), f.GetHosts()} {
+		for _, pattern := range patterns {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return fmt.Errorf("bad pattern %q: %v", pattern, err)
+			}
+		}
+	}
+	return nil
+}
+
+// Matches reports whether the event passes the filter, a nil filter passes everything
+func (f *EventFilter) Matches(event *Event) bool {
+	if f == nil {
+		return true
+	}
+	return matchesAny(f.Consumers, event.Consumer, false) &&
+		matchesAny(f.Methods, event.Method, true) &&
+		matchesAny(f.Hosts, event.Host, true)
+}
+
+// matchesAny is true for an empty list
+func matchesAny(patterns []string, value string, glob bool) bool {
+	if len(patterns) == 0 {
+		return true
+	}
+	for _, pattern := range patterns {
+		if !glob && pattern == value {
+			return true
+		}
+		if ok, _ := path.Match(pattern, value); glob && ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestLoggingFilter(t *testing.T) {
+	ctx, finish := context.WithCancel(context.Background())
+	srv, err := StartMyMicroservice(ctx, listenAddr, ACLData)
+	if err != nil {
+		t.Fatalf("cant start server initial: %v", err)
+	}
+	wait(1)
+	defer func() {
+		finish()
+		srv.Wait()
+	}()
+
+	conn := getGrpcConn(t)
+	defer conn.Close()
+
+	biz := NewBizClient(conn)
+	adm := NewAdminClient(conn)
+
+	streamCtx, cancel := getConsumerCtxWithCancel("logger1")
+	defer cancel()
+	logStream, err := adm.Logging(streamCtx, &LoggingRequest{
+		Filter: &EventFilter{
+			Consumers: []string{"biz_admin", "biz_user"},
+			Methods:   []string{"/main.Biz/[CT]*"},
+			Hosts:     []string{"127.0.0.1:*"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	wait(1)
+
+	biz.Check(getConsumerCtx("biz_user"), &Nothing{})
+	biz.Add(getConsumerCtx("biz_user"), &Nothing{})
+	biz.Check(getConsumerCtx("unknown"), &Nothing{})
+	biz.Test(getConsumerCtx("biz_admin"), &Nothing{})
+
+	var got []*Event
+	for i := 0; i < 2; i++ {
+		evt, err := logStream.Recv()
+		if err != nil {
+			t.Fatalf("unexpected error: %v, awaiting event", err)
+		}
+		got = append(got, &Event{Consumer: evt.Consumer, Method: evt.Method})
+	}
+	expected := []*Event{
+		{Consumer: "biz_user", Method: "/main.Biz/Check"},
+		{Consumer: "biz_admin", Method: "/main.Biz/Test"},
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Fatalf("filtered logs dont match\nhave %+v\nwant %+v", got, expected)
+	}
+
+	badStream, err := adm.Logging(getConsumerCtx("logger2"), &LoggingRequest{
+		Filter: &EventFilter{Methods: []string{"/main.Biz/["}},
+	})
+	if err == nil {
+		_, err = badStream.Recv()
+	}
+	if code := status.Code(err); code != codes.InvalidArgument {
+		t.Fatalf("expected InvalidArgument on a bad pattern, got %v", code)
+	}
+}
+
+func TestStatCollector(t *testing.T) {
+	collector := NewCollector([]Dimension{Dimension_METHOD, Dimension_CONSUMER}, []float64{50, 99.9})
+
+	check := &Event{Consumer: "biz_user", Method: "/main.Biz/Check", Host: "127.0.0.1:1"}
+	add := &Event{Consumer: "biz_user", Method: "/main.Biz/Add", Host: "127.0.0.1:2"}
+	for i := 1; i <= 10; i++ {
+		collector.Update(check)
+		collector.Observe(check, time.Duration(i)*time.Millisecond)
+	}
+	collector.Update(add)
+	collector.Update(&Event{Consumer: "biz_admin", Method: "/main.Biz/Add", Host: "127.0.0.1:2"})
+
+	stat := collector.Collect()
+
+	type group struct {
+		Method, Consumer, Host string
+		Count                  uint64
+	}
+	var groups []group
+	for _, g := range stat.Groups {
+		groups = append(groups, group{g.Method, g.Consumer, g.Host, g.Count})
+	}
+	expectedGroups := []group{
+		{"/main.Biz/Add", "biz_admin", "", 1},
+		{"/main.Biz/Add", "biz_user", "", 1},
+		{"/main.Biz/Check", "biz_user", "", 10},
+	}
+	if !reflect.DeepEqual(groups, expectedGroups) {
+		t.Fatalf("groups dont match\nhave %+v\nwant %+v", groups, expectedGroups)
+	}
+
+	latency := stat.LatencyByMethod["/main.Biz/Check"]
+	expectedPercentiles := map[string]float64{"p50": 5, "p99.9": 10}
+	if latency.GetCount() != 10 || latency.GetMaxMs() != 10 || !reflect.DeepEqual(latency.GetPercentilesMs(), expectedPercentiles) {
+		t.Fatalf("latency dont match\nhave %+v\nwant percentiles %+v", latency, expectedPercentiles)
+	}
+	if _, ok := stat.LatencyByMethod["/main.Biz/Add"]; ok {
+		t.Fatalf("latency of calls which are not finished")
+	}
+
+	// следующий интервал начинается с нуля
+	if next := collector.Collect(); len(next.ByMethod) != 0 || len(next.Groups) != 0 || next.LatencyByMethod != nil {
+		t.Fatalf("collector is not reset: %+v", next)
+	}
+}
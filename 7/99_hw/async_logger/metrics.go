@@ -0,0 +1,138 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/codes"
+)
+
+type requestKey struct {
+	method, consumer string
+	code             codes.Code
+}
+
+// histogram counts observations per upper bound, the last count is +Inf
+type histogram struct {
+	counts []uint64
+	sum    float64
+	count  uint64
+}
+
+// Metrics collects calls for Prometheus, ServeHTTP writes them in the text
+// exposition format
+type Metrics struct {
+	mu        sync.Mutex
+	requests  map[requestKey]uint64
+	latencies map[string]*histogram
+	// bounds are upper bounds of the latency buckets in seconds
+	bounds []float64
+	subs   *SubEvent
+}
+
+func NewMetrics(subs *SubEvent) *Metrics {
+	return &Metrics{
+		requests:  make(map[requestKey]uint64),
+		latencies: make(map[string]*histogram),
+		bounds:    []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10},
+		subs:      subs,
+	}
+}
+
+// Observe records a finished call
+func (m *Metrics) Observe(event *Event, code codes.Code, duration time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.requests[requestKey{event.Method, event.Consumer, code}]++
+
+	h, ok := m.latencies[event.Method]
+	if !ok {
+		h = &histogram{counts: make([]uint64, len(m.bounds)+1)}
+		m.latencies[event.Method] = h
+	}
+	seconds := duration.Seconds()
+	i := sort.SearchFloat64s(m.bounds, seconds)
+	h.counts[i]++
+	h.sum += seconds
+	h.count++
+}
+
+func (m *Metrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	m.WriteTo(w)
+}
+
+// WriteTo writes the metrics sorted by labels, so the output is stable
+func (m *Metrics) WriteTo(w io.Writer) (int64, error) {
+	var b strings.Builder
+
+	m.mu.Lock()
+	keys := make([]requestKey, 0, len(m.requests))
+	for key := range m.requests {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		a, b := keys[i], keys[j]
+		if a.method != b.method {
+			return a.method < b.method
+		}
+		if a.consumer != b.consumer {
+			return a.consumer < b.consumer
+		}
+		return a.code < b.code
+	})
+	b.WriteString("# HELP async_logger_requests_total Calls by method, consumer and status code.\n")
+	b.WriteString("# TYPE async_logger_requests_total counter\n")
+	for _, key := range keys {
+		fmt.Fprintf(&b, "async_logger_requests_total{method=%s,consumer=%s,code=%s} %d\n",
+			labelValue(key.method), labelValue(key.consumer), labelValue(key.code.String()), m.requests[key])
+	}
+
+	methods := make([]string, 0, len(m.latencies))
+	for method := range m.latencies {
+		methods = append(methods, method)
+	}
+	sort.Strings(methods)
+	b.WriteString("# HELP async_logger_request_duration_seconds Duration of calls by method.\n")
+	b.WriteString("# TYPE async_logger_request_duration_seconds histogram\n")
+	for _, method := range methods {
+		h := m.latencies[method]
+		var cumulative uint64
+		for i, count := range h.counts {
+			cumulative += count
+			le := "+Inf"
+			if i < len(m.bounds) {
+				le = strconv.FormatFloat(m.bounds[i], 'g', -1, 64)
+			}
+			fmt.Fprintf(&b, "async_logger_request_duration_seconds_bucket{method=%s,le=%s} %d\n",
+				labelValue(method), labelValue(le), cumulative)
+		}
+		fmt.Fprintf(&b, "async_logger_request_duration_seconds_sum{method=%s} %s\n",
+			labelValue(method), strconv.FormatFloat(h.sum, 'g', -1, 64))
+		fmt.Fprintf(&b, "async_logger_request_duration_seconds_count{method=%s} %d\n",
+			labelValue(method), h.count)
+	}
+	m.mu.Unlock()
+
+	b.WriteString("# HELP async_logger_active_subscribers Open Logging and Statistics streams.\n")
+	b.WriteString("# TYPE async_logger_active_subscribers gauge\n")
+	fmt.Fprintf(&b, "async_logger_active_subscribers %d\n", m.subs.Len())
+
+	n, err := io.WriteString(w, b.String())
+	return int64(n), err
+}
+
+// labelValue quotes a label value as the exposition format wants it
+func labelValue(value string) string {
+	value = strings.ReplaceAll(value, `\`, `\\`)
+	value = strings.ReplaceAll(value, `"`, `\"`)
+	value = strings.ReplaceAll(value, "\n", `\n`)
+	return `"` + value + `"`
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	reflectionpb "google.golang.org/grpc/reflection/grpc_reflection_v1alpha"
+	"google.golang.org/grpc/status"
+)
+
+const metricsAddr string = "127.0.0.1:8083"
+
+func TestMetrics(t *testing.T) {
+	ctx, finish := context.WithCancel(context.Background())
+	srv, err := StartMicroservice(ctx, Config{
+		Addr:        listenAddr,
+		ACL:         `{"biz_user": ["/main.Biz/Check"], "logger": ["/main.Admin/Logging"]}`,
+		MetricsAddr: metricsAddr,
+	})
+	if err != nil {
+		t.Fatalf("cant start server initial: %v", err)
+	}
+	wait(1)
+	defer func() {
+		finish()
+		srv.Wait()
+	}()
+
+	conn := getGrpcConn(t)
+	defer conn.Close()
+
+	biz := NewBizClient(conn)
+	adm := NewAdminClient(conn)
+
+	streamCtx, cancel := getConsumerCtxWithCancel("logger")
+	defer cancel()
+	if _, err := adm.Logging(streamCtx, &LoggingRequest{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	wait(1)
+
+	biz.Check(getConsumerCtx("biz_user"), &Nothing{})
+	biz.Check(getConsumerCtx("biz_user"), &Nothing{})
+	if _, err := biz.Add(getConsumerCtx("biz_user"), &Nothing{}); status.Code(err) != codes.Unauthenticated {
+		t.Fatalf("expected Unauthenticated, got %v", err)
+	}
+
+	resp, err := http.Get("http://" + metricsAddr + "/metrics")
+	if err != nil {
+		t.Fatalf("cant get metrics: %v", err)
+	}
+	body, _ := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	for _, line := range []string{
+		`async_logger_requests_total{method="/main.Biz/Check",consumer="biz_user",code="OK"} 2`,
+		`async_logger_requests_total{method="/main.Biz/Add",consumer="biz_user",code="Unauthenticated"} 1`,
+		`async_logger_request_duration_seconds_bucket{method="/main.Biz/Check",le="+Inf"} 2`,
+		`async_logger_request_duration_seconds_count{method="/main.Biz/Add"} 1`,
+		`async_logger_active_subscribers 1`,
+	} {
+		if !strings.Contains(string(body), line+"\n") {
+			t.Errorf("expected %s in metrics:\n%s", line, body)
+		}
+	}
+}
+
+// health и reflection отвечают без consumer и не попадают в лог
+func TestHealthAndReflection(t *testing.T) {
+	ctx, finish := context.WithCancel(context.Background())
+	srv, err := StartMyMicroservice(ctx, listenAddr, `{"logger": ["/main.Admin/Logging"]}`)
+	if err != nil {
+		t.Fatalf("cant start server initial: %v", err)
+	}
+	wait(1)
+	defer func() {
+		finish()
+		srv.Wait()
+	}()
+
+	conn := getGrpcConn(t)
+	defer conn.Close()
+
+	streamCtx, cancel := getConsumerCtxWithCancel("logger")
+	defer cancel()
+	logStream, err := NewAdminClient(conn).Logging(streamCtx, &LoggingRequest{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	wait(1)
+
+	healthClient := healthpb.NewHealthClient(conn)
+	for _, service := range []string{"", "main.Biz", "main.Admin"} {
+		resp, err := healthClient.Check(context.Background(), &healthpb.HealthCheckRequest{Service: service})
+		if err != nil {
+			t.Fatalf("unexpected error on health check of %q: %v", service, err)
+		}
+		if resp.Status != healthpb.HealthCheckResponse_SERVING {
+			t.Fatalf("expected %q to be serving, got %v", service, resp.Status)
+		}
+	}
+
+	info, err := reflectionpb.NewServerReflectionClient(conn).ServerReflectionInfo(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	err = info.Send(&reflectionpb.ServerReflectionRequest{
+		MessageRequest: &reflectionpb.ServerReflectionRequest_ListServices{},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	resp, err := info.Recv()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	services := map[string]bool{}
+	for _, service := range resp.GetListServicesResponse().GetService() {
+		services[service.Name] = true
+	}
+	if !services["main.Biz"] || !services["main.Admin"] || !services["grpc.health.v1.Health"] {
+		t.Fatalf("unexpected services: %v", services)
+	}
+	info.CloseSend()
+
+	if _, err := NewBizClient(conn).Check(getConsumerCtx("logger"), &Nothing{}); status.Code(err) != codes.Unauthenticated {
+		t.Fatalf("expected Unauthenticated, got %v", err)
+	}
+	evt, err := logStream.Recv()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if evt.Method != "/main.Biz/Check" {
+		t.Fatalf("expected only the Check event, got %s", evt.Method)
+	}
+}
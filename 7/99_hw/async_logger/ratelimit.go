@@ -0,0 +1,175 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// aclLimit is a rate limit of the ACL JSON:
+//
+//	"limits": [{"consumer": "biz_user", "method": "/main.Biz/*", "rate": 10, "burst": 20}]
+//
+// rate is calls per second, burst is how many calls can be made at once.
+// consumer "*" is every consumer, each of them gets its own bucket
+type aclLimit struct {
+	Consumer string  `json:"consumer"`
+	Method   string  `json:"method"`
+	Rate     float64 `json:"rate"`
+	Burst    float64 `json:"burst"`
+}
+
+type limitRule struct {
+	consumer string
+	method   aclPattern
+	rate     float64
+	burst    float64
+}
+
+func parseLimits(limits []aclLimit) ([]limitRule, error) {
+	rules := make([]limitRule, len(limits))
+	for i, l := range limits {
+		if l.Consumer == "" {
+			return nil, fmt.Errorf("limit %d: consumer is required", i)
+		}
+		if !(l.Rate > 0) {
+			return nil, fmt.Errorf("limit %d: rate must be positive", i)
+		}
+		if l.Burst == 0 {
+			l.Burst = math.Max(1, l.Rate)
+		}
+		if l.Burst < 1 {
+			return nil, fmt.Errorf("limit %d: burst must be at least 1", i)
+		}
+		if l.Method == "" {
+			l.Method = "/**"
+		}
+		patterns, err := parsePatterns([]string{l.Method})
+		if err != nil {
+			return nil, fmt.Errorf("limit %d: %v", i, err)
+		}
+		rules[i] = limitRule{consumer: l.Consumer, method: patterns[0], rate: l.Rate, burst: l.Burst}
+	}
+	return rules, nil
+}
+
+// limit finds the first rule for the call, false means the call isn't limited
+func (acl *ACL) limit(consumer, method string) (limitRule, bool) {
+	splitted := strings.Split(method, "/")
+	for _, rule := range acl.limits {
+		if (rule.consumer == consumer || rule.consumer == "*") && rule.method.match(splitted) {
+			return rule, true
+		}
+	}
+	return limitRule{}, false
+}
+
+type bucketKey struct {
+	consumer, method string
+}
+
+type bucket struct {
+	tokens  float64
+	rate    float64
+	burst   float64
+	updated time.Time
+}
+
+// level is the number of tokens at now
+func (b *bucket) level(now time.Time) float64 {
+	return math.Min(b.burst, b.tokens+now.Sub(b.updated).Seconds()*b.rate)
+}
+
+// bucketSweepInterval is how often Allow drops buckets which have refilled
+const bucketSweepInterval = time.Minute
+
+// RateLimiter keeps a token bucket per consumer and method. Rates are taken
+// from the ACL on every call, so an ACL update changes them for buckets which
+// already exist, their levels are kept.
+// A full bucket is no different from a new one, so such buckets are dropped
+// from time to time, otherwise "*" rules would keep one for every consumer ever seen
+type RateLimiter struct {
+	mu        sync.Mutex
+	buckets   map[bucketKey]*bucket
+	lastSweep time.Time
+}
+
+func NewRateLimiter() *RateLimiter {
+	return &RateLimiter{buckets: make(map[bucketKey]*bucket)}
+}
+
+// Allow takes a token for the call, otherwise it tells when the next token comes
+func (r *RateLimiter) Allow(acl *ACL, consumer, method string, now time.Time) (bool, time.Duration) {
+	rule, ok := acl.limit(consumer, method)
+	if !ok {
+		return true, 0
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if now.Sub(r.lastSweep) >= bucketSweepInterval {
+		r.sweep(now)
+	}
+
+	key := bucketKey{consumer, method}
+	b, ok := r.buckets[key]
+	if !ok {
+		b = &bucket{tokens: rule.burst, rate: rule.rate, burst: rule.burst, updated: now}
+		r.buckets[key] = b
+	}
+	b.tokens = b.level(now)
+	b.updated = now
+	b.rate = rule.rate
+	b.burst = rule.burst
+	b.tokens = math.Min(b.tokens, b.burst)
+
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+	wait := time.Duration((1 - b.tokens) / b.rate * float64(time.Second))
+	return false, wait
+}
+
+// sweep drops buckets which are full at now
+func (r *RateLimiter) sweep(now time.Time) {
+	for key, b := range r.buckets {
+		if b.level(now) >= b.burst {
+			delete(r.buckets, key)
+		}
+	}
+	r.lastSweep = now
+}
+
+// Levels returns buckets of the calls passing the filter
+func (r *RateLimiter) Levels(filter *EventFilter, now time.Time) []*BucketLevel {
+	// buckets don't depend on hosts
+	filter = &EventFilter{Consumers: filter.GetConsumers(), Methods: filter.GetMethods()}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var levels []*BucketLevel
+	for key, b := range r.buckets {
+		if !filter.Matches(&Event{Consumer: key.consumer, Method: key.method}) {
+			continue
+		}
+		levels = append(levels, &BucketLevel{
+			Consumer: key.consumer,
+			Method:   key.method,
+			Tokens:   b.level(now),
+			Burst:    b.burst,
+		})
+	}
+	sort.Slice(levels, func(i, j int) bool {
+		if levels[i].Consumer != levels[j].Consumer {
+			return levels[i].Consumer < levels[j].Consumer
+		}
+		return levels[i].Method < levels[j].Method
+	})
+	return levels
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestRateLimiter(t *testing.T) {
+	acl, err := NewACL(`{
+		"consumers": {"biz_user": ["/main.Biz/*"]},
+		"limits": [
+			{"consumer": "biz_user", "method": "/main.Biz/Check", "rate": 1, "burst": 2},
+			{"consumer": "*", "rate": 10}
+		]
+	}`)
+	if err != nil {
+		t.Fatalf("cant parse acl: %v", err)
+	}
+
+	limiter := NewRateLimiter()
+	now := time.Now()
+	for i := 0; i < 2; i++ {
+		if ok, _ := limiter.Allow(acl, "biz_user", "/main.Biz/Check", now); !ok {
+			t.Fatalf("[%d] expected call within burst to pass", i)
+		}
+	}
+	ok, wait := limiter.Allow(acl, "biz_user", "/main.Biz/Check", now)
+	if ok || wait != time.Second {
+		t.Fatalf("expected rejection with 1s wait, got %v %v", ok, wait)
+	}
+	// половина токена за полсекунды - все еще мало
+	if ok, wait := limiter.Allow(acl, "biz_user", "/main.Biz/Check", now.Add(500*time.Millisecond)); ok || wait != 500*time.Millisecond {
+		t.Fatalf("expected rejection with 500ms wait, got %v %v", ok, wait)
+	}
+	if ok, _ := limiter.Allow(acl, "biz_user", "/main.Biz/Check", now.Add(time.Second)); !ok {
+		t.Fatalf("expected call to pass after refill")
+	}
+	// у другого метода свой бакет по правилу "*"
+	if ok, _ := limiter.Allow(acl, "biz_user", "/main.Biz/Add", now); !ok {
+		t.Fatalf("expected Add to have its own bucket")
+	}
+
+	levels := limiter.Levels(&EventFilter{Methods: []string{"/main.Biz/Add"}}, now)
+	if len(levels) != 1 || levels[0].Tokens != 9 || levels[0].Burst != 10 {
+		t.Fatalf("unexpected levels: %v", levels)
+	}
+	if levels := limiter.Levels(nil, now.Add(time.Second)); len(levels) != 2 {
+		t.Fatalf("expected 2 buckets, got %v", levels)
+	}
+
+	for _, data := range []string{
+		`{"consumers": {}, "limits": [{"method": "/main.Biz/*", "rate": 1}]}`,
+		`{"consumers": {}, "limits": [{"consumer": "x", "rate": 0}]}`,
+		`{"consumers": {}, "limits": [{"consumer": "x", "rate": 1, "burst": 0.5}]}`,
+		`{"consumers": {}, "limits": [{"consumer": "x", "method": "main.Biz/*", "rate": 1}]}`,
+		`{"consumers": {}, "limits": [{"consumer": "x", "rate": 1, "brst": 1}]}`,
+	} {
+		if _, err := NewACL(data); err == nil {
+			t.Errorf("expected error on acl %s", data)
+		}
+	}
+}
+
+// по правилу "*" у каждого потребителя свой бакет, полные выкидываются,
+// иначе их число растет без предела
+func TestRateLimiterSweep(t *testing.T) {
+	acl, err := NewACL(`{
+		"consumers": {},
+		"limits": [
+			{"consumer": "slow", "rate": 0.001, "burst": 1},
+			{"consumer": "*", "rate": 10}
+		]
+	}`)
+	if err != nil {
+		t.Fatalf("cant parse acl: %v", err)
+	}
+
+	limiter := NewRateLimiter()
+	now := time.Now()
+	for i := 0; i < 100; i++ {
+		limiter.Allow(acl, "consumer_"+strconv.Itoa(i), "/main.Biz/Check", now)
+	}
+	limiter.Allow(acl, "slow", "/main.Biz/Check", now)
+	if levels := limiter.Levels(nil, now); len(levels) != 101 {
+		t.Fatalf("expected 101 buckets, got %d", len(levels))
+	}
+
+	// до следующей чистки бакеты живут, даже если уже полные
+	now = now.Add(bucketSweepInterval / 2)
+	limiter.Allow(acl, "consumer_0", "/main.Biz/Check", now)
+	if levels := limiter.Levels(nil, now); len(levels) != 101 {
+		t.Fatalf("expected 101 buckets before sweep, got %d", len(levels))
+	}
+
+	// после чистки остаются consumer_1, у которого только что взяли токен,
+	// и slow, который не успел наполниться
+	now = now.Add(bucketSweepInterval)
+	ok, _ := limiter.Allow(acl, "consumer_1", "/main.Biz/Check", now)
+	if !ok {
+		t.Fatalf("expected call to pass after sweep")
+	}
+	levels := limiter.Levels(nil, now)
+	if len(levels) != 2 || levels[0].Consumer != "consumer_1" || levels[0].Tokens != 9 || levels[1].Consumer != "slow" {
+		t.Fatalf("unexpected buckets after sweep: %v", levels)
+	}
+	if ok, _ := limiter.Allow(acl, "slow", "/main.Biz/Check", now); ok {
+		t.Fatalf("expected slow bucket to keep its level")
+	}
+}
+
+func TestRateLimitExceeded(t *testing.T) {
+	ctx, finish := context.WithCancel(context.Background())
+	srv, err := StartMyMicroservice(ctx, listenAddr, `{
+	"consumers": {
+		"biz_user": ["/main.Biz/*"],
+		"stat": ["/main.Admin/Statistics"]
+	},
+	"limits": [{"consumer": "biz_user", "method": "/main.Biz/*", "rate": 0.5, "burst": 2}]
+}`)
+	if err != nil {
+		t.Fatalf("cant start server initial: %v", err)
+	}
+	wait(1)
+	defer func() {
+		finish()
+		srv.Wait()
+	}()
+
+	conn := getGrpcConn(t)
+	defer conn.Close()
+
+	biz := NewBizClient(conn)
+	adm := NewAdminClient(conn)
+
+	statCtx, cancel := getConsumerCtxWithCancel("stat")
+	defer cancel()
+	statStream, err := adm.Statistics(statCtx, &StatInterval{IntervalSeconds: 1})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	wait(1)
+
+	for i := 0; i < 2; i++ {
+		if _, err := biz.Check(getConsumerCtx("biz_user"), &Nothing{}); err != nil {
+			t.Fatalf("[%d] unexpected error: %v", i, err)
+		}
+	}
+
+	var header metadata.MD
+	_, err = biz.Check(getConsumerCtx("biz_user"), &Nothing{}, grpc.Header(&header))
+	if status.Code(err) != codes.ResourceExhausted {
+		t.Fatalf("expected ResourceExhausted, got %v", err)
+	}
+	retryAfter, err := strconv.ParseFloat(header.Get("retry-after")[0], 64)
+	if err != nil || retryAfter <= 0 || retryAfter > 2 {
+		t.Fatalf("unexpected retry-after %v: %v", header.Get("retry-after"), err)
+	}
+
+	// у каждого метода свой бакет
+	if _, err := biz.Add(getConsumerCtx("biz_user"), &Nothing{}); err != nil {
+		t.Fatalf("unexpected error on Add: %v", err)
+	}
+
+	// статистика может прийти раньше последнего вызова
+	var stat *Stat
+	for stat == nil || len(stat.Buckets) < 2 {
+		stat, err = statStream.Recv()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if len(stat.Buckets) != 2 || stat.Buckets[0].Method != "/main.Biz/Add" || stat.Buckets[1].Method != "/main.Biz/Check" {
+		t.Fatalf("unexpected buckets: %v", stat.Buckets)
+	}
+	if stat.Buckets[1].Tokens >= 1 || stat.Buckets[1].Burst != 2 {
+		t.Fatalf("expected Check bucket to be almost empty, got %v", stat.Buckets[1])
+	}
+}
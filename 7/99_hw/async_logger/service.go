@@ -1,16 +1,26 @@
-package __
+package main
 
 import (
 	"context"
-	"encoding/json"
+	"crypto/tls"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
 	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/reflection"
 	"google.golang.org/grpc/status"
+	"log"
+	"math"
 	"net"
+	"net/http"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -37,147 +47,315 @@ func NewBizServer() *bizServer {
 type adminServer struct {
 	UnimplementedAdminServer
 	subsManager *SubEvent
+	acl         *ACLStore
+	limiter     *RateLimiter
 }
 
-func (a *adminServer) Logging(n *Nothing, ad Admin_LoggingServer) error {
-	id, events := a.subsManager.NewSub()
+// UpdateACL replaces the ACL for the calls which start after it
+func (a *adminServer) UpdateACL(ctx context.Context, update *ACLUpdate) (*Nothing, error) {
+	acl, err := NewACL(update.Acl)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "bad acl: %v", err)
+	}
+	a.acl.Store(acl)
+	return &Nothing{}, nil
+}
+
+// Logging replays past events if the request asks for them, then streams new ones
+func (a *adminServer) Logging(req *LoggingRequest, ad Admin_LoggingServer) error {
+	if err := req.GetFilter().Validate(); err != nil {
+		return status.Errorf(codes.InvalidArgument, "filter: %v", err)
+	}
+	id, sub := a.subsManager.NewSub(req.GetFilter())
 	defer a.subsManager.RemoveSub(id)
 
-	for event := range events {
+	// events after Since are already in the queue of the subscriber
+	history, err := a.subsManager.Log.Replay(req, sub.Since)
+	if err != nil {
+		return status.Errorf(codes.Internal, "cant read event log: %v", err)
+	}
+	for _, event := range history {
 		if err := ad.Send(event); err != nil {
 			return err
 		}
 	}
-	return nil
+
+	for {
+		select {
+		case notice, ok := <-sub.Events:
+			if !ok {
+				return sub.Err()
+			}
+			if err := ad.Send(notice.Event); err != nil {
+				return err
+			}
+		case <-ad.Context().Done():
+			return nil
+		}
+	}
 }
 
+// statGroupKey holds the dimensions of group_by, the others are empty
+type statGroupKey struct {
+	method, consumer, host string
+}
+
+// StatCollector counts events of an interval
 type StatCollector struct {
-	stat Stat
+	byMethod    map[string]uint64
+	byConsumer  map[string]uint64
+	groupBy     []Dimension
+	groups      map[statGroupKey]uint64
+	percentiles []float64
+	durations   map[string][]time.Duration
 }
 
-func NewCollector() *StatCollector {
-	return &StatCollector{
-		Stat{
-			ByMethod:   make(map[string]uint64),
-			ByConsumer: make(map[string]uint64),
-		},
+func NewCollector(groupBy []Dimension, percentiles []float64) *StatCollector {
+	if len(percentiles) == 0 {
+		percentiles = []float64{50, 90, 99}
+	}
+	s := &StatCollector{
+		groupBy:     groupBy,
+		percentiles: percentiles,
 	}
+	s.reset()
+	return s
 }
 
 func (s *StatCollector) Update(event *Event) {
-	s.stat.ByConsumer[event.Consumer] += 1
-	s.stat.ByMethod[event.Method] += 1
+	s.byConsumer[event.Consumer] += 1
+	s.byMethod[event.Method] += 1
+
+	if len(s.groupBy) == 0 {
+		return
+	}
+	key := statGroupKey{}
+	for _, dimension := range s.groupBy {
+		switch dimension {
+		case Dimension_METHOD:
+			key.method = event.Method
+		case Dimension_CONSUMER:
+			key.consumer = event.Consumer
+		case Dimension_HOST:
+			key.host = event.Host
+		}
+	}
+	s.groups[key] += 1
+}
+
+// Observe records the duration of a finished call
+func (s *StatCollector) Observe(event *Event, duration time.Duration) {
+	s.durations[event.Method] = append(s.durations[event.Method], duration)
 }
 
 func (s *StatCollector) reset() {
-	s.stat = Stat{
-		ByMethod:   make(map[string]uint64),
-		ByConsumer: make(map[string]uint64),
-	}
+	s.byMethod = make(map[string]uint64)
+	s.byConsumer = make(map[string]uint64)
+	s.groups = make(map[statGroupKey]uint64)
+	s.durations = make(map[string][]time.Duration)
 }
 
 func (s *StatCollector) Collect() *Stat {
 	newStat := &Stat{
 		Timestamp:  time.Now().Unix(),
-		ByMethod:   s.stat.ByMethod,
-		ByConsumer: s.stat.ByConsumer,
+		ByMethod:   s.byMethod,
+		ByConsumer: s.byConsumer,
+	}
+
+	for key, count := range s.groups {
+		newStat.Groups = append(newStat.Groups, &StatGroup{
+			Method:   key.method,
+			Consumer: key.consumer,
+			Host:     key.host,
+			Count:    count,
+		})
+	}
+	sort.Slice(newStat.Groups, func(i, j int) bool {
+		a, b := newStat.Groups[i], newStat.Groups[j]
+		if a.Method != b.Method {
+			return a.Method < b.Method
+		}
+		if a.Consumer != b.Consumer {
+			return a.Consumer < b.Consumer
+		}
+		return a.Host < b.Host
+	})
+
+	if len(s.durations) > 0 {
+		newStat.LatencyByMethod = make(map[string]*Latency, len(s.durations))
+	}
+	for method, durations := range s.durations {
+		newStat.LatencyByMethod[method] = latency(durations, s.percentiles)
 	}
 	s.reset()
 
 	return newStat
 }
 
+// latency takes percentiles by the nearest rank
+func latency(durations []time.Duration, percentiles []float64) *Latency {
+	sort.Slice(durations, func(i, j int) bool { return durations[i] < durations[j] })
+	ms := func(d time.Duration) float64 {
+		return float64(d) / float64(time.Millisecond)
+	}
+
+	l := &Latency{
+		Count:         uint64(len(durations)),
+		MaxMs:         ms(durations[len(durations)-1]),
+		PercentilesMs: make(map[string]float64, len(percentiles)),
+	}
+	for _, p := range percentiles {
+		rank := int(math.Ceil(p / 100 * float64(len(durations))))
+		if rank < 1 {
+			rank = 1
+		}
+		l.PercentilesMs["p"+strconv.FormatFloat(p, 'f', -1, 64)] = ms(durations[rank-1])
+	}
+	return l
+}
+
+// Statistics sends a stat of each window, windows are computed by the
+// aggregator shared by all streams
 func (a *adminServer) Statistics(s *StatInterval, ad Admin_StatisticsServer) error {
-	id, events := a.subsManager.NewSub()
-	defer a.subsManager.RemoveSub(id)
-	statCollector := NewCollector()
-	ticker := time.NewTicker(time.Duration(s.IntervalSeconds))
-	defer ticker.Stop()
+	if s.IntervalSeconds == 0 {
+		return status.Errorf(codes.InvalidArgument, "interval_seconds must be positive")
+	}
+	if s.SlideSeconds > s.IntervalSeconds {
+		return status.Errorf(codes.InvalidArgument, "slide_seconds can't be longer than interval_seconds")
+	}
+	if err := s.GetFilter().Validate(); err != nil {
+		return status.Errorf(codes.InvalidArgument, "filter: %v", err)
+	}
+	for _, p := range s.Percentiles {
+		if !(p > 0 && p <= 100) {
+			return status.Errorf(codes.InvalidArgument, "percentile %v is out of (0, 100]", p)
+		}
+	}
+
+	id, window := a.subsManager.Stats.Register(s)
+	defer a.subsManager.Stats.Unregister(id)
 
 	for {
 		select {
-		case event, ok := <-events:
-			if ok {
-				statCollector.Update(event)
-			} else {
+		case stat, ok := <-window.Stats:
+			if !ok {
 				return nil
 			}
-		case <-ticker.C:
-			if err := ad.Send(statCollector.Collect()); err != nil {
+			stat.Buckets = a.limiter.Levels(s.GetFilter(), time.Now())
+			if err := ad.Send(stat); err != nil {
 				return err
 			}
+		case <-ad.Context().Done():
+			return nil
 		}
 	}
 }
 
-func NewAdminServer(subs *SubEvent) *adminServer {
-	return &adminServer{subsManager: subs}
+func NewAdminServer(subs *SubEvent, acl *ACLStore, limiter *RateLimiter) *adminServer {
+	return &adminServer{subsManager: subs, acl: acl, limiter: limiter}
 }
 
-// []string{"url components"}
-type ACLMethods [][]string
+// OverflowPolicy is what happens when a subscriber lags behind by more than its queue
+type OverflowPolicy int
+
+const (
+	// DropOldest drops the oldest queued event to make room for the new one
+	DropOldest OverflowPolicy = iota
+	// Disconnect closes the stream of the subscriber with ResourceExhausted
+	Disconnect
+)
 
-type ACL struct {
-	allowed map[string]ACLMethods
+// Notice is a call which has started, or has finished if Finished is set
+type Notice struct {
+	Event    *Event
+	Finished bool
+	Duration time.Duration
 }
 
-func (acl *ACL) IsAllowed(consumer, method string) bool {
-	splitted := strings.Split(method, "/")
-	if methods, ok := acl.allowed[consumer]; ok {
-		for _, rule := range methods {
-			match := true
-			for i := 0; i < len(rule); i++ {
-				if i >= len(splitted) {
-					match = false
-					break
-				}
-				if rule[i] != "*" && rule[i] != splitted[i] {
-					match = false
-					break
-				}
-			}
-			if match {
-				return true
-			}
-		}
-	}
-	return false
+// Subscriber gets events through a buffered queue, so a slow client
+// doesn't hold up the calls which are logged
+type Subscriber struct {
+	Events chan *Notice
+	// Since is the number of the last event before the subscription
+	Since  uint64
+	Policy OverflowPolicy
+	Filter *EventFilter
+
+	dropped    uint64
+	overflowed bool
 }
 
-func NewACL(data string) (*ACL, error) {
-	// map{key:[]values}
-	aclTempMap := make(map[string][]string)
-	if err := json.Unmarshal([]byte(data), &aclTempMap); err != nil {
-		return nil, err
+// push queues the event without blocking, false means the subscriber
+// has to be disconnected
+func (s *Subscriber) push(notice *Notice) bool {
+	select {
+	case s.Events <- notice:
+		return true
+	default:
 	}
-
-	auth := &ACL{
-		allowed: make(map[string]ACLMethods),
+	if s.Policy == Disconnect {
+		s.overflowed = true
+		return false
 	}
 
-	for consumer, methods := range aclTempMap {
-		auth.allowed[consumer] = make([][]string, len(methods))
-		for i, method := range methods {
-			auth.allowed[consumer][i] = strings.Split(method, "/")
-		}
+	select {
+	case <-s.Events:
+		atomic.AddUint64(&s.dropped, 1)
+	default:
+	}
+	select {
+	case s.Events <- notice:
+	default:
+		atomic.AddUint64(&s.dropped, 1)
 	}
+	return true
+}
+
+// Dropped is how many events the subscriber has missed
+func (s *Subscriber) Dropped() uint64 {
+	return atomic.LoadUint64(&s.dropped)
+}
 
-	return auth, nil
+// Err tells why the queue is closed, it's valid after Events is closed
+func (s *Subscriber) Err() error {
+	if s.overflowed {
+		return status.Errorf(codes.ResourceExhausted, "subscriber is too slow, more than %d events behind", cap(s.Events))
+	}
+	return nil
 }
 
 type SubEvent struct {
 	UUID   int
-	Events map[int]chan *Event
+	Events map[int]*Subscriber
 	Mtx    *sync.Mutex
+	Log    *EventLog
+	// QueueSize and Policy are for new subscribers
+	QueueSize int
+	Policy    OverflowPolicy
+	// Stats gets notices of started and finished calls for Statistics
+	Stats *StatAggregator
+	// closed is set by RemoveAll, new subscribers get a closed queue then
+	closed bool
 }
 
-func (s *SubEvent) NewSub() (int, chan *Event) {
+// NewSub subscribes to events passing the filter
+func (s *SubEvent) NewSub(filter *EventFilter) (int, *Subscriber) {
 	s.Mtx.Lock()
 	defer s.Mtx.Unlock()
 
 	s.UUID++
-	s.Events[s.UUID] = make(chan *Event)
-	return s.UUID, s.Events[s.UUID]
+	subscriber := &Subscriber{
+		Events: make(chan *Notice, s.QueueSize),
+		Since:  s.Log.Seq(),
+		Policy: s.Policy,
+		Filter: filter,
+	}
+	if s.closed {
+		close(subscriber.Events)
+		return s.UUID, subscriber
+	}
+	s.Events[s.UUID] = subscriber
+	return s.UUID, subscriber
 }
 
 func (s *SubEvent) RemoveSub(id int) {
@@ -185,17 +363,29 @@ func (s *SubEvent) RemoveSub(id int) {
 	defer s.Mtx.Unlock()
 
 	if sub, ok := s.Events[id]; ok {
-		close(sub)
+		close(sub.Events)
 		delete(s.Events, id)
 	}
 }
 
+// Len is the number of subscribers
+func (s *SubEvent) Len() int {
+	s.Mtx.Lock()
+	defer s.Mtx.Unlock()
+
+	return len(s.Events)
+}
+
+// RemoveAll closes queues of all subscribers, the streams send what is
+// queued and finish. Nobody can subscribe after it
 func (s *SubEvent) RemoveAll() {
 	s.Mtx.Lock()
 	defer s.Mtx.Unlock()
 
-	for key, _ := range s.Events {
-		s.RemoveSub(key)
+	s.closed = true
+	for id, sub := range s.Events {
+		close(sub.Events)
+		delete(s.Events, id)
 	}
 }
 
@@ -204,65 +394,131 @@ func (sub *SubEvent) Notify(event *Event) {
 	sub.Mtx.Lock()
 	defer sub.Mtx.Unlock()
 
-	for _, subscriber := range sub.Events {
-		subscriber <- event
+	if err := sub.Log.Append(event); err != nil {
+		log.Printf("cant write event log: %v", err)
 	}
+	notice := &Notice{Event: event}
+	sub.publish(notice)
+	sub.Stats.Add(notice)
 }
 
-func NewEventSub() *SubEvent {
+// Finish tells Statistics how long the call took
+func (sub *SubEvent) Finish(event *Event, duration time.Duration) {
+	sub.Stats.Add(&Notice{Event: event, Finished: true, Duration: duration})
+}
+
+// publish must be called with Mtx locked
+func (sub *SubEvent) publish(notice *Notice) {
+	for id, subscriber := range sub.Events {
+		if !subscriber.Filter.Matches(notice.Event) {
+			continue
+		}
+		if !subscriber.push(notice) {
+			close(subscriber.Events)
+			delete(sub.Events, id)
+		}
+	}
+}
+
+func NewEventSub(eventLog *EventLog, queueSize int, policy OverflowPolicy) *SubEvent {
 	return &SubEvent{
-		Events: map[int]chan *Event{},
-		Mtx:    &sync.Mutex{},
+		Events:    map[int]*Subscriber{},
+		Mtx:       &sync.Mutex{},
+		Log:       eventLog,
+		QueueSize: queueSize,
+		Policy:    policy,
+		Stats:     NewStatAggregator(),
 	}
 }
 
 type middleware struct {
-	Options []grpc.ServerOption
-	Auth    *ACL
-	subs    *SubEvent
+	Options        []grpc.ServerOption
+	Auth           *ACLStore
+	Authenticators []Authenticator
+	Limiter        *RateLimiter
+	Metrics        *Metrics
+	subs           *SubEvent
+}
+
+// infrastructure services for standard tooling, they are open to everyone
+// and aren't logged
+func isInfrastructure(method string) bool {
+	return strings.HasPrefix(method, "/grpc.health.v1.Health/") ||
+		strings.HasPrefix(method, "/grpc.reflection.v1alpha.ServerReflection/")
 }
 
-func (m *middleware) process(ctx context.Context, method string) error {
-	md, _ := metadata.FromIncomingContext(ctx)
-	str := strings.Join(md.Get("consumer"), "")
+func (m *middleware) process(ctx context.Context, method string) (*Event, error) {
+	// не прошедший аутентификацию вызов логируется с пустым consumer
+	str, authErr := authenticate(ctx, m.Authenticators)
 	host := ""
 	if h, ok := peer.FromContext(ctx); ok {
 		host = h.Addr.String()
 	}
 	// отправка сформированного лога(Event) всем подписчикам
-	m.subs.Notify(
-		&Event{
-			Timestamp: time.Now().Unix(),
-			Consumer:  str,
-			Method:    method,
-			Host:      host,
-		},
-	)
+	event := &Event{
+		Timestamp: time.Now().Unix(),
+		Consumer:  str,
+		Method:    method,
+		Host:      host,
+	}
+	m.subs.Notify(event)
 
+	if authErr != nil {
+		return event, status.Errorf(codes.Unauthenticated, "authentication failed: %v", authErr)
+	}
 	// проверка (consumer, method) в ACL
-	if !m.Auth.IsAllowed(str, method) {
-		return status.Errorf(codes.Unauthenticated, "Inappropriate method for this consumer")
+	acl := m.Auth.Load()
+	if !acl.IsAllowed(str, method) {
+		return event, status.Errorf(codes.Unauthenticated, "Inappropriate method for this consumer")
 	}
-	return nil
+
+	// токен тратят только разрешенные вызовы
+	if ok, retryAfter := m.Limiter.Allow(acl, str, method, time.Now()); !ok {
+		grpc.SetHeader(ctx, metadata.Pairs("retry-after", strconv.FormatFloat(retryAfter.Seconds(), 'f', 3, 64)))
+		return event, status.Errorf(codes.ResourceExhausted, "rate limit exceeded, retry after %v", retryAfter.Round(time.Millisecond))
+	}
+	return event, nil
 }
 
-func (m *middleware) middlewareUnaryInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
-	if err := m.process(ctx, info.FullMethod); err != nil {
+// длительность вызова, в том числе отклоненного, уходит в статистику и метрики
+func (m *middleware) finish(event *Event, err error, duration time.Duration) {
+	m.subs.Finish(event, duration)
+	m.Metrics.Observe(event, status.Code(err), duration)
+}
+
+func (m *middleware) middlewareUnaryInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp interface{}, err error) {
+	if isInfrastructure(info.FullMethod) {
+		return handler(ctx, req)
+	}
+	start := time.Now()
+	event, err := m.process(ctx, info.FullMethod)
+	defer func() {
+		m.finish(event, err, time.Since(start))
+	}()
+	if err != nil {
 		return nil, err
 	}
 	return handler(ctx, req)
 }
 
-func (m *middleware) middlewareStreamInterceptor(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
-	if err := m.process(ss.Context(), info.FullMethod); err != nil {
+func (m *middleware) middlewareStreamInterceptor(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+	if isInfrastructure(info.FullMethod) {
+		return handler(srv, ss)
+	}
+	start := time.Now()
+	event, err := m.process(ss.Context(), info.FullMethod)
+	defer func() {
+		m.finish(event, err, time.Since(start))
+	}()
+	if err != nil {
 		return err
 	}
 	return handler(srv, ss)
 }
 
 // перехватчик запросов
-func NewMiddleware(acl *ACL, sub *SubEvent) (*middleware, error) {
-	m := &middleware{Auth: acl, subs: sub}
+func NewMiddleware(acl *ACLStore, authenticators []Authenticator, limiter *RateLimiter, metrics *Metrics, sub *SubEvent) (*middleware, error) {
+	m := &middleware{Auth: acl, Authenticators: authenticators, Limiter: limiter, Metrics: metrics, subs: sub}
 	m.Options = []grpc.ServerOption{
 		grpc.UnaryInterceptor(m.middlewareUnaryInterceptor),
 		grpc.StreamInterceptor(m.middlewareStreamInterceptor),
@@ -270,38 +526,164 @@ func NewMiddleware(acl *ACL, sub *SubEvent) (*middleware, error) {
 	return m, nil
 }
 
+// Config настройки микросервиса, нулевые поля заменяются значениями по умолчанию
+type Config struct {
+	Addr string
+	ACL  string
+	// EventLogSize сколько последних событий хранится в памяти для Logging с повтором
+	EventLogSize int
+	// EventLogFile файл, в который дописываются все события, пустой - только память
+	EventLogFile string
+	// QueueSize на сколько событий подписчик может отстать
+	QueueSize int
+	// Policy что делать с подписчиком, который отстал больше
+	Policy OverflowPolicy
+	// Authenticators определяют consumer вызова, по умолчанию он берется из метаданных как есть
+	Authenticators []Authenticator
+	// TLS включает TLS, для CertAuthenticator нужна проверка клиентских сертификатов
+	TLS *tls.Config
+	// MetricsAddr адрес HTTP, на котором отдаются метрики Prometheus (/metrics), пустой - не слушать
+	MetricsAddr string
+	// ShutdownTimeout сколько ждать завершения вызовов после отмены ctx, потом они обрываются
+	ShutdownTimeout time.Duration
+}
+
+const (
+	defaultEventLogSize    = 1000
+	defaultQueueSize       = 100
+	defaultShutdownTimeout = 5 * time.Second
+)
+
+// Microservice is a started server, it stops when the ctx of the start is done
+type Microservice struct {
+	done chan struct{}
+	err  error
+}
+
+// Done is closed when the server has stopped and released its ports
+func (m *Microservice) Done() <-chan struct{} {
+	return m.done
+}
+
+// Wait blocks till the server has stopped, the error is of the server or of the event log
+func (m *Microservice) Wait() error {
+	<-m.done
+	return m.err
+}
+
 // тут вы пишете код
 // обращаю ваше внимание - в этом задании запрещены глобальные переменные
-func StartMyMicroservice(ctx context.Context, addr, ACLData string) error {
+func StartMyMicroservice(ctx context.Context, addr, ACLData string) (*Microservice, error) {
+	return StartMicroservice(ctx, Config{Addr: addr, ACL: ACLData})
+}
+
+func StartMicroservice(ctx context.Context, cfg Config) (*Microservice, error) {
+	if cfg.EventLogSize == 0 {
+		cfg.EventLogSize = defaultEventLogSize
+	}
+	if cfg.QueueSize == 0 {
+		cfg.QueueSize = defaultQueueSize
+	}
+	if len(cfg.Authenticators) == 0 {
+		cfg.Authenticators = []Authenticator{MetadataAuthenticator{}}
+	}
+	if cfg.ShutdownTimeout == 0 {
+		cfg.ShutdownTimeout = defaultShutdownTimeout
+	}
+
 	// создание таблицы ACL
-	acl, err := NewACL(ACLData)
+	acl, err := NewACL(cfg.ACL)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	eventLog, err := NewEventLog(cfg.EventLogSize, cfg.EventLogFile)
+	if err != nil {
+		return nil, err
 	}
 	//net.Listener
-	lis, err := net.Listen("tcp", addr)
+	lis, err := net.Listen("tcp", cfg.Addr)
 	if err != nil {
-		return err
+		eventLog.Close()
+		return nil, err
 	}
 	// написание middleware , в который передаётся ACL таблица, который обрабатывает события до вызова
-	sub := NewEventSub()
-	mid, err := NewMiddleware(acl, sub)
+	sub := NewEventSub(eventLog, cfg.QueueSize, cfg.Policy)
+	aclStore := NewACLStore(acl)
+	limiter := NewRateLimiter()
+	metrics := NewMetrics(sub)
+	mid, err := NewMiddleware(aclStore, cfg.Authenticators, limiter, metrics, sub)
 	if mid == nil || err != nil {
-		return err
+		return nil, err
+	}
+
+	var metricsServer *http.Server
+	if cfg.MetricsAddr != "" {
+		metricsLis, err := net.Listen("tcp", cfg.MetricsAddr)
+		if err != nil {
+			lis.Close()
+			eventLog.Close()
+			return nil, err
+		}
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", metrics)
+		metricsServer = &http.Server{Handler: mux}
+		go metricsServer.Serve(metricsLis)
 	}
 
 	// передача опций из middleware в параметры сервера
 
 	// mid в параметрах
-	server := grpc.NewServer(mid.Options...)
+	options := mid.Options
+	if cfg.TLS != nil {
+		options = append(options, grpc.Creds(credentials.NewTLS(cfg.TLS)))
+	}
+	server := grpc.NewServer(options...)
 
-	RegisterAdminServer(server, NewAdminServer(sub))
+	RegisterAdminServer(server, NewAdminServer(sub, aclStore, limiter))
 	RegisterBizServer(server, NewBizServer())
 
-	go server.Serve(lis)
+	// health и reflection для стандартных инструментов (grpc_health_probe, grpcurl)
+	healthServer := health.NewServer()
+	healthServer.SetServingStatus("main.Admin", healthpb.HealthCheckResponse_SERVING)
+	healthServer.SetServingStatus("main.Biz", healthpb.HealthCheckResponse_SERVING)
+	healthpb.RegisterHealthServer(server, healthServer)
+	reflection.Register(server)
+
+	ms := &Microservice{done: make(chan struct{})}
+	served := make(chan error, 1)
+	go sub.Stats.Run(ctx)
+	go func() {
+		served <- server.Serve(lis)
+	}()
 	go func() {
 		<-ctx.Done()
-		server.GracefulStop()
+		healthServer.Shutdown()
+
+		// потоки Admin дописывают очереди и завершаются, остальные вызовы
+		// доделываются, пока не выйдет ShutdownTimeout
+		sub.RemoveAll()
+		stopped := make(chan struct{})
+		go func() {
+			server.GracefulStop()
+			close(stopped)
+		}()
+		timer := time.NewTimer(cfg.ShutdownTimeout)
+		select {
+		case <-stopped:
+			timer.Stop()
+		case <-timer.C:
+			server.Stop()
+			<-stopped
+		}
+
+		if metricsServer != nil {
+			metricsServer.Close()
+		}
+		ms.err = <-served
+		if err := eventLog.Close(); ms.err == nil {
+			ms.err = err
+		}
+		close(ms.done)
 	}()
-	return nil
-}
\ No newline at end of file
+	return ms, nil
+}
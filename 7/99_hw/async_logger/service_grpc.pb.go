@@ -0,0 +1,390 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.2.0
+// - protoc             (unknown)
+// source: service.proto
+
+package main
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+// AdminClient is the client API for Admin service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type AdminClient interface {
+	Logging(ctx context.Context, in *LoggingRequest, opts ...grpc.CallOption) (Admin_LoggingClient, error)
+	Statistics(ctx context.Context, in *StatInterval, opts ...grpc.CallOption) (Admin_StatisticsClient, error)
+	UpdateACL(ctx context.Context, in *ACLUpdate, opts ...grpc.CallOption) (*Nothing, error)
+}
+
+type adminClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewAdminClient(cc grpc.ClientConnInterface) AdminClient {
+	return &adminClient{cc}
+}
+
+func (c *adminClient) Logging(ctx context.Context, in *LoggingRequest, opts ...grpc.CallOption) (Admin_LoggingClient, error) {
+	stream, err := c.cc.NewStream(ctx, &Admin_ServiceDesc.Streams[0], "/main.Admin/Logging", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &adminLoggingClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Admin_LoggingClient interface {
+	Recv() (*Event, error)
+	grpc.ClientStream
+}
+
+type adminLoggingClient struct {
+	grpc.ClientStream
+}
+
+func (x *adminLoggingClient) Recv() (*Event, error) {
+	m := new(Event)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *adminClient) Statistics(ctx context.Context, in *StatInterval, opts ...grpc.CallOption) (Admin_StatisticsClient, error) {
+	stream, err := c.cc.NewStream(ctx, &Admin_ServiceDesc.Streams[1], "/main.Admin/Statistics", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &adminStatisticsClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Admin_StatisticsClient interface {
+	Recv() (*Stat, error)
+	grpc.ClientStream
+}
+
+type adminStatisticsClient struct {
+	grpc.ClientStream
+}
+
+func (x *adminStatisticsClient) Recv() (*Stat, error) {
+	m := new(Stat)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *adminClient) UpdateACL(ctx context.Context, in *ACLUpdate, opts ...grpc.CallOption) (*Nothing, error) {
+	out := new(Nothing)
+	err := c.cc.Invoke(ctx, "/main.Admin/UpdateACL", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// AdminServer is the server API for Admin service.
+// All implementations must embed UnimplementedAdminServer
+// for forward compatibility
+type AdminServer interface {
+	Logging(*LoggingRequest, Admin_LoggingServer) error
+	Statistics(*StatInterval, Admin_StatisticsServer) error
+	UpdateACL(context.Context, *ACLUpdate) (*Nothing, error)
+	mustEmbedUnimplementedAdminServer()
+}
+
+// UnimplementedAdminServer must be embedded to have forward compatible implementations.
+type UnimplementedAdminServer struct {
+}
+
+func (UnimplementedAdminServer) Logging(*LoggingRequest, Admin_LoggingServer) error {
+	return status.Errorf(codes.Unimplemented, "method Logging not implemented")
+}
+func (UnimplementedAdminServer) Statistics(*StatInterval, Admin_StatisticsServer) error {
+	return status.Errorf(codes.Unimplemented, "method Statistics not implemented")
+}
+func (UnimplementedAdminServer) UpdateACL(context.Context, *ACLUpdate) (*Nothing, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UpdateACL not implemented")
+}
+func (UnimplementedAdminServer) mustEmbedUnimplementedAdminServer() {}
+
+// UnsafeAdminServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to AdminServer will
+// result in compilation errors.
+type UnsafeAdminServer interface {
+	mustEmbedUnimplementedAdminServer()
+}
+
+func RegisterAdminServer(s grpc.ServiceRegistrar, srv AdminServer) {
+	s.RegisterService(&Admin_ServiceDesc, srv)
+}
+
+func _Admin_Logging_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(LoggingRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(AdminServer).Logging(m, &adminLoggingServer{stream})
+}
+
+type Admin_LoggingServer interface {
+	Send(*Event) error
+	grpc.ServerStream
+}
+
+type adminLoggingServer struct {
+	grpc.ServerStream
+}
+
+func (x *adminLoggingServer) Send(m *Event) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _Admin_Statistics_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(StatInterval)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(AdminServer).Statistics(m, &adminStatisticsServer{stream})
+}
+
+type Admin_StatisticsServer interface {
+	Send(*Stat) error
+	grpc.ServerStream
+}
+
+type adminStatisticsServer struct {
+	grpc.ServerStream
+}
+
+func (x *adminStatisticsServer) Send(m *Stat) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _Admin_UpdateACL_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ACLUpdate)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServer).UpdateACL(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/main.Admin/UpdateACL",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServer).UpdateACL(ctx, req.(*ACLUpdate))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// Admin_ServiceDesc is the grpc.ServiceDesc for Admin service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Admin_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "main.Admin",
+	HandlerType: (*AdminServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "UpdateACL",
+			Handler:    _Admin_UpdateACL_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Logging",
+			Handler:       _Admin_Logging_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "Statistics",
+			Handler:       _Admin_Statistics_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "service.proto",
+}
+
+// BizClient is the client API for Biz service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type BizClient interface {
+	Check(ctx context.Context, in *Nothing, opts ...grpc.CallOption) (*Nothing, error)
+	Add(ctx context.Context, in *Nothing, opts ...grpc.CallOption) (*Nothing, error)
+	Test(ctx context.Context, in *Nothing, opts ...grpc.CallOption) (*Nothing, error)
+}
+
+type bizClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewBizClient(cc grpc.ClientConnInterface) BizClient {
+	return &bizClient{cc}
+}
+
+func (c *bizClient) Check(ctx context.Context, in *Nothing, opts ...grpc.CallOption) (*Nothing, error) {
+	out := new(Nothing)
+	err := c.cc.Invoke(ctx, "/main.Biz/Check", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *bizClient) Add(ctx context.Context, in *Nothing, opts ...grpc.CallOption) (*Nothing, error) {
+	out := new(Nothing)
+	err := c.cc.Invoke(ctx, "/main.Biz/Add", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *bizClient) Test(ctx context.Context, in *Nothing, opts ...grpc.CallOption) (*Nothing, error) {
+	out := new(Nothing)
+	err := c.cc.Invoke(ctx, "/main.Biz/Test", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// BizServer is the server API for Biz service.
+// All implementations must embed UnimplementedBizServer
+// for forward compatibility
+type BizServer interface {
+	Check(context.Context, *Nothing) (*Nothing, error)
+	Add(context.Context, *Nothing) (*Nothing, error)
+	Test(context.Context, *Nothing) (*Nothing, error)
+	mustEmbedUnimplementedBizServer()
+}
+
+// UnimplementedBizServer must be embedded to have forward compatible implementations.
+type UnimplementedBizServer struct {
+}
+
+func (UnimplementedBizServer) Check(context.Context, *Nothing) (*Nothing, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Check not implemented")
+}
+func (UnimplementedBizServer) Add(context.Context, *Nothing) (*Nothing, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Add not implemented")
+}
+func (UnimplementedBizServer) Test(context.Context, *Nothing) (*Nothing, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Test not implemented")
+}
+func (UnimplementedBizServer) mustEmbedUnimplementedBizServer() {}
+
+// UnsafeBizServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to BizServer will
+// result in compilation errors.
+type UnsafeBizServer interface {
+	mustEmbedUnimplementedBizServer()
+}
+
+func RegisterBizServer(s grpc.ServiceRegistrar, srv BizServer) {
+	s.RegisterService(&Biz_ServiceDesc, srv)
+}
+
+func _Biz_Check_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Nothing)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BizServer).Check(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/main.Biz/Check",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BizServer).Check(ctx, req.(*Nothing))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Biz_Add_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Nothing)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BizServer).Add(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/main.Biz/Add",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BizServer).Add(ctx, req.(*Nothing))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Biz_Test_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Nothing)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BizServer).Test(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/main.Biz/Test",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BizServer).Test(ctx, req.(*Nothing))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// Biz_ServiceDesc is the grpc.ServiceDesc for Biz service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Biz_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "main.Biz",
+	HandlerType: (*BizServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Check",
+			Handler:    _Biz_Check_Handler,
+		},
+		{
+			MethodName: "Add",
+			Handler:    _Biz_Add_Handler,
+		},
+		{
+			MethodName: "Test",
+			Handler:    _Biz_Test_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "service.proto",
+}
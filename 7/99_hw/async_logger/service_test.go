@@ -72,23 +72,23 @@ func getConsumerCtxWithCancel(consumerName string) (context.Context, context.Can
 // старт-стоп сервера
 func TestServerStartStop(t *testing.T) {
 	ctx, finish := context.WithCancel(context.Background())
-	err := StartMyMicroservice(ctx, listenAddr, ACLData)
+	srv, err := StartMyMicroservice(ctx, listenAddr, ACLData)
 	if err != nil {
 		t.Fatalf("cant start server initial: %v", err)
 	}
 	wait(1)
 	finish() // при вызове этой функции ваш сервер должен остановиться и освободить порт
-	wait(1)
+	srv.Wait()
 
 	// теперь проверим что вы освободили порт и мы можем стартовать сервер ещё раз
 	ctx, finish = context.WithCancel(context.Background())
-	err = StartMyMicroservice(ctx, listenAddr, ACLData)
+	srv, err = StartMyMicroservice(ctx, listenAddr, ACLData)
 	if err != nil {
 		t.Fatalf("cant start server again: %v", err)
 	}
 	wait(1)
 	finish()
-	wait(1)
+	srv.Wait()
 }
 
 // у вас наверняка будет что-то выполняться в отдельных горутинах
@@ -115,7 +115,7 @@ func TestServerLeak(t *testing.T) {
 // ACL (права на методы доступа) парсится корректно
 func TestACLParseError(t *testing.T) {
 	// finish'а тут нет потому что стартовать у вас ничего не должно если не получилось распаковать ACL
-	err := StartMyMicroservice(context.Background(), listenAddr, "{.;")
+	_, err := StartMyMicroservice(context.Background(), listenAddr, "{.;")
 	if err == nil {
 		t.Fatalf("expacted error on bad acl json, have nil")
 	}
@@ -125,14 +125,14 @@ func TestACLParseError(t *testing.T) {
 func TestACL(t *testing.T) {
 	wait(1)
 	ctx, finish := context.WithCancel(context.Background())
-	err := StartMyMicroservice(ctx, listenAddr, ACLData)
+	srv, err := StartMyMicroservice(ctx, listenAddr, ACLData)
 	if err != nil {
 		t.Fatalf("cant start server initial: %v", err)
 	}
 	wait(1)
 	defer func() {
 		finish()
-		wait(1)
+		srv.Wait()
 	}()
 
 	conn := getGrpcConn(t)
@@ -169,7 +169,7 @@ func TestACL(t *testing.T) {
 	}
 
 	// ACL на методах, которые возвращают поток данных
-	logger, err := adm.Logging(getConsumerCtx("unknown"), &Nothing{})
+	logger, err := adm.Logging(getConsumerCtx("unknown"), &LoggingRequest{})
 	_, err = logger.Recv()
 	if err == nil {
 		t.Fatalf("ACL fail: expected err on disallowed method")
@@ -180,14 +180,14 @@ func TestACL(t *testing.T) {
 
 func TestLogging(t *testing.T) {
 	ctx, finish := context.WithCancel(context.Background())
-	err := StartMyMicroservice(ctx, listenAddr, ACLData)
+	srv, err := StartMyMicroservice(ctx, listenAddr, ACLData)
 	if err != nil {
 		t.Fatalf("cant start server initial: %v", err)
 	}
 	wait(1)
 	defer func() {
 		finish()
-		wait(1)
+		srv.Wait()
 	}()
 
 	conn := getGrpcConn(t)
@@ -196,10 +196,10 @@ func TestLogging(t *testing.T) {
 	biz := NewBizClient(conn)
 	adm := NewAdminClient(conn)
 
-	logStream1, err := adm.Logging(getConsumerCtx("logger1"), &Nothing{})
+	logStream1, err := adm.Logging(getConsumerCtx("logger1"), &LoggingRequest{})
 	time.Sleep(1 * time.Millisecond)
 
-	logStream2, err := adm.Logging(getConsumerCtx("logger2"), &Nothing{})
+	logStream2, err := adm.Logging(getConsumerCtx("logger2"), &LoggingRequest{})
 
 	logData1 := []*Event{}
 	logData2 := []*Event{}
@@ -291,14 +291,14 @@ func TestLogging(t *testing.T) {
 
 func TestStat(t *testing.T) {
 	ctx, finish := context.WithCancel(context.Background())
-	err := StartMyMicroservice(ctx, listenAddr, ACLData)
+	srv, err := StartMyMicroservice(ctx, listenAddr, ACLData)
 	if err != nil {
 		t.Fatalf("cant start server initial: %v", err)
 	}
 	wait(1)
 	defer func() {
 		finish()
-		wait(2)
+		srv.Wait()
 	}()
 
 	conn := getGrpcConn(t)
@@ -430,14 +430,14 @@ func TestStat(t *testing.T) {
 // see comments marked CHANGED
 func TestWorkAfterDisconnect(t *testing.T) {
 	ctx, finish := context.WithCancel(context.Background())
-	err := StartMyMicroservice(ctx, listenAddr, ACLData)
+	srv, err := StartMyMicroservice(ctx, listenAddr, ACLData)
 	if err != nil {
 		t.Fatalf("cant start server initial: %v", err)
 	}
 	wait(1)
 	defer func() {
 		finish()
-		wait(1)
+		srv.Wait()
 	}()
 
 	conn := getGrpcConn(t)
@@ -447,10 +447,10 @@ func TestWorkAfterDisconnect(t *testing.T) {
 	adm := NewAdminClient(conn)
 
 	ctx1, cancel1 := getConsumerCtxWithCancel("logger1")
-	logStream1, err := adm.Logging(ctx1, &Nothing{})
+	logStream1, err := adm.Logging(ctx1, &LoggingRequest{})
 	time.Sleep(1 * time.Millisecond)
 
-	logStream2, err := adm.Logging(getConsumerCtx("logger2"), &Nothing{})
+	logStream2, err := adm.Logging(getConsumerCtx("logger2"), &LoggingRequest{})
 
 	logData1 := []*Event{}
 	logData2 := []*Event{}
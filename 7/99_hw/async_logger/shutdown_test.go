@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+)
+
+func TestRemoveAll(t *testing.T) {
+	eventLog, err := NewEventLog(10, "")
+	if err != nil {
+		t.Fatalf("cant create event log: %v", err)
+	}
+	subs := NewEventSub(eventLog, 2, DropOldest)
+	_, sub1 := subs.NewSub(nil)
+	_, sub2 := subs.NewSub(nil)
+	subs.Notify(&Event{Method: "/main.Biz/Check"})
+
+	subs.RemoveAll()
+	for i, sub := range []*Subscriber{sub1, sub2} {
+		// очередь дочитывается до конца
+		if notice, ok := <-sub.Events; !ok || notice.Event.Method != "/main.Biz/Check" {
+			t.Fatalf("[%d] expected the queued event", i)
+		}
+		if _, ok := <-sub.Events; ok {
+			t.Fatalf("[%d] expected closed queue", i)
+		}
+	}
+	if subs.Len() != 0 {
+		t.Fatalf("expected no subscribers, got %d", subs.Len())
+	}
+	if _, sub := subs.NewSub(nil); sub != nil {
+		if _, ok := <-sub.Events; ok {
+			t.Fatalf("expected closed queue after RemoveAll")
+		}
+	}
+}
+
+// после отмены ctx потоки Admin получают то, что успели поставить в очередь, и io.EOF
+func TestGracefulShutdown(t *testing.T) {
+	ctx, finish := context.WithCancel(context.Background())
+	srv, err := StartMyMicroservice(ctx, listenAddr, ACLData)
+	if err != nil {
+		t.Fatalf("cant start server initial: %v", err)
+	}
+	wait(1)
+
+	conn := getGrpcConn(t)
+	defer conn.Close()
+
+	biz := NewBizClient(conn)
+	adm := NewAdminClient(conn)
+
+	logStream, err := adm.Logging(getConsumerCtx("logger1"), &LoggingRequest{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	wait(1)
+	statStream, err := adm.Statistics(getConsumerCtx("stat1"), &StatInterval{IntervalSeconds: 60})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	wait(1)
+
+	biz.Check(getConsumerCtx("biz_user"), &Nothing{})
+	biz.Add(getConsumerCtx("biz_user"), &Nothing{})
+	finish()
+
+	for _, method := range []string{"/main.Admin/Statistics", "/main.Biz/Check", "/main.Biz/Add"} {
+		evt, err := logStream.Recv()
+		if err != nil {
+			t.Fatalf("unexpected error: %v, awaiting %s", err, method)
+		}
+		if evt.Method != method {
+			t.Fatalf("expected %s event, got %s", method, evt.Method)
+		}
+	}
+	if _, err := logStream.Recv(); err != io.EOF {
+		t.Fatalf("expected io.EOF on Logging, got %v", err)
+	}
+	if _, err := statStream.Recv(); err != io.EOF {
+		t.Fatalf("expected io.EOF on Statistics, got %v", err)
+	}
+
+	select {
+	case <-srv.Done():
+	case <-time.After(time.Second):
+		t.Fatalf("server hasn't stopped")
+	}
+	if err := srv.Wait(); err != nil {
+		t.Fatalf("unexpected error on stop: %v", err)
+	}
+
+	// порт свободен сразу после Wait
+	ctx, finish = context.WithCancel(context.Background())
+	srv, err = StartMyMicroservice(ctx, listenAddr, ACLData)
+	if err != nil {
+		t.Fatalf("cant start server again: %v", err)
+	}
+	finish()
+	srv.Wait()
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// DecodeError ошибка в значении по пути Path, например Users[3].Address.Zip.
+// Если тип данных не подходит, заполнены Expected и Actual, иначе причина в Err
+type DecodeError struct {
+	Path     string
+	Expected reflect.Type
+	// Actual тип данных, nil для null
+	Actual reflect.Type
+	Err    error
+}
+
+func (e *DecodeError) Error() string {
+	msg := ""
+	if e.Err != nil {
+		msg = e.Err.Error()
+	} else {
+		msg = fmt.Sprintf("expected %v, got %v", e.Expected, typeName(e.Actual))
+	}
+	if e.Path == "" {
+		return msg
+	}
+	return e.Path + ": " + msg
+}
+
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
+
+func typeName(t reflect.Type) string {
+	if t == nil {
+		return "null"
+	}
+	return t.String()
+}
+
+// DecodeErrors все ошибки распаковки с Options.CollectErrors
+type DecodeErrors []*DecodeError
+
+func (e DecodeErrors) Error() string {
+	if len(e) == 1 {
+		return e[0].Error()
+	}
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return strconv.Itoa(len(e)) + " errors: " + strings.Join(msgs, "; ")
+}
+
+func (e DecodeErrors) Unwrap() []error {
+	errs := make([]error, len(e))
+	for i, err := range e {
+		errs[i] = err
+	}
+	return errs
+}
+
+// pathSegment часть пути до значения: поле структуры, индекс слайса или ключ map
+type pathSegment struct {
+	field string
+	index int
+	key   reflect.Value
+}
+
+// valuePath путь до текущего значения, работает как стек: сегмент
+// добавляется при спуске и убирается при возврате, строка собирается только
+// для ошибки
+type valuePath []pathSegment
+
+func (p valuePath) String() string {
+	var b strings.Builder
+	for _, s := range p {
+		switch {
+		case s.key.IsValid():
+			fmt.Fprintf(&b, "[%v]", s.key)
+		case s.field != "":
+			if b.Len() > 0 {
+				b.WriteByte('.')
+			}
+			b.WriteString(s.field)
+		default:
+			b.WriteString("[" + strconv.Itoa(s.index) + "]")
+		}
+	}
+	return b.String()
+}
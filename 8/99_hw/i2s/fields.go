@@ -0,0 +1,137 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// fieldCache reflect.Type -> []field, поля типа собираются один раз
+var fieldCache sync.Map
+
+func cachedFields(t reflect.Type) []field {
+	if cached, ok := fieldCache.Load(t); ok {
+		return cached.([]field)
+	}
+	// гонка тут не страшна, оба варианта одинаковые
+	cached, _ := fieldCache.LoadOrStore(t, fields(t))
+	return cached.([]field)
+}
+
+// field поле структуры с путем до него через встроенные структуры
+type field struct {
+	name      string
+	index     []int
+	omitEmpty bool
+}
+
+// fields собирает поля структуры, поля встроенных структур без тега
+// поднимаются наверх, если снаружи нет поля с тем же именем
+func fields(t reflect.Type) []field {
+	var result []field
+	seen := map[string]bool{}
+	// visited типы, уже обойденные на меньшей вложенности: их поля все равно
+	// перекрыты, а без этой проверки type Node struct{ *Node } обходился бы
+	// бесконечно. Так же делает typeFields в encoding/json
+	visited := map[reflect.Type]bool{}
+
+	// обход в ширину: чем меньше вложенность, тем важнее поле
+	level := []field{{index: nil}}
+	types := []reflect.Type{t}
+	for len(level) > 0 {
+		var nextLevel []field
+		var nextTypes []reflect.Type
+		names := map[string]int{}
+		var found []field
+
+		for i, parent := range level {
+			st := types[i]
+			if visited[st] {
+				continue
+			}
+			for j := 0; j < st.NumField(); j++ {
+				f := st.Field(j)
+				tag := f.Tag.Get("json")
+				if tag == "-" {
+					continue
+				}
+				options := strings.Split(tag, ",")
+				name := options[0]
+				index := append(append([]int{}, parent.index...), j)
+
+				ft := f.Type
+				if ft.Kind() == reflect.Ptr {
+					ft = ft.Elem()
+				}
+				if f.Anonymous && name == "" && ft.Kind() == reflect.Struct {
+					// в неэкспортируемый указатель не записать
+					if f.Type.Kind() == reflect.Ptr && f.PkgPath != "" {
+						continue
+					}
+					nextLevel = append(nextLevel, field{index: index})
+					nextTypes = append(nextTypes, ft)
+					continue
+				}
+				if f.PkgPath != "" {
+					continue
+				}
+				if name == "" {
+					name = f.Name
+				}
+				omitEmpty := false
+				for _, option := range options[1:] {
+					omitEmpty = omitEmpty || option == "omitempty"
+				}
+				names[name]++
+				found = append(found, field{name: name, index: index, omitEmpty: omitEmpty})
+			}
+		}
+
+		// одинаковые имена на одном уровне неоднозначны, их пропускаем
+		for _, f := range found {
+			if !seen[f.name] && names[f.name] == 1 {
+				result = append(result, f)
+			}
+		}
+		for name := range names {
+			seen[name] = true
+		}
+		// помечаем после уровня, чтобы тип, встроенный дважды на одной
+		// глубине, дал неоднозначные поля, а не одно из них
+		for _, st := range types {
+			visited[st] = true
+		}
+		level, types = nextLevel, nextTypes
+	}
+	return result
+}
+
+// fieldByIndex как reflect.Value.FieldByIndex, но создает встроенные
+// структуры по nil указателям
+func fieldByIndex(v reflect.Value, index []int) reflect.Value {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v
+}
+
+// fieldByIndexRead как fieldByIndex, но на nil встроенной структуре
+// возвращает false, а не создает ее
+func fieldByIndexRead(v reflect.Value, index []int) (reflect.Value, bool) {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				return reflect.Value{}, false
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v, true
+}
@@ -1,66 +1,479 @@
 package main
 
 import (
+	"encoding"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"math"
 	"reflect"
+	"sort"
+	"strconv"
+	"strings"
 )
 
+// i2s заполняет out данными, которые получаются при распаковке json в
+// interface{}: map[string]interface{}, []interface{}, float64, string, bool
+// и nil. Числа также принимаются любых числовых типов Go и json.Number.
+//
+// Поля структуры ищутся по тегу json (`json:"name"`, "-" пропускает поле,
+// omitempty ни на что не влияет), без тега - по имени поля, сначала точно,
+// потом без учета регистра. Поля встроенных структур без тега считаются
+// полями внешней. Поля, которых нет в данных, не меняются.
+//
+// Тип, реализующий encoding.TextUnmarshaler, распаковывается из строки.
+//
+// Ошибки в данных возвращаются как *DecodeError с путем до значения.
 func i2s(data interface{}, out interface{}) error {
+	return i2sWithOptions(data, out, Options{})
+}
+
+// Options меняют строгость i2sWithOptions
+type Options struct {
+	// ErrorUnused - ошибка на ключи, которым нет поля в структуре
+	ErrorUnused bool
+	// CollectErrors - не останавливаться на первой ошибке, а вернуть все
+	// сразу в DecodeErrors
+	CollectErrors bool
+	// WeaklyTyped приводит типы: строки к числам и bool ("42" в int, "true"
+	// в bool), числа и bool к строкам, bool к числам (1 и 0), числа к bool
+	// (не 0 - true), одно значение к слайсу из него
+	WeaklyTyped bool
+}
+
+func i2sWithOptions(data interface{}, out interface{}, opts Options) error {
 	outValue := reflect.ValueOf(out)
 	if outValue.Kind() != reflect.Ptr {
 		return errors.New("data is not a pointer")
 	}
-	outValue = outValue.Elem()
+	if outValue.IsNil() {
+		return errors.New("out is a nil pointer")
+	}
+
+	d := &decoder{opts: opts}
+	if err := d.decode(data, outValue.Elem()); err != nil {
+		return err
+	}
+	if len(d.errs) > 0 {
+		return d.errs
+	}
+	return nil
+}
+
+var textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+
+// errMismatch - тип данных не подходит, decode превращает ее в DecodeError
+// с Expected и Actual
+var errMismatch = errors.New("type mismatch")
+
+type decoder struct {
+	opts Options
+	errs DecodeErrors
+	// path до текущего значения, decode дописывает и убирает сегменты
+	path valuePath
+}
+
+// fail дописывает путь к ошибке и запоминает ее, если собираются все
+// ошибки, иначе возвращает ее
+func (d *decoder) fail(err *DecodeError) error {
+	err.Path = d.path.String()
+	if d.opts.CollectErrors {
+		d.errs = append(d.errs, err)
+		return nil
+	}
+	return err
+}
+
+func (d *decoder) decode(data interface{}, out reflect.Value) error {
+	// null обнуляет значение, как в encoding/json
+	if data == nil {
+		out.Set(reflect.Zero(out.Type()))
+		return nil
+	}
 
-	switch outValue.Kind() {
-	case reflect.Int:
-		d, ok := data.(float64)
+	var err error
+	switch {
+	case out.Kind() != reflect.Ptr && out.CanAddr() && out.Addr().Type().Implements(textUnmarshalerType):
+		text, ok := data.(string)
 		if !ok {
-			return errors.New("data is not a float64")
+			err = errMismatch
+			break
 		}
-		outValue.SetInt(int64(d))
-	case reflect.String:
-		d, ok := data.(string)
-		if !ok {
-			return errors.New("data is not a string")
+		err = out.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(text))
+	case out.Kind() == reflect.Ptr:
+		if out.IsNil() {
+			out.Set(reflect.New(out.Type().Elem()))
+		}
+		return d.decode(data, out.Elem())
+	case out.Kind() == reflect.Slice:
+		return d.decodeSlice(data, out)
+	case out.Kind() == reflect.Map:
+		return d.decodeMap(data, out)
+	case out.Kind() == reflect.Struct:
+		return d.decodeStruct(data, out)
+	default:
+		err = d.decodeScalar(data, out)
+	}
+
+	if err == errMismatch {
+		return d.fail(&DecodeError{Expected: out.Type(), Actual: reflect.TypeOf(data)})
+	}
+	if err != nil {
+		return d.fail(&DecodeError{Err: err})
+	}
+	return nil
+}
+
+func (d *decoder) decodeScalar(data interface{}, out reflect.Value) error {
+	switch out.Kind() {
+	case reflect.Interface:
+		value := reflect.ValueOf(data)
+		if !value.Type().AssignableTo(out.Type()) {
+			return errMismatch
 		}
-		outValue.SetString(d)
-		fmt.Print(outValue)
+		out.Set(value)
 	case reflect.Bool:
-		d, ok := data.(bool)
-		if !ok {
-			return errors.New("data is not a bool")
+		b, err := d.toBool(data)
+		if err != nil {
+			return err
 		}
-		outValue.SetBool(d)
-	case reflect.Slice:
-		d, ok := data.([]interface{})
-		if !ok {
-			return errors.New("data is not a []interface{}")
+		out.SetBool(b)
+	case reflect.String:
+		s, err := d.toString(data)
+		if err != nil {
+			return err
 		}
-		slice := reflect.MakeSlice(outValue.Type(), len(d), len(d))
-		for i := 0; i < len(d); i++ {
-			o := reflect.New(outValue.Type().Elem())
-			if err := i2s(d[i], o.Interface()); err != nil {
-				return fmt.Errorf("Error converting data to slice, %v", err)
+		out.SetString(s)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := d.toNumber(data)
+		if err != nil {
+			return err
+		}
+		return setInt(n, out)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		n, err := d.toNumber(data)
+		if err != nil {
+			return err
+		}
+		return setUint(n, out)
+	case reflect.Float32, reflect.Float64:
+		n, err := d.toNumber(data)
+		if err != nil {
+			return err
+		}
+		return setFloat(n, out)
+	default:
+		return fmt.Errorf("unsupported type %v", out.Type())
+	}
+	return nil
+}
+
+func (d *decoder) toBool(data interface{}) (bool, error) {
+	if b, ok := data.(bool); ok {
+		return b, nil
+	}
+	if !d.opts.WeaklyTyped {
+		return false, errMismatch
+	}
+	if s, ok := data.(string); ok {
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return false, fmt.Errorf("cannot parse %q as bool", s)
+		}
+		return b, nil
+	}
+	n, err := toNumber(data)
+	if err != nil {
+		return false, err
+	}
+	return n.i != 0 || n.u != 0 || n.f != 0, nil
+}
+
+func (d *decoder) toString(data interface{}) (string, error) {
+	if s, ok := data.(string); ok {
+		return s, nil
+	}
+	if !d.opts.WeaklyTyped {
+		return "", errMismatch
+	}
+	if b, ok := data.(bool); ok {
+		return strconv.FormatBool(b), nil
+	}
+	n, err := toNumber(data)
+	if err != nil {
+		return "", err
+	}
+	switch {
+	case n.isFloat:
+		return strconv.FormatFloat(n.f, 'f', -1, 64), nil
+	case n.kind == reflect.Uint64:
+		return strconv.FormatUint(n.u, 10), nil
+	default:
+		return strconv.FormatInt(n.i, 10), nil
+	}
+}
+
+func (d *decoder) toNumber(data interface{}) (number, error) {
+	if d.opts.WeaklyTyped {
+		switch v := data.(type) {
+		case string:
+			n, err := toNumber(json.Number(v))
+			if err != nil {
+				return number{}, fmt.Errorf("cannot parse %q as a number", v)
+			}
+			return n, nil
+		case bool:
+			if v {
+				return number{i: 1, kind: reflect.Int64}, nil
 			}
-			slice.Index(i).Set(o.Elem())
+			return number{kind: reflect.Int64}, nil
 		}
-		outValue.Set(slice)
-	case reflect.Struct:
-		d, ok := data.(map[string]interface{})
-		if !ok {
-			return errors.New("data is not a map[string]interface{}")
+	}
+	return toNumber(data)
+}
+
+// number разбирает любое число из данных: целые возвращаются в i или u, а
+// дробные в f с isFloat
+type number struct {
+	i       int64
+	u       uint64
+	f       float64
+	kind    reflect.Kind
+	isFloat bool
+}
+
+func toNumber(data interface{}) (number, error) {
+	if n, ok := data.(json.Number); ok {
+		if i, err := strconv.ParseInt(string(n), 10, 64); err == nil {
+			return number{i: i, kind: reflect.Int64}, nil
 		}
-		for i := 0; i < outValue.NumField(); i++ {
-			f := outValue.Type().Field(i)
-			v, ok := d[f.Name]
-			if !ok {
-				return errors.New(fmt.Sprintf("Field %v not found in data", f.Name))
+		if u, err := strconv.ParseUint(string(n), 10, 64); err == nil {
+			return number{u: u, kind: reflect.Uint64}, nil
+		}
+		f, err := strconv.ParseFloat(string(n), 64)
+		if err != nil {
+			return number{}, fmt.Errorf("bad number %q", string(n))
+		}
+		return number{f: f, isFloat: true}, nil
+	}
+
+	value := reflect.ValueOf(data)
+	switch value.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return number{i: value.Int(), kind: reflect.Int64}, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return number{u: value.Uint(), kind: reflect.Uint64}, nil
+	case reflect.Float32, reflect.Float64:
+		return number{f: value.Float(), isFloat: true}, nil
+	}
+	return number{}, errMismatch
+}
+
+func setInt(n number, out reflect.Value) error {
+	var i int64
+	switch {
+	case n.isFloat:
+		// 2^63 уже не влезает в int64, а -2^63 влезает
+		if n.f != math.Trunc(n.f) || n.f < math.MinInt64 || n.f >= math.MaxInt64 {
+			return fmt.Errorf("%v doesn't fit %v", n.f, out.Type())
+		}
+		i = int64(n.f)
+	case n.kind == reflect.Uint64:
+		if n.u > math.MaxInt64 {
+			return fmt.Errorf("%v overflows %v", n.u, out.Type())
+		}
+		i = int64(n.u)
+	default:
+		i = n.i
+	}
+	if out.OverflowInt(i) {
+		return fmt.Errorf("%v overflows %v", i, out.Type())
+	}
+	out.SetInt(i)
+	return nil
+}
+
+func setUint(n number, out reflect.Value) error {
+	var u uint64
+	switch {
+	case n.isFloat:
+		if n.f != math.Trunc(n.f) || n.f < 0 || n.f >= math.MaxUint64 {
+			return fmt.Errorf("%v doesn't fit %v", n.f, out.Type())
+		}
+		u = uint64(n.f)
+	case n.kind == reflect.Int64:
+		if n.i < 0 {
+			return fmt.Errorf("%v overflows %v", n.i, out.Type())
+		}
+		u = uint64(n.i)
+	default:
+		u = n.u
+	}
+	if out.OverflowUint(u) {
+		return fmt.Errorf("%v overflows %v", u, out.Type())
+	}
+	out.SetUint(u)
+	return nil
+}
+
+func setFloat(n number, out reflect.Value) error {
+	f := n.f
+	switch {
+	case n.isFloat:
+	case n.kind == reflect.Uint64:
+		f = float64(n.u)
+	default:
+		f = float64(n.i)
+	}
+	if out.OverflowFloat(f) {
+		return fmt.Errorf("%v overflows %v", f, out.Type())
+	}
+	out.SetFloat(f)
+	return nil
+}
+
+// decodeNested распаковывает поле, элемент слайса или значение map
+func (d *decoder) decodeNested(data interface{}, out reflect.Value, segment pathSegment) error {
+	d.path = append(d.path, segment)
+	err := d.decode(data, out)
+	d.path = d.path[:len(d.path)-1]
+	return err
+}
+
+// failNested ошибка во вложенном значении, которое не распаковывается
+func (d *decoder) failNested(err *DecodeError, segment pathSegment) error {
+	d.path = append(d.path, segment)
+	result := d.fail(err)
+	d.path = d.path[:len(d.path)-1]
+	return result
+}
+
+func (d *decoder) decodeSlice(data interface{}, out reflect.Value) error {
+	// json всегда дает []interface{}, остальные слайсы через reflect
+	items, ok := data.([]interface{})
+	if !ok {
+		value := reflect.ValueOf(data)
+		switch {
+		case value.Kind() == reflect.Slice || value.Kind() == reflect.Array:
+			items = make([]interface{}, value.Len())
+			for i := range items {
+				items[i] = value.Index(i).Interface()
 			}
-			if err := i2s(v, outValue.Field(i).Addr().Interface()); err != nil {
-				return errors.New(fmt.Sprintf("Field %v not found in data", f.Name))
+		case d.opts.WeaklyTyped:
+			items = []interface{}{data}
+		default:
+			return d.fail(&DecodeError{Expected: out.Type(), Actual: value.Type()})
+		}
+	}
+
+	slice := reflect.MakeSlice(out.Type(), len(items), len(items))
+	for i, item := range items {
+		if err := d.decodeNested(item, slice.Index(i), pathSegment{index: i}); err != nil {
+			return err
+		}
+	}
+	out.Set(slice)
+	return nil
+}
+
+// decodeMap дописывает ключи в map, как encoding/json. Ключи бывают
+// строками, целыми числами и TextUnmarshaler
+func (d *decoder) decodeMap(data interface{}, out reflect.Value) error {
+	items := reflect.ValueOf(data)
+	if items.Kind() != reflect.Map {
+		return d.fail(&DecodeError{Expected: out.Type(), Actual: items.Type()})
+	}
+	if out.IsNil() {
+		out.Set(reflect.MakeMapWithSize(out.Type(), items.Len()))
+	}
+
+	iter := items.MapRange()
+	for iter.Next() {
+		segment := pathSegment{key: iter.Key()}
+		key := reflect.New(out.Type().Key()).Elem()
+		if err := d.decodeKey(iter.Key().Interface(), key); err != nil {
+			if err = d.failNested(&DecodeError{Err: fmt.Errorf("bad key: %v", err)}, segment); err != nil {
+				return err
 			}
+			continue
+		}
+		elem := reflect.New(out.Type().Elem()).Elem()
+		if err := d.decodeNested(iter.Value().Interface(), elem, segment); err != nil {
+			return err
+		}
+		out.SetMapIndex(key, elem)
+	}
+	return nil
+}
+
+// decodeKey распаковывает ключ сам по себе, без сбора ошибок
+func (d *decoder) decodeKey(data interface{}, out reflect.Value) error {
+	if s, ok := data.(string); ok && !out.Addr().Type().Implements(textUnmarshalerType) {
+		switch out.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+			data = json.Number(s)
+		}
+	}
+	strict := &decoder{opts: Options{WeaklyTyped: d.opts.WeaklyTyped}}
+	return strict.decode(data, out)
+}
+
+func (d *decoder) decodeStruct(data interface{}, out reflect.Value) error {
+	// json всегда дает map[string]interface{}, остальные map через reflect
+	values, ok := data.(map[string]interface{})
+	if !ok {
+		items := reflect.ValueOf(data)
+		if items.Kind() != reflect.Map || items.Type().Key().Kind() != reflect.String {
+			return d.fail(&DecodeError{Expected: out.Type(), Actual: items.Type()})
+		}
+		values = make(map[string]interface{}, items.Len())
+		iter := items.MapRange()
+		for iter.Next() {
+			values[iter.Key().String()] = iter.Value().Interface()
+		}
+	}
+
+	var used map[string]bool
+	if d.opts.ErrorUnused {
+		used = make(map[string]bool, len(values))
+	}
+	for _, f := range cachedFields(out.Type()) {
+		key := f.name
+		value, ok := values[key]
+		if !ok {
+			// без учета регистра, из нескольких подходящих ключей - меньший
+			key = ""
+			for k, v := range values {
+				if strings.EqualFold(k, f.name) && (key == "" || k < key) {
+					key, value = k, v
+				}
+			}
+			if key == "" {
+				continue
+			}
+		}
+		if used != nil {
+			used[key] = true
+		}
+		if err := d.decodeNested(value, fieldByIndex(out, f.index), pathSegment{field: key}); err != nil {
+			return err
+		}
+	}
+
+	if !d.opts.ErrorUnused {
+		return nil
+	}
+	var unused []string
+	for key := range values {
+		if !used[key] {
+			unused = append(unused, key)
+		}
+	}
+	sort.Strings(unused)
+	for _, key := range unused {
+		if err := d.failNested(&DecodeError{Err: errors.New("unknown key")}, pathSegment{field: key}); err != nil {
+			return err
 		}
 	}
 	return nil
@@ -0,0 +1,299 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type Base struct {
+	ID      int
+	Created string `json:"created"`
+}
+
+type Level string
+
+func (l *Level) UnmarshalText(text []byte) error {
+	switch s := strings.ToLower(string(text)); s {
+	case "debug", "info", "error":
+		*l = Level(s)
+		return nil
+	}
+	return &json.UnsupportedValueError{Str: string(text)}
+}
+
+type Config struct {
+	Base
+	*Owner
+	Name     string            `json:"name,omitempty"`
+	Secret   string            `json:"-"`
+	Port     uint16            `json:"port"`
+	Ratio    float32           `json:"ratio"`
+	Timeout  *int64            `json:"timeout"`
+	Labels   map[string]string `json:"labels"`
+	Limits   map[int]Base      `json:"limits"`
+	Extra    interface{}       `json:"extra"`
+	Level    Level             `json:"level"`
+	Listen   net.IP            `json:"listen"`
+	Started  *time.Time        `json:"started"`
+	Children []*Config         `json:"children"`
+}
+
+type Owner struct {
+	Owner string `json:"owner"`
+	// port есть у Config, поле внешней структуры важнее
+	Port int `json:"port"`
+	// ID есть и в Base на той же глубине, такое поле неоднозначно и пропускается
+	ID int
+}
+
+func TestDecodeConfig(t *testing.T) {
+	var data interface{}
+	err := json.Unmarshal([]byte(`{
+		"ID": 7,
+		"created": "yesterday",
+		"owner": "rvasily",
+		"NAME": "api",
+		"Secret": "leaked",
+		"-": "nothing",
+		"port": 8080,
+		"ratio": 0.5,
+		"timeout": 30,
+		"labels": {"env": "prod"},
+		"limits": {"1": {"ID": 1}, "20": {"ID": 20}},
+		"extra": {"any": [1, "two", null]},
+		"level": "INFO",
+		"listen": "127.0.0.1",
+		"started": "2020-01-02T03:04:05Z",
+		"children": [{"name": "child"}, null]
+	}`), &data)
+	if err != nil {
+		t.Fatalf("cant unmarshal: %v", err)
+	}
+
+	result := &Config{Secret: "kept"}
+	if err := i2s(data, result); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	timeout := int64(30)
+	started := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	expected := &Config{
+		Base:    Base{Created: "yesterday"},
+		Owner:   &Owner{Owner: "rvasily"},
+		Name:    "api",
+		Secret:  "kept",
+		Port:    8080,
+		Ratio:   0.5,
+		Timeout: &timeout,
+		Labels:  map[string]string{"env": "prod"},
+		Limits:  map[int]Base{1: {ID: 1}, 20: {ID: 20}},
+		Extra: map[string]interface{}{
+			"any": []interface{}{float64(1), "two", nil},
+		},
+		Level:    "info",
+		Listen:   net.ParseIP("127.0.0.1"),
+		Started:  &started,
+		Children: []*Config{{Name: "child"}, nil},
+	}
+	if !reflect.DeepEqual(expected, result) {
+		t.Errorf("results not match\nGot:\n%#v\nExpected:\n%#v", result, expected)
+	}
+}
+
+func TestDecodeNumbers(t *testing.T) {
+	for idx, item := range []struct {
+		data     interface{}
+		out      interface{}
+		expected interface{}
+	}{
+		{float64(-128), new(int8), int8(-128)},
+		{float64(255), new(uint8), uint8(255)},
+		{float64(1 << 40), new(int64), int64(1 << 40)},
+		{float64(-1 << 63), new(int64), int64(math.MinInt64)},
+		{json.Number("18446744073709551615"), new(uint64), uint64(math.MaxUint64)},
+		{json.Number("9223372036854775807"), new(int64), int64(math.MaxInt64)},
+		{int32(7), new(uint), uint(7)},
+		{uint64(7), new(float32), float32(7)},
+		{float64(1.5), new(float64), 1.5},
+	} {
+		if err := i2s(item.data, item.out); err != nil {
+			t.Errorf("[%d] unexpected error: %v", idx, err)
+			continue
+		}
+		if got := reflect.ValueOf(item.out).Elem().Interface(); got != item.expected {
+			t.Errorf("[%d] expected %v, got %v", idx, item.expected, got)
+		}
+	}
+
+	for idx, item := range []struct {
+		data interface{}
+		out  interface{}
+	}{
+		{float64(128), new(int8)},
+		{float64(-1), new(uint)},
+		{float64(256), new(uint8)},
+		{float64(1.5), new(int)},
+		{float64(1 << 63), new(int64)},
+		{float64(1 << 64), new(uint64)},
+		{json.Number("9223372036854775808"), new(int64)},
+		{uint64(math.MaxUint64), new(int)},
+		{int64(-1), new(uint32)},
+		{float64(1e300), new(float32)},
+		{"1", new(int)},
+		{true, new(float64)},
+		{"12", new(map[int]string)},
+		{map[string]interface{}{"x": "y"}, new(map[int]string)},
+		{"warn", new(Level)},
+		{float64(1), new(Level)},
+		{"x", new(interface{ Error() string })},
+	} {
+		if err := i2s(item.data, item.out); err == nil {
+			t.Errorf("[%d] expected error on %v into %T", idx, item.data, item.out)
+		}
+	}
+}
+
+type Address struct {
+	City string
+	Zip  int `json:"zip"`
+}
+
+type User struct {
+	Name    string
+	Age     uint8
+	Admin   bool
+	Tags    []string
+	Address Address
+}
+
+type Users struct {
+	Users []User
+}
+
+func unmarshal(t *testing.T, data string) interface{} {
+	var result interface{}
+	if err := json.Unmarshal([]byte(data), &result); err != nil {
+		t.Fatalf("cant unmarshal: %v", err)
+	}
+	return result
+}
+
+func TestDecodeErrorPath(t *testing.T) {
+	data := unmarshal(t, `{"Users": [
+		{"Name": "a"},
+		{"Name": "b", "Address": {"zip": "123"}}
+	]}`)
+	err := i2s(data, &Users{})
+
+	var decodeErr *DecodeError
+	if !errors.As(err, &decodeErr) {
+		t.Fatalf("expected *DecodeError, got %#v", err)
+	}
+	if decodeErr.Path != "Users[1].Address.zip" || decodeErr.Expected != reflect.TypeOf(0) || decodeErr.Actual != reflect.TypeOf("") {
+		t.Fatalf("unexpected error %#v", decodeErr)
+	}
+	if err.Error() != "Users[1].Address.zip: expected int, got string" {
+		t.Fatalf("unexpected message %q", err.Error())
+	}
+
+	err = i2s(unmarshal(t, `{"Users": [{"Age": 300}]}`), &Users{})
+	if !errors.As(err, &decodeErr) || decodeErr.Path != "Users[0].Age" || decodeErr.Err == nil {
+		t.Fatalf("expected overflow of Users[0].Age, got %v", err)
+	}
+
+	err = i2s(unmarshal(t, `{"labels": {"env": 1}}`), &Config{})
+	if err == nil || err.Error() != "labels[env]: expected string, got float64" {
+		t.Fatalf("unexpected error %v", err)
+	}
+}
+
+func TestDecodeOptions(t *testing.T) {
+	data := unmarshal(t, `{"Users": [
+		{"Name": 1, "Age": "x", "Address": {"City": "Moscow", "Street": "Tverskaya"}},
+		{"Name": "b", "Admin": "yes", "Extra": true}
+	]}`)
+
+	err := i2sWithOptions(data, &Users{}, Options{CollectErrors: true, ErrorUnused: true})
+	var errs DecodeErrors
+	if !errors.As(err, &errs) {
+		t.Fatalf("expected DecodeErrors, got %#v", err)
+	}
+	var paths []string
+	for _, e := range errs {
+		paths = append(paths, e.Path)
+	}
+	expected := []string{
+		"Users[0].Name",
+		"Users[0].Age",
+		"Users[0].Address.Street",
+		"Users[1].Admin",
+		"Users[1].Extra",
+	}
+	if !reflect.DeepEqual(paths, expected) {
+		t.Fatalf("expected errors at %v, got %v", expected, paths)
+	}
+
+	// без CollectErrors - только первая ошибка
+	err = i2sWithOptions(data, &Users{}, Options{ErrorUnused: true})
+	var decodeErr *DecodeError
+	if !errors.As(err, &decodeErr) || decodeErr.Path != "Users[0].Name" {
+		t.Fatalf("expected the first error only, got %v", err)
+	}
+
+	data = unmarshal(t, `{"Users": [{"Name": 42, "Age": "42", "Admin": "true", "Tags": "single", "Address": {"zip": "101000"}}]}`)
+	if err := i2s(data, &Users{}); err == nil {
+		t.Fatalf("expected error without weak typing")
+	}
+	result := &Users{}
+	if err := i2sWithOptions(data, result, Options{WeaklyTyped: true}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	weak := &Users{Users: []User{{
+		Name:    "42",
+		Age:     42,
+		Admin:   true,
+		Tags:    []string{"single"},
+		Address: Address{Zip: 101000},
+	}}}
+	if !reflect.DeepEqual(result, weak) {
+		t.Errorf("results not match\nGot:\n%#v\nExpected:\n%#v", result, weak)
+	}
+
+	err = i2sWithOptions(unmarshal(t, `{"Age": "old"}`), &User{}, Options{WeaklyTyped: true})
+	if err == nil || err.Error() != `Age: cannot parse "old" as a number` {
+		t.Fatalf("unexpected error %v", err)
+	}
+}
+
+// Node встраивает сам себя, обход встроенных структур должен закончиться
+type Node struct {
+	*Node
+	X int
+}
+
+func TestDecodeRecursiveEmbedded(t *testing.T) {
+	done := make(chan struct{})
+	var n Node
+	var err error
+	go func() {
+		defer close(done)
+		err = i2s(map[string]interface{}{"X": 1.0}, &n)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("i2s hangs on recursive embedded struct")
+	}
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if n.X != 1 || n.Node != nil {
+		t.Fatalf("bad result: %+v", n)
+	}
+}
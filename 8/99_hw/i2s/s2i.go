@@ -0,0 +1,194 @@
+package main
+
+import (
+	"encoding"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+)
+
+// s2i обратна i2s: раскладывает структуру в map[string]interface{} по тем же
+// правилам тегов. Поля с omitempty и пустым значением пропускаются, как в
+// encoding/json.
+//
+// Числа остаются своих типов, а не float64, чтобы не терять точность, i2s их
+// принимает. encoding.TextMarshaler становится строкой, слайсы -
+// []interface{}, map - map[string]interface{} со строковыми ключами.
+func s2i(in interface{}) (map[string]interface{}, error) {
+	v := reflect.ValueOf(in)
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return nil, errors.New("in is a nil pointer")
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("in is not a struct, but %v", v.Type())
+	}
+	e := &encoder{}
+	return e.encodeStruct(v)
+}
+
+var textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+
+type encoder struct {
+	// path до текущего значения, как у decoder
+	path valuePath
+}
+
+// fail дописывает к ошибке путь, как у DecodeError
+func (e *encoder) fail(err error) error {
+	if len(e.path) == 0 {
+		return err
+	}
+	return fmt.Errorf("%v: %w", e.path, err)
+}
+
+func (e *encoder) encodeNested(v reflect.Value, segment pathSegment) (interface{}, error) {
+	e.path = append(e.path, segment)
+	item, err := e.encode(v)
+	e.path = e.path[:len(e.path)-1]
+	return item, err
+}
+
+func (e *encoder) encode(v reflect.Value) (interface{}, error) {
+	if v.Kind() != reflect.Interface && v.Kind() != reflect.Ptr {
+		marshaler := v
+		if !v.Type().Implements(textMarshalerType) && v.CanAddr() {
+			marshaler = v.Addr()
+		}
+		if marshaler.Type().Implements(textMarshalerType) {
+			text, err := marshaler.Interface().(encoding.TextMarshaler).MarshalText()
+			if err != nil {
+				return nil, e.fail(err)
+			}
+			return string(text), nil
+		}
+	}
+
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			return nil, nil
+		}
+		return e.encode(v.Elem())
+	case reflect.Bool:
+		return v.Bool(), nil
+	case reflect.String:
+		return v.String(), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64:
+		// именованные типы вроде time.Duration становятся базовыми
+		return v.Convert(kindTypes[v.Kind()]).Interface(), nil
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			return nil, nil
+		}
+		items := make([]interface{}, v.Len())
+		for i := range items {
+			item, err := e.encodeNested(v.Index(i), pathSegment{index: i})
+			if err != nil {
+				return nil, err
+			}
+			items[i] = item
+		}
+		return items, nil
+	case reflect.Map:
+		if v.IsNil() {
+			return nil, nil
+		}
+		return e.encodeMap(v)
+	case reflect.Struct:
+		return e.encodeStruct(v)
+	}
+	return nil, e.fail(fmt.Errorf("unsupported type %v", v.Type()))
+}
+
+var kindTypes = map[reflect.Kind]reflect.Type{
+	reflect.Int:     reflect.TypeOf(int(0)),
+	reflect.Int8:    reflect.TypeOf(int8(0)),
+	reflect.Int16:   reflect.TypeOf(int16(0)),
+	reflect.Int32:   reflect.TypeOf(int32(0)),
+	reflect.Int64:   reflect.TypeOf(int64(0)),
+	reflect.Uint:    reflect.TypeOf(uint(0)),
+	reflect.Uint8:   reflect.TypeOf(uint8(0)),
+	reflect.Uint16:  reflect.TypeOf(uint16(0)),
+	reflect.Uint32:  reflect.TypeOf(uint32(0)),
+	reflect.Uint64:  reflect.TypeOf(uint64(0)),
+	reflect.Uintptr: reflect.TypeOf(uintptr(0)),
+	reflect.Float32: reflect.TypeOf(float32(0)),
+	reflect.Float64: reflect.TypeOf(float64(0)),
+}
+
+func (e *encoder) encodeMap(v reflect.Value) (map[string]interface{}, error) {
+	result := make(map[string]interface{}, v.Len())
+	iter := v.MapRange()
+	for iter.Next() {
+		key, err := encodeKey(iter.Key())
+		if err != nil {
+			e.path = append(e.path, pathSegment{key: iter.Key()})
+			return nil, e.fail(err)
+		}
+		item, err := e.encodeNested(iter.Value(), pathSegment{key: iter.Key()})
+		if err != nil {
+			return nil, err
+		}
+		result[key] = item
+	}
+	return result, nil
+}
+
+// encodeKey обратен decodeKey
+func encodeKey(key reflect.Value) (string, error) {
+	if key.Type().Implements(textMarshalerType) {
+		text, err := key.Interface().(encoding.TextMarshaler).MarshalText()
+		return string(text), err
+	}
+	switch key.Kind() {
+	case reflect.String:
+		return key.String(), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(key.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return strconv.FormatUint(key.Uint(), 10), nil
+	}
+	return "", fmt.Errorf("unsupported key type %v", key.Type())
+}
+
+func (e *encoder) encodeStruct(v reflect.Value) (map[string]interface{}, error) {
+	fields := cachedFields(v.Type())
+	result := make(map[string]interface{}, len(fields))
+	for _, f := range fields {
+		value, ok := fieldByIndexRead(v, f.index)
+		if !ok || f.omitEmpty && isEmpty(value) {
+			continue
+		}
+		item, err := e.encodeNested(value, pathSegment{field: f.name})
+		if err != nil {
+			return nil, err
+		}
+		result[f.name] = item
+	}
+	return result, nil
+}
+
+// isEmpty пустые значения для omitempty, как в encoding/json
+func isEmpty(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool:
+		return !v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return v.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return v.Float() == 0
+	case reflect.Interface, reflect.Ptr:
+		return v.IsNil()
+	}
+	return false
+}
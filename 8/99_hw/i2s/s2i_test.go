@@ -0,0 +1,181 @@
+package main
+
+import (
+	"encoding/json"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestS2I(t *testing.T) {
+	timeout := int64(30)
+	started := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	in := &Config{
+		Base:     Base{ID: 7, Created: "yesterday"},
+		Name:     "api",
+		Secret:   "hidden",
+		Port:     8080,
+		Timeout:  &timeout,
+		Labels:   map[string]string{"env": "prod"},
+		Limits:   map[int]Base{1: {ID: 1}},
+		Extra:    []interface{}{1, "two"},
+		Level:    "info",
+		Listen:   net.ParseIP("127.0.0.1"),
+		Started:  &started,
+		Children: []*Config{{Name: "child", Level: "debug"}},
+	}
+
+	out, err := s2i(in)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	child := map[string]interface{}{
+		"created": "", "port": uint16(0), "ratio": float32(0), "timeout": nil,
+		"labels": nil, "limits": nil, "extra": nil, "level": "debug", "listen": "",
+		"started": nil, "children": nil,
+	}
+	child["name"] = "child"
+	expected := map[string]interface{}{
+		// name с omitempty есть, Secret нет, ID неоднозначно из-за Owner
+		"created":  "yesterday",
+		"name":     "api",
+		"port":     uint16(8080),
+		"ratio":    float32(0),
+		"timeout":  int64(30),
+		"labels":   map[string]interface{}{"env": "prod"},
+		"limits":   map[string]interface{}{"1": map[string]interface{}{"ID": 1, "created": ""}},
+		"extra":    []interface{}{1, "two"},
+		"level":    "info",
+		"listen":   "127.0.0.1",
+		"started":  "2020-01-02T03:04:05Z",
+		"children": []interface{}{child},
+	}
+	if !reflect.DeepEqual(out, expected) {
+		t.Fatalf("results not match\nGot:\n%#v\nExpected:\n%#v", out, expected)
+	}
+
+	// обратно через i2s получается то же самое, кроме поля с "-"
+	back := &Config{}
+	if err := i2s(out, back); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	in.Secret = ""
+	in.Base.ID = 0
+	if !reflect.DeepEqual(back, in) {
+		t.Fatalf("round trip doesn't match\nGot:\n%#v\nExpected:\n%#v", back, in)
+	}
+
+	for idx, item := range []interface{}{
+		42,
+		(*Simple)(nil),
+		struct{ C chan int }{},
+		struct{ M map[float64]int }{M: map[float64]int{1: 1}},
+	} {
+		if _, err := s2i(item); err == nil {
+			t.Errorf("[%d] expected error on %#v", idx, item)
+		}
+	}
+}
+
+func benchmarkData() *Complex {
+	smpl := Simple{ID: 42, Username: "rvasily", Active: true}
+	return &Complex{
+		SubSimple:  smpl,
+		ManySimple: []Simple{smpl, smpl, smpl, smpl},
+		Blocks:     []IDBlock{{42}, {43}, {44}},
+	}
+}
+
+func BenchmarkI2S(b *testing.B) {
+	jsonRaw, _ := json.Marshal(benchmarkData())
+	var data interface{}
+	json.Unmarshal(jsonRaw, &data)
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if err := i2s(data, &Complex{}); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// то же через json: map -> json -> структура
+func BenchmarkI2SJSON(b *testing.B) {
+	jsonRaw, _ := json.Marshal(benchmarkData())
+	var data interface{}
+	json.Unmarshal(jsonRaw, &data)
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		raw, err := json.Marshal(data)
+		if err != nil {
+			b.Fatal(err)
+		}
+		if err := json.Unmarshal(raw, &Complex{}); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkS2I(b *testing.B) {
+	in := benchmarkData()
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := s2i(in); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// то же через json: структура -> json -> map
+func BenchmarkS2IJSON(b *testing.B) {
+	in := benchmarkData()
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		raw, err := json.Marshal(in)
+		if err != nil {
+			b.Fatal(err)
+		}
+		var out map[string]interface{}
+		if err := json.Unmarshal(raw, &out); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestS2IRecursiveEmbedded(t *testing.T) {
+	done := make(chan struct{})
+	var out map[string]interface{}
+	var err error
+	go func() {
+		defer close(done)
+		out, err = s2i(Node{X: 1})
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("s2i hangs on recursive embedded struct")
+	}
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(out, map[string]interface{}{"X": 1}) {
+		t.Fatalf("bad result: %#v", out)
+	}
+
+	// план полей посчитан один раз и лежит в кеше
+	if _, ok := fieldCache.Load(reflect.TypeOf(Node{})); !ok {
+		t.Fatal("fields of Node are not cached")
+	}
+
+	var back Node
+	if err := i2s(out, &back); err != nil {
+		t.Fatalf("round trip: %v", err)
+	}
+	if back.X != 1 {
+		t.Fatalf("round trip: %+v", back)
+	}
+}
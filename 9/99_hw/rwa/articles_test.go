@@ -0,0 +1,231 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/mcuadros/go-lookup"
+)
+
+// rememberSlug запоминает slug статьи из ответа, проверяя, что он равен want:
+// в TestArticle slug не сравнивается
+func rememberSlug(tplParams map[string]string, key, want string) func(*http.Response, []byte, interface{}) error {
+	return func(r *http.Response, body []byte, resp interface{}) error {
+		val, err := lookup.LookupString(resp, "Article.Slug")
+		if err != nil {
+			return err
+		}
+		if val.String() != want {
+			return fmt.Errorf("expected slug %q, got %q", want, val.String())
+		}
+		tplParams[key] = val.String()
+		return nil
+	}
+}
+
+func TestArticleCRUD(t *testing.T) {
+	ts := httptest.NewServer(GetApp())
+	defer ts.Close()
+
+	tplParams := map[string]string{
+		"APIURL": ts.URL + "/api",
+	}
+
+	article := func(title, body string) func() interface{} {
+		return func() interface{} {
+			return &struct {
+				Article TestArticle
+			}{
+				Article: TestArticle{
+					Author:      TestProfile{Username: "alice"},
+					Body:        body,
+					Title:       title,
+					Description: "about",
+					CreatedAt:   FakeTime{true},
+					UpdatedAt:   FakeTime{true},
+					TagList:     []string{},
+				},
+			}
+		}
+	}
+
+	testCases := []*ApiTestCase{
+		registerCase(tplParams, "alice"),
+		registerCase(tplParams, "bob"),
+		&ApiTestCase{
+			Name:           "Create article",
+			Method:         "POST",
+			Body:           `{"article":{"title":"Article CRUD", "description":"about", "body":"body"}}`,
+			URL:            "{{APIURL}}/articles",
+			TokenName:      "token_alice",
+			ResponseStatus: 201,
+			Expected:       article("Article CRUD", "body"),
+			After:          rememberSlug(tplParams, "slug", "article-crud"),
+		},
+		&ApiTestCase{
+			Name:           "Create second article",
+			Method:         "POST",
+			Body:           `{"article":{"title":"Second one", "description":"about", "body":"body"}}`,
+			URL:            "{{APIURL}}/articles",
+			TokenName:      "token_alice",
+			ResponseStatus: 201,
+			Expected:       article("Second one", "body"),
+			After:          rememberSlug(tplParams, "second", "second-one"),
+		},
+
+		&ApiTestCase{
+			Name:           "Update - requires auth",
+			Method:         "PUT",
+			Body:           `{"article":{"body":"new body"}}`,
+			URL:            "{{APIURL}}/articles/{{slug}}",
+			ResponseStatus: 401,
+		},
+		&ApiTestCase{
+			Name:           "Update - not an author",
+			Method:         "PUT",
+			Body:           `{"article":{"body":"new body"}}`,
+			URL:            "{{APIURL}}/articles/{{slug}}",
+			TokenName:      "token_bob",
+			ResponseStatus: 403,
+		},
+		&ApiTestCase{
+			Name:           "Update - unknown slug",
+			Method:         "PUT",
+			Body:           `{"article":{"body":"new body"}}`,
+			URL:            "{{APIURL}}/articles/nothing-here",
+			TokenName:      "token_alice",
+			ResponseStatus: 404,
+		},
+		&ApiTestCase{
+			Name:           "Update - empty title",
+			Method:         "PUT",
+			Body:           `{"article":{"title":""}}`,
+			URL:            "{{APIURL}}/articles/{{slug}}",
+			TokenName:      "token_alice",
+			ResponseStatus: 422,
+		},
+		&ApiTestCase{
+			Name:           "Update - body keeps the slug",
+			Method:         "PUT",
+			Body:           `{"article":{"body":"new body"}}`,
+			URL:            "{{APIURL}}/articles/{{slug}}",
+			TokenName:      "token_alice",
+			ResponseStatus: 200,
+			Expected:       article("Article CRUD", "new body"),
+			After:          rememberSlug(tplParams, "slug", "article-crud"),
+		},
+		&ApiTestCase{
+			Name:           "Update - seen by others",
+			Method:         "GET",
+			URL:            "{{APIURL}}/articles/{{slug}}",
+			TokenName:      "token_bob",
+			ResponseStatus: 200,
+			Expected:       article("Article CRUD", "new body"),
+		},
+		&ApiTestCase{
+			Name:           "Rename - to a taken title gets a unique slug",
+			Method:         "PUT",
+			Body:           `{"article":{"title":"Article CRUD"}}`,
+			URL:            "{{APIURL}}/articles/{{second}}",
+			TokenName:      "token_alice",
+			ResponseStatus: 200,
+			Expected:       article("Article CRUD", "body"),
+			After:          rememberSlug(tplParams, "renamed", "article-crud-2"),
+		},
+		&ApiTestCase{
+			Name:           "Rename - old slug is gone",
+			Method:         "GET",
+			URL:            "{{APIURL}}/articles/{{second}}",
+			ResponseStatus: 404,
+		},
+		&ApiTestCase{
+			Name:           "Rename - new slug",
+			Method:         "GET",
+			URL:            "{{APIURL}}/articles/{{renamed}}",
+			ResponseStatus: 200,
+			Expected:       article("Article CRUD", "body"),
+		},
+
+		&ApiTestCase{
+			Name:           "Comment before delete",
+			Method:         "POST",
+			Body:           `{"comment":{"body":"nice"}}`,
+			URL:            "{{APIURL}}/articles/{{slug}}/comments",
+			TokenName:      "token_bob",
+			ResponseStatus: 200,
+		},
+		&ApiTestCase{
+			Name:           "Delete - requires auth",
+			Method:         "DELETE",
+			URL:            "{{APIURL}}/articles/{{slug}}",
+			ResponseStatus: 401,
+		},
+		&ApiTestCase{
+			Name:           "Delete - not an author",
+			Method:         "DELETE",
+			URL:            "{{APIURL}}/articles/{{slug}}",
+			TokenName:      "token_bob",
+			ResponseStatus: 403,
+		},
+		&ApiTestCase{
+			Name:           "Delete - unknown slug",
+			Method:         "DELETE",
+			URL:            "{{APIURL}}/articles/nothing-here",
+			TokenName:      "token_alice",
+			ResponseStatus: 404,
+		},
+		&ApiTestCase{
+			Name:           "Delete",
+			Method:         "DELETE",
+			URL:            "{{APIURL}}/articles/{{slug}}",
+			TokenName:      "token_alice",
+			ResponseStatus: 200,
+		},
+		&ApiTestCase{
+			Name:           "Delete - article is gone",
+			Method:         "GET",
+			URL:            "{{APIURL}}/articles/{{slug}}",
+			ResponseStatus: 404,
+		},
+		&ApiTestCase{
+			Name:           "Delete - comments are gone",
+			Method:         "GET",
+			URL:            "{{APIURL}}/articles/{{slug}}/comments",
+			ResponseStatus: 404,
+		},
+		&ApiTestCase{
+			Name:           "Delete - twice",
+			Method:         "DELETE",
+			URL:            "{{APIURL}}/articles/{{slug}}",
+			TokenName:      "token_alice",
+			ResponseStatus: 404,
+		},
+		&ApiTestCase{
+			Name:           "Delete - slug is free again",
+			Method:         "POST",
+			Body:           `{"article":{"title":"Article CRUD", "description":"about", "body":"body"}}`,
+			URL:            "{{APIURL}}/articles",
+			TokenName:      "token_alice",
+			ResponseStatus: 201,
+			Expected:       article("Article CRUD", "body"),
+			After:          rememberSlug(tplParams, "slug", "article-crud"),
+		},
+		&ApiTestCase{
+			Name:           "Delete - new article has no old comments",
+			Method:         "GET",
+			URL:            "{{APIURL}}/articles/{{slug}}/comments",
+			ResponseStatus: 200,
+			Expected: func() interface{} {
+				return &struct {
+					Comments []TestComment `json:"comments"`
+				}{
+					Comments: []TestComment{},
+				}
+			},
+		},
+	}
+
+	runApiTestCases(t, tplParams, testCases)
+}
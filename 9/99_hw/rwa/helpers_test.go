@@ -0,0 +1,134 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/mcuadros/go-lookup"
+	"gopkg.in/d4l3k/messagediff.v1"
+)
+
+// runApiTestCases выполняет кейсы по порядку: подставляет параметры из
+// tplParams, делает запрос и сравнивает статус и тело. After может дописать в
+// tplParams значения из ответа для следующих кейсов, первый упавший кейс
+// останавливает остальные
+func runApiTestCases(t *testing.T, tplParams map[string]string, testCases []*ApiTestCase) {
+	replaceRe := regexp.MustCompile("{{(.*?)}}")
+	replaceBrackets := strings.NewReplacer("{", "", "}", "")
+	replacer := func(key []byte) []byte {
+		k := replaceBrackets.Replace(string(key))
+		val, ok := tplParams[k]
+		if !ok {
+			t.Fatalf("not found key %s during tpl substitution", string(key))
+		}
+		return []byte(val)
+	}
+
+	for _, item := range testCases {
+		ok := t.Run(item.Name, func(t *testing.T) {
+
+			if item.Before != nil {
+				item.Before()
+			}
+			// some kind of eval params with substitution
+			if item.Expected != nil {
+				item.Expected = item.Expected.(func() interface{})()
+			}
+
+			var (
+				body []byte
+				url  = replaceRe.ReplaceAllFunc([]byte(item.URL), replacer)
+			)
+			if item.Body != "" {
+				body = replaceRe.ReplaceAllFunc([]byte(item.Body), replacer)
+			}
+
+			req, _ := http.NewRequest(item.Method, string(url), bytes.NewReader(body))
+			req.Header.Add("X-Requested-With", "XMLHttpRequest")
+			req.Header.Add("Content-Type", "application/json")
+
+			if item.TokenName != "" {
+				req.Header.Add("Authorization", "Token "+tplParams[item.TokenName])
+			}
+
+			resp, err := client.Do(req)
+			if err != nil {
+				t.Fatalf("request error: %v", err)
+			}
+			defer resp.Body.Close()
+			respBody, err := ioutil.ReadAll(resp.Body)
+
+			// t.Logf("\nreq body: %s\nresp body: %s", body, respBody)
+
+			if item.ResponseStatus != resp.StatusCode {
+				t.Fatalf("bad status code, want: %v, have:%v", item.ResponseStatus, resp.StatusCode)
+			}
+
+			// for cases with just status check
+			if item.Expected == nil {
+				return
+			}
+
+			got := WeirdMagicClone(item.Expected)
+			err = json.Unmarshal(respBody, got)
+			if err != nil {
+				t.Fatalf("cant unmarshal resp: %s, body: %s", err, respBody)
+			}
+
+			diff, equal := messagediff.PrettyDiff(item.Expected, got)
+			if !equal {
+				t.Fatalf("\033[1;31mresults not match\033[0m\n \033[1;35mbody\033[0m: %s\n\033[1;32mwant\033[0m %#v\n\033[1;34mgot\033[0m %#v\n\033[1;33mdiff\033[0m:\n%s", respBody, item.Expected, got, diff)
+			}
+
+			if item.After != nil {
+				err = item.After(resp, respBody, got)
+				if err != nil {
+					t.Fatalf("after func failed %s", err)
+				}
+			}
+		})
+		if !ok {
+			break
+		}
+	}
+}
+
+// registerCase регистрирует name и запоминает его токен как token_<name>
+func registerCase(tplParams map[string]string, name string) *ApiTestCase {
+	return &ApiTestCase{
+		Name:           "Register " + name,
+		Method:         "POST",
+		Body:           `{"user":{"email":"` + name + `@example.com", "password":"love", "username":"` + name + `"}}`,
+		URL:            "{{APIURL}}/users",
+		ResponseStatus: 201,
+		After:          rememberString(tplParams, "token_"+name, "User.Token"),
+		Expected: func() interface{} {
+			return &struct {
+				User TestProfile
+			}{
+				User: TestProfile{
+					Email:     name + "@example.com",
+					CreatedAt: FakeTime{true},
+					UpdatedAt: FakeTime{true},
+					Username:  name,
+				},
+			}
+		},
+	}
+}
+
+func rememberString(tplParams map[string]string, key, path string) func(*http.Response, []byte, interface{}) error {
+	return func(r *http.Response, body []byte, resp interface{}) error {
+		val, err := lookup.LookupString(resp, path)
+		if err != nil {
+			return err
+		}
+		tplParams[key] = val.String()
+		return nil
+	}
+}
@@ -0,0 +1,74 @@
+package article
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	ErrNotFound  = errors.New("article not found")
+	ErrSlugTaken = errors.New("slug has already been taken")
+	ErrForbidden = errors.New("article belongs to another user")
+
+	ErrCommentNotFound  = errors.New("comment not found")
+	ErrCommentForbidden = errors.New("comment belongs to another user")
+)
+
+type Article struct {
+	ID          uint32
+	Slug        string
+	Title       string
+	Description string
+	Body        string
+	TagList     []string
+	AuthorID    uint32
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+// Filter отбор статей для List, пустые поля не фильтруют
+type Filter struct {
+	Tag string
+	// AuthorIDs nil - любой автор, пустой слайс - ни одного
+	AuthorIDs   []uint32
+	FavoritedBy uint32
+	Limit       int
+	Offset      int
+}
+
+// Repo хранилище статей и их избранного. Slug уникален, Add и Update
+// возвращают ErrSlugTaken. List отдаёт статьи в порядке создания и общее
+// число подходящих под фильтр без учёта Limit и Offset. Повторные Favorite и
+// Unfavorite ничего не меняют, Delete удаляет и избранное статьи
+type Repo interface {
+	Add(a *Article) (uint32, error)
+	GetBySlug(slug string) (*Article, error)
+	List(f Filter) ([]*Article, int, error)
+	Update(a *Article) error
+	Delete(id uint32) error
+
+	Favorite(articleID, userID uint32) error
+	Unfavorite(articleID, userID uint32) error
+	// Favorites сколько пользователей добавили статью в избранное и есть ли
+	// среди них userID
+	Favorites(articleID, userID uint32) (int, bool, error)
+}
+
+type Comment struct {
+	ID        uint32
+	ArticleID uint32
+	AuthorID  uint32
+	Body      string
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// CommentRepo хранилище комментариев, ListByArticle отдаёт их в порядке
+// создания
+type CommentRepo interface {
+	Add(c *Comment) (uint32, error)
+	GetByID(id uint32) (*Comment, error)
+	ListByArticle(articleID uint32) ([]*Comment, error)
+	Delete(id uint32) error
+	DeleteByArticle(articleID uint32) error
+}
@@ -0,0 +1,80 @@
+package article
+
+import (
+	"sync"
+)
+
+type CommentMemoryRepo struct {
+	mu       sync.RWMutex
+	lastID   uint32
+	comments []*Comment
+}
+
+func NewCommentMemoryRepo() *CommentMemoryRepo {
+	return &CommentMemoryRepo{}
+}
+
+func (repo *CommentMemoryRepo) Add(c *Comment) (uint32, error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	repo.lastID++
+	stored := *c
+	stored.ID = repo.lastID
+	repo.comments = append(repo.comments, &stored)
+	return stored.ID, nil
+}
+
+func (repo *CommentMemoryRepo) GetByID(id uint32) (*Comment, error) {
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+
+	for _, c := range repo.comments {
+		if c.ID == id {
+			res := *c
+			return &res, nil
+		}
+	}
+	return nil, ErrCommentNotFound
+}
+
+func (repo *CommentMemoryRepo) ListByArticle(articleID uint32) ([]*Comment, error) {
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+
+	res := []*Comment{}
+	for _, c := range repo.comments {
+		if c.ArticleID == articleID {
+			comment := *c
+			res = append(res, &comment)
+		}
+	}
+	return res, nil
+}
+
+func (repo *CommentMemoryRepo) Delete(id uint32) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	for i, c := range repo.comments {
+		if c.ID == id {
+			repo.comments = append(repo.comments[:i], repo.comments[i+1:]...)
+			return nil
+		}
+	}
+	return ErrCommentNotFound
+}
+
+func (repo *CommentMemoryRepo) DeleteByArticle(articleID uint32) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	kept := repo.comments[:0]
+	for _, c := range repo.comments {
+		if c.ArticleID != articleID {
+			kept = append(kept, c)
+		}
+	}
+	repo.comments = kept
+	return nil
+}
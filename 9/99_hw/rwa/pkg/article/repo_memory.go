@@ -0,0 +1,188 @@
+package article
+
+import (
+	"sync"
+)
+
+// MemoryRepo хранит статьи слайсом в порядке создания, так List не нужно
+// ничего сортировать
+type MemoryRepo struct {
+	mu       sync.RWMutex
+	lastID   uint32
+	articles []*Article
+	// favorites избранное: статья -> множество пользователей
+	favorites map[uint32]map[uint32]struct{}
+}
+
+func NewMemoryRepo() *MemoryRepo {
+	return &MemoryRepo{
+		favorites: make(map[uint32]map[uint32]struct{}),
+	}
+}
+
+func (repo *MemoryRepo) Add(a *Article) (uint32, error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	if repo.slugTaken(a) {
+		return 0, ErrSlugTaken
+	}
+	repo.lastID++
+	stored := copyArticle(a)
+	stored.ID = repo.lastID
+	repo.articles = append(repo.articles, stored)
+	return stored.ID, nil
+}
+
+func (repo *MemoryRepo) slugTaken(a *Article) bool {
+	for _, other := range repo.articles {
+		if other.ID != a.ID && other.Slug == a.Slug {
+			return true
+		}
+	}
+	return false
+}
+
+func (repo *MemoryRepo) GetBySlug(slug string) (*Article, error) {
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+
+	for _, a := range repo.articles {
+		if a.Slug == slug {
+			return copyArticle(a), nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (repo *MemoryRepo) List(f Filter) ([]*Article, int, error) {
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+
+	res := []*Article{}
+	total := 0
+	for _, a := range repo.articles {
+		if !repo.matches(a, f) {
+			continue
+		}
+		total++
+		if total <= f.Offset || f.Limit > 0 && len(res) >= f.Limit {
+			continue
+		}
+		res = append(res, copyArticle(a))
+	}
+	return res, total, nil
+}
+
+func (repo *MemoryRepo) matches(a *Article, f Filter) bool {
+	if f.AuthorIDs != nil && !hasID(f.AuthorIDs, a.AuthorID) {
+		return false
+	}
+	if f.Tag != "" && !hasTag(a.TagList, f.Tag) {
+		return false
+	}
+	if f.FavoritedBy != 0 {
+		if _, ok := repo.favorites[a.ID][f.FavoritedBy]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+func hasID(ids []uint32, id uint32) bool {
+	for _, other := range ids {
+		if other == id {
+			return true
+		}
+	}
+	return false
+}
+
+func hasTag(tags []string, tag string) bool {
+	for _, t := range tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+func (repo *MemoryRepo) Update(a *Article) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	i := repo.index(a.ID)
+	if i < 0 {
+		return ErrNotFound
+	}
+	if repo.slugTaken(a) {
+		return ErrSlugTaken
+	}
+	repo.articles[i] = copyArticle(a)
+	return nil
+}
+
+func (repo *MemoryRepo) Delete(id uint32) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	i := repo.index(id)
+	if i < 0 {
+		return ErrNotFound
+	}
+	repo.articles = append(repo.articles[:i], repo.articles[i+1:]...)
+	delete(repo.favorites, id)
+	return nil
+}
+
+func (repo *MemoryRepo) Favorite(articleID, userID uint32) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	if repo.index(articleID) < 0 {
+		return ErrNotFound
+	}
+	users, ok := repo.favorites[articleID]
+	if !ok {
+		users = make(map[uint32]struct{})
+		repo.favorites[articleID] = users
+	}
+	users[userID] = struct{}{}
+	return nil
+}
+
+func (repo *MemoryRepo) Unfavorite(articleID, userID uint32) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	if repo.index(articleID) < 0 {
+		return ErrNotFound
+	}
+	delete(repo.favorites[articleID], userID)
+	return nil
+}
+
+func (repo *MemoryRepo) Favorites(articleID, userID uint32) (int, bool, error) {
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+
+	users := repo.favorites[articleID]
+	_, favorited := users[userID]
+	return len(users), favorited, nil
+}
+
+func (repo *MemoryRepo) index(id uint32) int {
+	for i, a := range repo.articles {
+		if a.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// copyArticle копия вместе с тегами, чтобы снаружи не поменять хранимый слайс
+func copyArticle(a *Article) *Article {
+	res := *a
+	res.TagList = append([]string(nil), a.TagList...)
+	return &res
+}
@@ -0,0 +1,414 @@
+package article
+
+import (
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"rwa/pkg/user"
+	"rwa/pkg/validation"
+)
+
+const (
+	defaultLimit = 20
+	maxLimit     = 100
+)
+
+// Item статья глазами пользователя вместе с автором, в таком виде её отдаёт
+// usecase
+type Item struct {
+	*Article
+	Author         *user.Profile
+	Favorited      bool
+	FavoritesCount int
+}
+
+type CommentItem struct {
+	*Comment
+	Author *user.Profile
+}
+
+type NewArticle struct {
+	Title       string
+	Description string
+	Body        string
+	TagList     []string
+}
+
+// Update изменения статьи, nil поля не меняются
+type Update struct {
+	Title       *string
+	Description *string
+	Body        *string
+	TagList     *[]string
+}
+
+// Query параметры списка статей, Author и Favorited это username
+type Query struct {
+	Tag       string
+	Author    string
+	Favorited string
+	Limit     int
+	Offset    int
+}
+
+// Profiles то, что статьям нужно от пользователей, его реализует
+// user.Usecase
+type Profiles interface {
+	ProfileByID(viewerID, id uint32) (*user.Profile, error)
+	GetByUsername(username string) (*user.User, error)
+	FollowingIDs(viewerID uint32) ([]uint32, error)
+}
+
+// Usecase статьи, избранное и комментарии. viewerID во всех методах это
+// текущий пользователь, 0 значит аноним: от него зависят Favorited и
+// Following автора
+type Usecase struct {
+	repo     Repo
+	comments CommentRepo
+	profiles Profiles
+}
+
+func NewUsecase(repo Repo, comments CommentRepo, profiles Profiles) *Usecase {
+	return &Usecase{
+		repo:     repo,
+		comments: comments,
+		profiles: profiles,
+	}
+}
+
+func (uc *Usecase) Create(authorID uint32, in *NewArticle) (*Item, error) {
+	err := validation.First(
+		validation.Required("title", in.Title),
+		validation.Required("description", in.Description),
+		validation.Required("body", in.Body),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	a := &Article{
+		Title:       in.Title,
+		Description: in.Description,
+		Body:        in.Body,
+		TagList:     cleanTags(in.TagList),
+		AuthorID:    authorID,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	err = uc.withUniqueSlug(a, func() error {
+		var err error
+		a.ID, err = uc.repo.Add(a)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return uc.item(authorID, a)
+}
+
+func (uc *Usecase) Get(viewerID uint32, slug string) (*Item, error) {
+	a, err := uc.repo.GetBySlug(slug)
+	if err != nil {
+		return nil, err
+	}
+	return uc.item(viewerID, a)
+}
+
+// List возвращает статьи страницы и сколько всего статей подходит под q
+func (uc *Usecase) List(viewerID uint32, q *Query) ([]*Item, int, error) {
+	f := Filter{
+		Tag:    q.Tag,
+		Limit:  q.Limit,
+		Offset: q.Offset,
+	}
+	if q.Author != "" {
+		author, err := uc.profiles.GetByUsername(q.Author)
+		if err == user.ErrNotFound {
+			return []*Item{}, 0, nil
+		}
+		if err != nil {
+			return nil, 0, err
+		}
+		f.AuthorIDs = []uint32{author.ID}
+	}
+	if q.Favorited != "" {
+		fan, err := uc.profiles.GetByUsername(q.Favorited)
+		if err == user.ErrNotFound {
+			return []*Item{}, 0, nil
+		}
+		if err != nil {
+			return nil, 0, err
+		}
+		f.FavoritedBy = fan.ID
+	}
+	return uc.list(viewerID, f)
+}
+
+// Feed статьи авторов, на которых подписан viewerID
+func (uc *Usecase) Feed(viewerID uint32, limit, offset int) ([]*Item, int, error) {
+	ids, err := uc.profiles.FollowingIDs(viewerID)
+	if err != nil {
+		return nil, 0, err
+	}
+	if ids == nil {
+		ids = []uint32{}
+	}
+	return uc.list(viewerID, Filter{
+		AuthorIDs: ids,
+		Limit:     limit,
+		Offset:    offset,
+	})
+}
+
+func (uc *Usecase) list(viewerID uint32, f Filter) ([]*Item, int, error) {
+	if f.Limit <= 0 {
+		f.Limit = defaultLimit
+	}
+	if f.Limit > maxLimit {
+		f.Limit = maxLimit
+	}
+	if f.Offset < 0 {
+		f.Offset = 0
+	}
+
+	articles, total, err := uc.repo.List(f)
+	if err != nil {
+		return nil, 0, err
+	}
+	items := make([]*Item, 0, len(articles))
+	for _, a := range articles {
+		item, err := uc.item(viewerID, a)
+		if err != nil {
+			return nil, 0, err
+		}
+		items = append(items, item)
+	}
+	return items, total, nil
+}
+
+// Update меняет статью, это может только её автор. Со сменой заголовка
+// меняется и slug
+func (uc *Usecase) Update(userID uint32, slug string, upd *Update) (*Item, error) {
+	a, err := uc.repo.GetBySlug(slug)
+	if err != nil {
+		return nil, err
+	}
+	if a.AuthorID != userID {
+		return nil, ErrForbidden
+	}
+
+	titleChanged := false
+	if upd.Title != nil {
+		if err := validation.Required("title", *upd.Title); err != nil {
+			return nil, err
+		}
+		titleChanged = *upd.Title != a.Title
+		a.Title = *upd.Title
+	}
+	if upd.Description != nil {
+		if err := validation.Required("description", *upd.Description); err != nil {
+			return nil, err
+		}
+		a.Description = *upd.Description
+	}
+	if upd.Body != nil {
+		if err := validation.Required("body", *upd.Body); err != nil {
+			return nil, err
+		}
+		a.Body = *upd.Body
+	}
+	if upd.TagList != nil {
+		a.TagList = cleanTags(*upd.TagList)
+	}
+	a.UpdatedAt = time.Now().UTC()
+
+	save := func() error {
+		return uc.repo.Update(a)
+	}
+	if titleChanged {
+		err = uc.withUniqueSlug(a, save)
+	} else {
+		err = save()
+	}
+	if err != nil {
+		return nil, err
+	}
+	return uc.item(userID, a)
+}
+
+// Delete удаляет статью вместе с комментариями, это может только её автор
+func (uc *Usecase) Delete(userID uint32, slug string) error {
+	a, err := uc.repo.GetBySlug(slug)
+	if err != nil {
+		return err
+	}
+	if a.AuthorID != userID {
+		return ErrForbidden
+	}
+	if err := uc.repo.Delete(a.ID); err != nil {
+		return err
+	}
+	return uc.comments.DeleteByArticle(a.ID)
+}
+
+func (uc *Usecase) Favorite(userID uint32, slug string) (*Item, error) {
+	a, err := uc.repo.GetBySlug(slug)
+	if err != nil {
+		return nil, err
+	}
+	if err := uc.repo.Favorite(a.ID, userID); err != nil {
+		return nil, err
+	}
+	return uc.item(userID, a)
+}
+
+func (uc *Usecase) Unfavorite(userID uint32, slug string) (*Item, error) {
+	a, err := uc.repo.GetBySlug(slug)
+	if err != nil {
+		return nil, err
+	}
+	if err := uc.repo.Unfavorite(a.ID, userID); err != nil {
+		return nil, err
+	}
+	return uc.item(userID, a)
+}
+
+func (uc *Usecase) item(viewerID uint32, a *Article) (*Item, error) {
+	author, err := uc.profiles.ProfileByID(viewerID, a.AuthorID)
+	if err != nil {
+		return nil, err
+	}
+	count, favorited, err := uc.repo.Favorites(a.ID, viewerID)
+	if err != nil {
+		return nil, err
+	}
+	return &Item{
+		Article:        a,
+		Author:         author,
+		Favorited:      favorited,
+		FavoritesCount: count,
+	}, nil
+}
+
+func (uc *Usecase) AddComment(userID uint32, slug, body string) (*CommentItem, error) {
+	if err := validation.Required("body", body); err != nil {
+		return nil, err
+	}
+	a, err := uc.repo.GetBySlug(slug)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	c := &Comment{
+		ArticleID: a.ID,
+		AuthorID:  userID,
+		Body:      body,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	c.ID, err = uc.comments.Add(c)
+	if err != nil {
+		return nil, err
+	}
+	return uc.commentItem(userID, c)
+}
+
+func (uc *Usecase) Comments(viewerID uint32, slug string) ([]*CommentItem, error) {
+	a, err := uc.repo.GetBySlug(slug)
+	if err != nil {
+		return nil, err
+	}
+	comments, err := uc.comments.ListByArticle(a.ID)
+	if err != nil {
+		return nil, err
+	}
+	items := make([]*CommentItem, 0, len(comments))
+	for _, c := range comments {
+		item, err := uc.commentItem(viewerID, c)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+// DeleteComment удаляет комментарий, это может только его автор
+func (uc *Usecase) DeleteComment(userID uint32, slug string, id uint32) error {
+	a, err := uc.repo.GetBySlug(slug)
+	if err != nil {
+		return err
+	}
+	c, err := uc.comments.GetByID(id)
+	if err != nil {
+		return err
+	}
+	if c.ArticleID != a.ID {
+		return ErrCommentNotFound
+	}
+	if c.AuthorID != userID {
+		return ErrCommentForbidden
+	}
+	return uc.comments.Delete(id)
+}
+
+func (uc *Usecase) commentItem(viewerID uint32, c *Comment) (*CommentItem, error) {
+	author, err := uc.profiles.ProfileByID(viewerID, c.AuthorID)
+	if err != nil {
+		return nil, err
+	}
+	return &CommentItem{Comment: c, Author: author}, nil
+}
+
+// withUniqueSlug подбирает slug из заголовка: how-to-write, how-to-write-2 и
+// так далее, пока save не перестанет возвращать ErrSlugTaken
+func (uc *Usecase) withUniqueSlug(a *Article, save func() error) error {
+	base := slugify(a.Title)
+	a.Slug = base
+	for n := 2; ; n++ {
+		err := save()
+		if err != ErrSlugTaken {
+			return err
+		}
+		a.Slug = base + "-" + strconv.Itoa(n)
+	}
+}
+
+func slugify(title string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(title) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if dash && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			b.WriteRune(r)
+			dash = false
+		} else {
+			dash = true
+		}
+	}
+	if b.Len() == 0 {
+		return "article"
+	}
+	return b.String()
+}
+
+// cleanTags убирает пустые и повторяющиеся теги, порядок сохраняется
+func cleanTags(tags []string) []string {
+	res := make([]string, 0, len(tags))
+	seen := make(map[string]struct{}, len(tags))
+	for _, tag := range tags {
+		tag = strings.TrimSpace(tag)
+		if _, ok := seen[tag]; ok || tag == "" {
+			continue
+		}
+		seen[tag] = struct{}{}
+		res = append(res, tag)
+	}
+	return res
+}
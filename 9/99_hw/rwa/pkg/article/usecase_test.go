@@ -0,0 +1,40 @@
+package article
+
+import (
+	"testing"
+
+	"rwa/pkg/user"
+)
+
+// удаление статьи уносит ее комментарии, снаружи это не видно: статьи с тем
+// же id больше не будет
+func TestDeleteRemovesComments(t *testing.T) {
+	users := user.NewUsecase(user.NewMemoryRepo())
+	alice, err := users.Register("alice@example.com", "alice", "love")
+	if err != nil {
+		t.Fatalf("cant register: %v", err)
+	}
+	comments := NewCommentMemoryRepo()
+	uc := NewUsecase(NewMemoryRepo(), comments, users)
+
+	item, err := uc.Create(alice.ID, &NewArticle{Title: "title", Description: "about", Body: "body"})
+	if err != nil {
+		t.Fatalf("cant create article: %v", err)
+	}
+	for _, body := range []string{"first", "second"} {
+		if _, err := uc.AddComment(alice.ID, item.Slug, body); err != nil {
+			t.Fatalf("cant add comment: %v", err)
+		}
+	}
+
+	if err := uc.Delete(alice.ID, item.Slug); err != nil {
+		t.Fatalf("cant delete article: %v", err)
+	}
+	left, err := comments.ListByArticle(item.ID)
+	if err != nil {
+		t.Fatalf("cant list comments: %v", err)
+	}
+	if len(left) != 0 {
+		t.Fatalf("expected comments to be deleted with the article, got %d", len(left))
+	}
+}
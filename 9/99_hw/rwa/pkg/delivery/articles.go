@@ -0,0 +1,221 @@
+package delivery
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"rwa/pkg/article"
+	"rwa/pkg/session"
+)
+
+type articleJSON struct {
+	Slug           string       `json:"slug"`
+	Title          string       `json:"title"`
+	Description    string       `json:"description"`
+	Body           string       `json:"body"`
+	TagList        []string     `json:"tagList"`
+	CreatedAt      time.Time    `json:"createdAt"`
+	UpdatedAt      time.Time    `json:"updatedAt"`
+	Favorited      bool         `json:"favorited"`
+	FavoritesCount int          `json:"favoritesCount"`
+	Author         *profileJSON `json:"author"`
+}
+
+func newArticleJSON(item *article.Item) *articleJSON {
+	tags := item.TagList
+	if tags == nil {
+		tags = []string{}
+	}
+	return &articleJSON{
+		Slug:           item.Slug,
+		Title:          item.Title,
+		Description:    item.Description,
+		Body:           item.Body,
+		TagList:        tags,
+		CreatedAt:      item.CreatedAt,
+		UpdatedAt:      item.UpdatedAt,
+		Favorited:      item.Favorited,
+		FavoritesCount: item.FavoritesCount,
+		Author:         newProfileJSON(item.Author),
+	}
+}
+
+type articleResponse struct {
+	Article *articleJSON `json:"article"`
+}
+
+type articlesResponse struct {
+	Articles      []*articleJSON `json:"articles"`
+	ArticlesCount int            `json:"articlesCount"`
+}
+
+func newArticlesResponse(items []*article.Item, total int) *articlesResponse {
+	resp := &articlesResponse{
+		Articles:      make([]*articleJSON, 0, len(items)),
+		ArticlesCount: total,
+	}
+	for _, item := range items {
+		resp.Articles = append(resp.Articles, newArticleJSON(item))
+	}
+	return resp
+}
+
+type newArticleRequest struct {
+	Article struct {
+		Title       string   `json:"title"`
+		Description string   `json:"description"`
+		Body        string   `json:"body"`
+		TagList     []string `json:"tagList"`
+	} `json:"article"`
+}
+
+type updateArticleRequest struct {
+	Article struct {
+		Title       *string   `json:"title"`
+		Description *string   `json:"description"`
+		Body        *string   `json:"body"`
+		TagList     *[]string `json:"tagList"`
+	} `json:"article"`
+}
+
+type ArticleHandler struct {
+	Articles *article.Usecase
+}
+
+func (h *ArticleHandler) List(w http.ResponseWriter, r *http.Request) {
+	params := r.URL.Query()
+	q := &article.Query{
+		Tag:       params.Get("tag"),
+		Author:    params.Get("author"),
+		Favorited: params.Get("favorited"),
+	}
+	var ok bool
+	if q.Limit, q.Offset, ok = pageParams(w, r); !ok {
+		return
+	}
+
+	items, total, err := h.Articles.List(viewerID(r), q)
+	if err != nil {
+		handleError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, newArticlesResponse(items, total))
+}
+
+// Feed статьи авторов, на которых подписан текущий пользователь
+func (h *ArticleHandler) Feed(w http.ResponseWriter, r *http.Request) {
+	limit, offset, ok := pageParams(w, r)
+	if !ok {
+		return
+	}
+	sess, _ := session.FromContext(r.Context())
+	items, total, err := h.Articles.Feed(sess.UserID, limit, offset)
+	if err != nil {
+		handleError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, newArticlesResponse(items, total))
+}
+
+func pageParams(w http.ResponseWriter, r *http.Request) (limit, offset int, ok bool) {
+	params := r.URL.Query()
+	if limit, ok = intParam(w, params.Get("limit"), "limit"); !ok {
+		return 0, 0, false
+	}
+	if offset, ok = intParam(w, params.Get("offset"), "offset"); !ok {
+		return 0, 0, false
+	}
+	return limit, offset, true
+}
+
+// intParam разбирает необязательный числовой параметр запроса
+func intParam(w http.ResponseWriter, value, name string) (int, bool) {
+	if value == "" {
+		return 0, true
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 {
+		writeError(w, http.StatusUnprocessableEntity, name+" is invalid")
+		return 0, false
+	}
+	return n, true
+}
+
+func (h *ArticleHandler) Create(w http.ResponseWriter, r *http.Request) {
+	req := &newArticleRequest{}
+	if !readJSON(w, r, req) {
+		return
+	}
+	sess, _ := session.FromContext(r.Context())
+	item, err := h.Articles.Create(sess.UserID, &article.NewArticle{
+		Title:       req.Article.Title,
+		Description: req.Article.Description,
+		Body:        req.Article.Body,
+		TagList:     req.Article.TagList,
+	})
+	if err != nil {
+		handleError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, &articleResponse{Article: newArticleJSON(item)})
+}
+
+func (h *ArticleHandler) Get(w http.ResponseWriter, r *http.Request) {
+	item, err := h.Articles.Get(viewerID(r), mux.Vars(r)["slug"])
+	if err != nil {
+		handleError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, &articleResponse{Article: newArticleJSON(item)})
+}
+
+func (h *ArticleHandler) Update(w http.ResponseWriter, r *http.Request) {
+	req := &updateArticleRequest{}
+	if !readJSON(w, r, req) {
+		return
+	}
+	sess, _ := session.FromContext(r.Context())
+	item, err := h.Articles.Update(sess.UserID, mux.Vars(r)["slug"], &article.Update{
+		Title:       req.Article.Title,
+		Description: req.Article.Description,
+		Body:        req.Article.Body,
+		TagList:     req.Article.TagList,
+	})
+	if err != nil {
+		handleError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, &articleResponse{Article: newArticleJSON(item)})
+}
+
+func (h *ArticleHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	sess, _ := session.FromContext(r.Context())
+	if err := h.Articles.Delete(sess.UserID, mux.Vars(r)["slug"]); err != nil {
+		handleError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+func (h *ArticleHandler) Favorite(w http.ResponseWriter, r *http.Request) {
+	sess, _ := session.FromContext(r.Context())
+	item, err := h.Articles.Favorite(sess.UserID, mux.Vars(r)["slug"])
+	if err != nil {
+		handleError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, &articleResponse{Article: newArticleJSON(item)})
+}
+
+func (h *ArticleHandler) Unfavorite(w http.ResponseWriter, r *http.Request) {
+	sess, _ := session.FromContext(r.Context())
+	item, err := h.Articles.Unfavorite(sess.UserID, mux.Vars(r)["slug"])
+	if err != nil {
+		handleError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, &articleResponse{Article: newArticleJSON(item)})
+}
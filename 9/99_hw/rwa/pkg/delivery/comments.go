@@ -0,0 +1,88 @@
+package delivery
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"rwa/pkg/article"
+	"rwa/pkg/session"
+)
+
+type commentJSON struct {
+	ID        uint32       `json:"id"`
+	CreatedAt time.Time    `json:"createdAt"`
+	UpdatedAt time.Time    `json:"updatedAt"`
+	Body      string       `json:"body"`
+	Author    *profileJSON `json:"author"`
+}
+
+func newCommentJSON(item *article.CommentItem) *commentJSON {
+	return &commentJSON{
+		ID:        item.ID,
+		CreatedAt: item.CreatedAt,
+		UpdatedAt: item.UpdatedAt,
+		Body:      item.Body,
+		Author:    newProfileJSON(item.Author),
+	}
+}
+
+type commentResponse struct {
+	Comment *commentJSON `json:"comment"`
+}
+
+type commentsResponse struct {
+	Comments []*commentJSON `json:"comments"`
+}
+
+type newCommentRequest struct {
+	Comment struct {
+		Body string `json:"body"`
+	} `json:"comment"`
+}
+
+func (h *ArticleHandler) Comments(w http.ResponseWriter, r *http.Request) {
+	items, err := h.Articles.Comments(viewerID(r), mux.Vars(r)["slug"])
+	if err != nil {
+		handleError(w, err)
+		return
+	}
+	resp := &commentsResponse{
+		Comments: make([]*commentJSON, 0, len(items)),
+	}
+	for _, item := range items {
+		resp.Comments = append(resp.Comments, newCommentJSON(item))
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (h *ArticleHandler) AddComment(w http.ResponseWriter, r *http.Request) {
+	req := &newCommentRequest{}
+	if !readJSON(w, r, req) {
+		return
+	}
+	sess, _ := session.FromContext(r.Context())
+	item, err := h.Articles.AddComment(sess.UserID, mux.Vars(r)["slug"], req.Comment.Body)
+	if err != nil {
+		handleError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, &commentResponse{Comment: newCommentJSON(item)})
+}
+
+func (h *ArticleHandler) DeleteComment(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.ParseUint(vars["id"], 10, 32)
+	if err != nil {
+		handleError(w, article.ErrCommentNotFound)
+		return
+	}
+	sess, _ := session.FromContext(r.Context())
+	if err := h.Articles.DeleteComment(sess.UserID, vars["slug"], uint32(id)); err != nil {
+		handleError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
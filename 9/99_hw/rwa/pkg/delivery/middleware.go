@@ -0,0 +1,54 @@
+package delivery
+
+import (
+	"net/http"
+	"strings"
+
+	"rwa/pkg/session"
+)
+
+const tokenPrefix = "Token "
+
+// AuthMiddleware находит сессию по заголовку Authorization: Token <token> и
+// кладёт её в контекст. Запрос без токена или с чужим токеном проходит
+// анонимным, закрытые ручки оборачиваются в RequireAuth
+func AuthMiddleware(sm *session.Manager) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			header := r.Header.Get("Authorization")
+			if !strings.HasPrefix(header, tokenPrefix) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			token := strings.TrimSpace(strings.TrimPrefix(header, tokenPrefix))
+			sess, err := sm.Check(token)
+			if err != nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+			ctx := session.ContextWithSession(r.Context(), sess, token)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// RequireAuth отвечает 401 если AuthMiddleware не нашёл сессию
+func RequireAuth(next http.HandlerFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, err := session.FromContext(r.Context()); err != nil {
+			handleError(w, err)
+			return
+		}
+		next(w, r)
+	})
+}
+
+// viewerID пользователь запроса для ручек, открытых и анонимам, 0 если
+// сессии нет
+func viewerID(r *http.Request) uint32 {
+	sess, err := session.FromContext(r.Context())
+	if err != nil {
+		return 0
+	}
+	return sess.UserID
+}
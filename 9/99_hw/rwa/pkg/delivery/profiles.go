@@ -0,0 +1,63 @@
+package delivery
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+
+	"rwa/pkg/session"
+	"rwa/pkg/user"
+)
+
+type profileJSON struct {
+	Username  string `json:"username"`
+	Bio       string `json:"bio"`
+	Image     string `json:"image"`
+	Following bool   `json:"following"`
+}
+
+func newProfileJSON(p *user.Profile) *profileJSON {
+	return &profileJSON{
+		Username:  p.Username,
+		Bio:       p.Bio,
+		Image:     p.Image,
+		Following: p.Following,
+	}
+}
+
+type profileResponse struct {
+	Profile *profileJSON `json:"profile"`
+}
+
+type ProfileHandler struct {
+	Users *user.Usecase
+}
+
+func (h *ProfileHandler) Get(w http.ResponseWriter, r *http.Request) {
+	p, err := h.Users.Profile(viewerID(r), mux.Vars(r)["username"])
+	if err != nil {
+		handleError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, &profileResponse{Profile: newProfileJSON(p)})
+}
+
+func (h *ProfileHandler) Follow(w http.ResponseWriter, r *http.Request) {
+	sess, _ := session.FromContext(r.Context())
+	p, err := h.Users.Follow(sess.UserID, mux.Vars(r)["username"])
+	if err != nil {
+		handleError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, &profileResponse{Profile: newProfileJSON(p)})
+}
+
+func (h *ProfileHandler) Unfollow(w http.ResponseWriter, r *http.Request) {
+	sess, _ := session.FromContext(r.Context())
+	p, err := h.Users.Unfollow(sess.UserID, mux.Vars(r)["username"])
+	if err != nil {
+		handleError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, &profileResponse{Profile: newProfileJSON(p)})
+}
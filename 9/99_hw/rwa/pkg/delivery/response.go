@@ -0,0 +1,82 @@
+package delivery
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+
+	"rwa/pkg/article"
+	"rwa/pkg/session"
+	"rwa/pkg/user"
+	"rwa/pkg/validation"
+)
+
+const maxBodySize = 1 << 20
+
+// errorResponse GenericErrorModel из swagger.json
+type errorResponse struct {
+	Errors struct {
+		Body []string `json:"body"`
+	} `json:"errors"`
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	resp, err := json.Marshal(body)
+	if err != nil {
+		log.Println("cant marshal response:", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	w.Write(resp)
+}
+
+func writeError(w http.ResponseWriter, status int, msg string) {
+	resp := &errorResponse{}
+	resp.Errors.Body = []string{msg}
+	writeJSON(w, status, resp)
+}
+
+// handleError переводит ошибки usecase в статусы, неизвестные ошибки
+// логируются и наружу не отдаются
+func handleError(w http.ResponseWriter, err error) {
+	var validationErr *validation.Error
+	switch {
+	case errors.As(err, &validationErr):
+		writeError(w, http.StatusUnprocessableEntity, err.Error())
+	case errors.Is(err, user.ErrEmailTaken),
+		errors.Is(err, user.ErrUsernameTaken),
+		errors.Is(err, user.ErrFollowSelf):
+		writeError(w, http.StatusUnprocessableEntity, err.Error())
+	case errors.Is(err, user.ErrBadCredentials),
+		errors.Is(err, session.ErrNoAuth):
+		writeError(w, http.StatusUnauthorized, err.Error())
+	case errors.Is(err, article.ErrForbidden),
+		errors.Is(err, article.ErrCommentForbidden):
+		writeError(w, http.StatusForbidden, err.Error())
+	case errors.Is(err, user.ErrNotFound),
+		errors.Is(err, article.ErrNotFound),
+		errors.Is(err, article.ErrCommentNotFound):
+		writeError(w, http.StatusNotFound, err.Error())
+	default:
+		log.Println("internal error:", err)
+		writeError(w, http.StatusInternalServerError, "internal error")
+	}
+}
+
+// readJSON распаковывает тело запроса в dst, при ошибке сам отвечает 400
+func readJSON(w http.ResponseWriter, r *http.Request, dst interface{}) bool {
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxBodySize))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "cant read body")
+		return false
+	}
+	if err := json.Unmarshal(body, dst); err != nil {
+		writeError(w, http.StatusBadRequest, "cant unpack payload")
+		return false
+	}
+	return true
+}
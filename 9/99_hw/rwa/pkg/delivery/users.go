@@ -0,0 +1,137 @@
+package delivery
+
+import (
+	"net/http"
+	"time"
+
+	"rwa/pkg/session"
+	"rwa/pkg/user"
+)
+
+type userJSON struct {
+	Email     string    `json:"email"`
+	Token     string    `json:"token"`
+	Username  string    `json:"username"`
+	Bio       string    `json:"bio"`
+	Image     string    `json:"image"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+type userResponse struct {
+	User *userJSON `json:"user"`
+}
+
+func newUserResponse(u *user.User, token string) *userResponse {
+	return &userResponse{
+		User: &userJSON{
+			Email:     u.Email,
+			Token:     token,
+			Username:  u.Username,
+			Bio:       u.Bio,
+			Image:     u.Image,
+			CreatedAt: u.CreatedAt,
+			UpdatedAt: u.UpdatedAt,
+		},
+	}
+}
+
+type credentialsRequest struct {
+	User struct {
+		Email    string `json:"email"`
+		Username string `json:"username"`
+		Password string `json:"password"`
+	} `json:"user"`
+}
+
+type updateUserRequest struct {
+	User struct {
+		Email    *string `json:"email"`
+		Username *string `json:"username"`
+		Password *string `json:"password"`
+		Bio      *string `json:"bio"`
+		Image    *string `json:"image"`
+	} `json:"user"`
+}
+
+type UserHandler struct {
+	Users    *user.Usecase
+	Sessions *session.Manager
+}
+
+// Register создаёт пользователя и сразу открывает ему сессию
+func (h *UserHandler) Register(w http.ResponseWriter, r *http.Request) {
+	req := &credentialsRequest{}
+	if !readJSON(w, r, req) {
+		return
+	}
+	u, err := h.Users.Register(req.User.Email, req.User.Username, req.User.Password)
+	if err != nil {
+		handleError(w, err)
+		return
+	}
+	h.respondWithSession(w, http.StatusCreated, u)
+}
+
+func (h *UserHandler) Login(w http.ResponseWriter, r *http.Request) {
+	req := &credentialsRequest{}
+	if !readJSON(w, r, req) {
+		return
+	}
+	u, err := h.Users.Login(req.User.Email, req.User.Password)
+	if err != nil {
+		handleError(w, err)
+		return
+	}
+	h.respondWithSession(w, http.StatusOK, u)
+}
+
+func (h *UserHandler) respondWithSession(w http.ResponseWriter, status int, u *user.User) {
+	token, _, err := h.Sessions.Create(u.ID)
+	if err != nil {
+		handleError(w, err)
+		return
+	}
+	writeJSON(w, status, newUserResponse(u, token))
+}
+
+func (h *UserHandler) Current(w http.ResponseWriter, r *http.Request) {
+	sess, _ := session.FromContext(r.Context())
+	u, err := h.Users.Get(sess.UserID)
+	if err != nil {
+		handleError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, newUserResponse(u, session.TokenFromContext(r.Context())))
+}
+
+func (h *UserHandler) Update(w http.ResponseWriter, r *http.Request) {
+	req := &updateUserRequest{}
+	if !readJSON(w, r, req) {
+		return
+	}
+	sess, _ := session.FromContext(r.Context())
+	u, err := h.Users.Update(sess.UserID, &user.Update{
+		Email:    req.User.Email,
+		Username: req.User.Username,
+		Password: req.User.Password,
+		Bio:      req.User.Bio,
+		Image:    req.User.Image,
+	})
+	if err != nil {
+		handleError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, newUserResponse(u, session.TokenFromContext(r.Context())))
+}
+
+// Logout закрывает только текущую сессию, остальные токены пользователя
+// продолжают работать
+func (h *UserHandler) Logout(w http.ResponseWriter, r *http.Request) {
+	sess, _ := session.FromContext(r.Context())
+	if err := h.Sessions.Destroy(sess); err != nil {
+		handleError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
@@ -0,0 +1,45 @@
+package session
+
+import (
+	"sync"
+)
+
+type MemoryRepo struct {
+	mu       sync.RWMutex
+	sessions map[string]*Session
+}
+
+func NewMemoryRepo() *MemoryRepo {
+	return &MemoryRepo{
+		sessions: make(map[string]*Session),
+	}
+}
+
+func (repo *MemoryRepo) Add(sess *Session) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	stored := *sess
+	repo.sessions[sess.ID] = &stored
+	return nil
+}
+
+func (repo *MemoryRepo) Get(id string) (*Session, error) {
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+
+	sess, ok := repo.sessions[id]
+	if !ok {
+		return nil, ErrNoAuth
+	}
+	res := *sess
+	return &res, nil
+}
+
+func (repo *MemoryRepo) Delete(id string) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	delete(repo.sessions, id)
+	return nil
+}
@@ -0,0 +1,102 @@
+package session
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"time"
+)
+
+var (
+	ErrNoAuth = errors.New("no session found")
+)
+
+// Session ID это sha256 от токена: токен знает только клиент, и утечка
+// хранилища не даёт чужих сессий
+type Session struct {
+	ID        string
+	UserID    uint32
+	CreatedAt time.Time
+}
+
+type Repo interface {
+	Add(sess *Session) error
+	Get(id string) (*Session, error)
+	Delete(id string) error
+}
+
+// Manager выдаёт токены и находит по ним сессии, сами сессии лежат в Repo,
+// так что logout сразу делает токен недействительным
+type Manager struct {
+	repo Repo
+}
+
+func NewManager(repo Repo) *Manager {
+	return &Manager{
+		repo: repo,
+	}
+}
+
+// Create открывает сессию и возвращает её токен для заголовка Authorization
+func (sm *Manager) Create(userID uint32) (string, *Session, error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", nil, err
+	}
+	token := hex.EncodeToString(raw)
+	sess := &Session{
+		ID:        sessionID(token),
+		UserID:    userID,
+		CreatedAt: time.Now(),
+	}
+	if err := sm.repo.Add(sess); err != nil {
+		return "", nil, err
+	}
+	return token, sess, nil
+}
+
+func (sm *Manager) Check(token string) (*Session, error) {
+	if token == "" {
+		return nil, ErrNoAuth
+	}
+	return sm.repo.Get(sessionID(token))
+}
+
+func (sm *Manager) Destroy(sess *Session) error {
+	return sm.repo.Delete(sess.ID)
+}
+
+func sessionID(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// линтер ругается если используем базовые типы в Value контекста
+type ctxKey int
+
+const (
+	sessionKey ctxKey = iota
+	tokenKey
+)
+
+// ContextWithSession кладёт в контекст сессию и токен, по которому она
+// найдена, токен отдаётся обратно в ответах с пользователем
+func ContextWithSession(ctx context.Context, sess *Session, token string) context.Context {
+	ctx = context.WithValue(ctx, sessionKey, sess)
+	return context.WithValue(ctx, tokenKey, token)
+}
+
+func FromContext(ctx context.Context) (*Session, error) {
+	sess, ok := ctx.Value(sessionKey).(*Session)
+	if !ok {
+		return nil, ErrNoAuth
+	}
+	return sess, nil
+}
+
+func TokenFromContext(ctx context.Context) string {
+	token, _ := ctx.Value(tokenKey).(string)
+	return token
+}
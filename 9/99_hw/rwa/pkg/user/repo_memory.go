@@ -0,0 +1,151 @@
+package user
+
+import (
+	"sort"
+	"strings"
+	"sync"
+)
+
+// MemoryRepo хранит пользователей в памяти. Наружу отдаются копии, чтобы
+// вызывающий код не менял записи в обход Update
+type MemoryRepo struct {
+	mu     sync.RWMutex
+	lastID uint32
+	users  map[uint32]*User
+	// follows подписки: follower -> множество followee
+	follows map[uint32]map[uint32]struct{}
+}
+
+func NewMemoryRepo() *MemoryRepo {
+	return &MemoryRepo{
+		users:   make(map[uint32]*User),
+		follows: make(map[uint32]map[uint32]struct{}),
+	}
+}
+
+func (repo *MemoryRepo) Add(u *User) (uint32, error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	if err := repo.checkUnique(u); err != nil {
+		return 0, err
+	}
+	repo.lastID++
+	stored := *u
+	stored.ID = repo.lastID
+	repo.users[stored.ID] = &stored
+	return stored.ID, nil
+}
+
+// checkUnique ищет другого пользователя с тем же email или username, почта
+// сравнивается без учёта регистра
+func (repo *MemoryRepo) checkUnique(u *User) error {
+	for _, other := range repo.users {
+		if other.ID == u.ID {
+			continue
+		}
+		if strings.EqualFold(other.Email, u.Email) {
+			return ErrEmailTaken
+		}
+		if other.Username == u.Username {
+			return ErrUsernameTaken
+		}
+	}
+	return nil
+}
+
+func (repo *MemoryRepo) GetByID(id uint32) (*User, error) {
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+
+	u, ok := repo.users[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	res := *u
+	return &res, nil
+}
+
+func (repo *MemoryRepo) GetByEmail(email string) (*User, error) {
+	return repo.find(func(u *User) bool {
+		return strings.EqualFold(u.Email, email)
+	})
+}
+
+func (repo *MemoryRepo) GetByUsername(username string) (*User, error) {
+	return repo.find(func(u *User) bool {
+		return u.Username == username
+	})
+}
+
+func (repo *MemoryRepo) find(match func(*User) bool) (*User, error) {
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+
+	for _, u := range repo.users {
+		if match(u) {
+			res := *u
+			return &res, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (repo *MemoryRepo) Update(u *User) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	if _, ok := repo.users[u.ID]; !ok {
+		return ErrNotFound
+	}
+	if err := repo.checkUnique(u); err != nil {
+		return err
+	}
+	stored := *u
+	repo.users[u.ID] = &stored
+	return nil
+}
+
+func (repo *MemoryRepo) Follow(followerID, followeeID uint32) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	if _, ok := repo.users[followeeID]; !ok {
+		return ErrNotFound
+	}
+	followees, ok := repo.follows[followerID]
+	if !ok {
+		followees = make(map[uint32]struct{})
+		repo.follows[followerID] = followees
+	}
+	followees[followeeID] = struct{}{}
+	return nil
+}
+
+func (repo *MemoryRepo) Unfollow(followerID, followeeID uint32) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	delete(repo.follows[followerID], followeeID)
+	return nil
+}
+
+func (repo *MemoryRepo) IsFollowing(followerID, followeeID uint32) (bool, error) {
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+
+	_, ok := repo.follows[followerID][followeeID]
+	return ok, nil
+}
+
+func (repo *MemoryRepo) Following(followerID uint32) ([]uint32, error) {
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+
+	ids := make([]uint32, 0, len(repo.follows[followerID]))
+	for id := range repo.follows[followerID] {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids, nil
+}
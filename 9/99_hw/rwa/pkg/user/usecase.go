@@ -0,0 +1,218 @@
+package user
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"fmt"
+	"time"
+
+	"golang.org/x/crypto/argon2"
+
+	"rwa/pkg/validation"
+)
+
+const saltLen = 8
+
+// Update изменения профиля, nil поля не меняются
+type Update struct {
+	Email    *string
+	Username *string
+	Password *string
+	Bio      *string
+	Image    *string
+}
+
+type Usecase struct {
+	repo Repo
+}
+
+func NewUsecase(repo Repo) *Usecase {
+	return &Usecase{
+		repo: repo,
+	}
+}
+
+func (uc *Usecase) Register(email, username, password string) (*User, error) {
+	err := validation.First(
+		validation.Email("email", email),
+		validation.Required("username", username),
+		validation.Required("password", password),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	hash, err := newPasswordHash(password)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now().UTC()
+	u := &User{
+		Email:     email,
+		Username:  username,
+		Password:  hash,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	u.ID, err = uc.repo.Add(u)
+	if err != nil {
+		return nil, err
+	}
+	return u, nil
+}
+
+// Login проверяет пароль, для неизвестной почты и неверного пароля ошибка
+// одна и та же - ErrBadCredentials
+func (uc *Usecase) Login(email, password string) (*User, error) {
+	err := validation.First(
+		validation.Required("email", email),
+		validation.Required("password", password),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	u, err := uc.repo.GetByEmail(email)
+	if err == ErrNotFound {
+		return nil, ErrBadCredentials
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !checkPassword(u.Password, password) {
+		return nil, ErrBadCredentials
+	}
+	return u, nil
+}
+
+func (uc *Usecase) Get(id uint32) (*User, error) {
+	return uc.repo.GetByID(id)
+}
+
+func (uc *Usecase) GetByUsername(username string) (*User, error) {
+	return uc.repo.GetByUsername(username)
+}
+
+func (uc *Usecase) Update(id uint32, upd *Update) (*User, error) {
+	u, err := uc.repo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if upd.Email != nil {
+		if err := validation.Email("email", *upd.Email); err != nil {
+			return nil, err
+		}
+		u.Email = *upd.Email
+	}
+	if upd.Username != nil {
+		if err := validation.Required("username", *upd.Username); err != nil {
+			return nil, err
+		}
+		u.Username = *upd.Username
+	}
+	if upd.Password != nil {
+		if err := validation.Required("password", *upd.Password); err != nil {
+			return nil, err
+		}
+		hash, err := newPasswordHash(*upd.Password)
+		if err != nil {
+			return nil, err
+		}
+		u.Password = hash
+	}
+	if upd.Bio != nil {
+		u.Bio = *upd.Bio
+	}
+	if upd.Image != nil {
+		u.Image = *upd.Image
+	}
+	u.UpdatedAt = time.Now().UTC()
+
+	if err := uc.repo.Update(u); err != nil {
+		return nil, err
+	}
+	return u, nil
+}
+
+// Profile пользователь username глазами viewerID, 0 значит аноним
+func (uc *Usecase) Profile(viewerID uint32, username string) (*Profile, error) {
+	u, err := uc.repo.GetByUsername(username)
+	if err != nil {
+		return nil, err
+	}
+	return uc.profile(viewerID, u)
+}
+
+func (uc *Usecase) ProfileByID(viewerID, id uint32) (*Profile, error) {
+	u, err := uc.repo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	return uc.profile(viewerID, u)
+}
+
+func (uc *Usecase) profile(viewerID uint32, u *User) (*Profile, error) {
+	if viewerID == 0 || viewerID == u.ID {
+		return &Profile{User: u}, nil
+	}
+	following, err := uc.repo.IsFollowing(viewerID, u.ID)
+	if err != nil {
+		return nil, err
+	}
+	return &Profile{User: u, Following: following}, nil
+}
+
+func (uc *Usecase) Follow(viewerID uint32, username string) (*Profile, error) {
+	u, err := uc.repo.GetByUsername(username)
+	if err != nil {
+		return nil, err
+	}
+	if u.ID == viewerID {
+		return nil, ErrFollowSelf
+	}
+	if err := uc.repo.Follow(viewerID, u.ID); err != nil {
+		return nil, err
+	}
+	return &Profile{User: u, Following: true}, nil
+}
+
+func (uc *Usecase) Unfollow(viewerID uint32, username string) (*Profile, error) {
+	u, err := uc.repo.GetByUsername(username)
+	if err != nil {
+		return nil, err
+	}
+	if err := uc.repo.Unfollow(viewerID, u.ID); err != nil {
+		return nil, err
+	}
+	return &Profile{User: u}, nil
+}
+
+// FollowingIDs id всех, на кого подписан viewerID
+func (uc *Usecase) FollowingIDs(viewerID uint32) ([]uint32, error) {
+	return uc.repo.Following(viewerID)
+}
+
+// newPasswordHash хеширует пароль со свежей солью
+func newPasswordHash(password string) ([]byte, error) {
+	salt := make([]byte, saltLen)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, fmt.Errorf("cant make salt: %w", err)
+	}
+	return hashPassword(password, salt), nil
+}
+
+// hashPassword возвращает соль и argon2 хеш пароля одним слайсом
+func hashPassword(password string, salt []byte) []byte {
+	hash := argon2.IDKey([]byte(password), salt, 1, 64*1024, 4, 32)
+	res := make([]byte, 0, len(salt)+len(hash))
+	res = append(res, salt...)
+	return append(res, hash...)
+}
+
+func checkPassword(stored []byte, password string) bool {
+	if len(stored) < saltLen {
+		return false
+	}
+	return subtle.ConstantTimeCompare(stored, hashPassword(password, stored[:saltLen])) == 1
+}
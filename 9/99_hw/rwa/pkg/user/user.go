@@ -0,0 +1,49 @@
+package user
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	ErrNotFound       = errors.New("user not found")
+	ErrEmailTaken     = errors.New("email has already been taken")
+	ErrUsernameTaken  = errors.New("username has already been taken")
+	ErrBadCredentials = errors.New("email or password is invalid")
+	ErrFollowSelf     = errors.New("cannot follow yourself")
+)
+
+type User struct {
+	ID       uint32
+	Email    string
+	Username string
+	Bio      string
+	Image    string
+	// Password соль и хеш пароля, см hashPassword
+	Password  []byte
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// Profile пользователь глазами другого пользователя
+type Profile struct {
+	*User
+	Following bool
+}
+
+// Repo хранилище пользователей и подписок между ними. Email и Username
+// уникальны, Add и Update проверяют это сами, чтобы проверка и запись были
+// атомарными. Повторные Follow и Unfollow ничего не меняют
+type Repo interface {
+	Add(u *User) (uint32, error)
+	GetByID(id uint32) (*User, error)
+	GetByEmail(email string) (*User, error)
+	GetByUsername(username string) (*User, error)
+	Update(u *User) error
+
+	Follow(followerID, followeeID uint32) error
+	Unfollow(followerID, followeeID uint32) error
+	IsFollowing(followerID, followeeID uint32) (bool, error)
+	// Following id всех, на кого подписан followerID
+	Following(followerID uint32) ([]uint32, error)
+}
@@ -0,0 +1,47 @@
+package validation
+
+import (
+	"net/mail"
+	"strings"
+)
+
+// Error некорректное значение поля во входных данных, delivery отдаёт его
+// как 422 Unprocessable Entity
+type Error struct {
+	Field  string
+	Reason string
+}
+
+func (e *Error) Error() string {
+	return e.Field + " " + e.Reason
+}
+
+// Required проверяет что значение не пустое
+func Required(field, value string) error {
+	if strings.TrimSpace(value) == "" {
+		return &Error{Field: field, Reason: "can't be blank"}
+	}
+	return nil
+}
+
+// Email проверяет что значение похоже на адрес почты
+func Email(field, value string) error {
+	if err := Required(field, value); err != nil {
+		return err
+	}
+	addr, err := mail.ParseAddress(value)
+	if err != nil || addr.Address != value {
+		return &Error{Field: field, Reason: "is invalid"}
+	}
+	return nil
+}
+
+// First возвращает первую из ошибок
+func First(errs ...error) error {
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
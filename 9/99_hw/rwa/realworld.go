@@ -2,10 +2,64 @@ package main
 
 import (
 	"net/http"
+
+	"github.com/gorilla/mux"
+
+	"rwa/pkg/article"
+	"rwa/pkg/delivery"
+	"rwa/pkg/session"
+	"rwa/pkg/user"
 )
 
 // сюда писать код
 
+// GetApp собирает приложение: in-memory репозитории, usecase поверх них и
+// http-ручки на gorilla/mux под /api
 func GetApp() http.Handler {
-	return nil
+	usersRepo := user.NewMemoryRepo()
+	articlesRepo := article.NewMemoryRepo()
+	commentsRepo := article.NewCommentMemoryRepo()
+	sm := session.NewManager(session.NewMemoryRepo())
+
+	users := user.NewUsecase(usersRepo)
+	uh := &delivery.UserHandler{
+		Users:    users,
+		Sessions: sm,
+	}
+	ph := &delivery.ProfileHandler{
+		Users: users,
+	}
+	ah := &delivery.ArticleHandler{
+		Articles: article.NewUsecase(articlesRepo, commentsRepo, users),
+	}
+
+	r := mux.NewRouter()
+	api := r.PathPrefix("/api").Subrouter()
+	api.Use(delivery.AuthMiddleware(sm))
+
+	api.HandleFunc("/users", uh.Register).Methods(http.MethodPost)
+	api.HandleFunc("/users/login", uh.Login).Methods(http.MethodPost)
+	api.Handle("/user", delivery.RequireAuth(uh.Current)).Methods(http.MethodGet)
+	api.Handle("/user", delivery.RequireAuth(uh.Update)).Methods(http.MethodPut)
+	api.Handle("/user/logout", delivery.RequireAuth(uh.Logout)).Methods(http.MethodPost)
+
+	api.HandleFunc("/profiles/{username}", ph.Get).Methods(http.MethodGet)
+	api.Handle("/profiles/{username}/follow", delivery.RequireAuth(ph.Follow)).Methods(http.MethodPost)
+	api.Handle("/profiles/{username}/follow", delivery.RequireAuth(ph.Unfollow)).Methods(http.MethodDelete)
+
+	// feed раньше {slug}, иначе mux примет его за slug
+	api.Handle("/articles/feed", delivery.RequireAuth(ah.Feed)).Methods(http.MethodGet)
+	api.HandleFunc("/articles", ah.List).Methods(http.MethodGet)
+	api.Handle("/articles", delivery.RequireAuth(ah.Create)).Methods(http.MethodPost)
+	api.HandleFunc("/articles/{slug}", ah.Get).Methods(http.MethodGet)
+	api.Handle("/articles/{slug}", delivery.RequireAuth(ah.Update)).Methods(http.MethodPut)
+	api.Handle("/articles/{slug}", delivery.RequireAuth(ah.Delete)).Methods(http.MethodDelete)
+	api.Handle("/articles/{slug}/favorite", delivery.RequireAuth(ah.Favorite)).Methods(http.MethodPost)
+	api.Handle("/articles/{slug}/favorite", delivery.RequireAuth(ah.Unfavorite)).Methods(http.MethodDelete)
+
+	api.HandleFunc("/articles/{slug}/comments", ah.Comments).Methods(http.MethodGet)
+	api.Handle("/articles/{slug}/comments", delivery.RequireAuth(ah.AddComment)).Methods(http.MethodPost)
+	api.Handle("/articles/{slug}/comments/{id:[0-9]+}", delivery.RequireAuth(ah.DeleteComment)).Methods(http.MethodDelete)
+
+	return r
 }
@@ -0,0 +1,336 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type TestComment struct {
+	ID        int         `json:"id" testdiff:"ignore"`
+	CreatedAt FakeTime    `json:"createdAt"`
+	UpdatedAt FakeTime    `json:"updatedAt"`
+	Body      string      `json:"body"`
+	Author    TestProfile `json:"author"`
+}
+
+func rememberComment(tplParams map[string]string, key string) func(*http.Response, []byte, interface{}) error {
+	return func(r *http.Response, body []byte, resp interface{}) error {
+		data := &struct {
+			Comment struct {
+				ID json.Number `json:"id"`
+			} `json:"comment"`
+		}{}
+		if err := json.Unmarshal(body, data); err != nil {
+			return err
+		}
+		tplParams[key] = data.Comment.ID.String()
+		return nil
+	}
+}
+
+func TestSocial(t *testing.T) {
+	ts := httptest.NewServer(GetApp())
+	defer ts.Close()
+
+	tplParams := map[string]string{
+		"APIURL": ts.URL + "/api",
+	}
+
+	article := func(favorited bool, count int, following bool) func() interface{} {
+		return func() interface{} {
+			return &struct {
+				Article TestArticle
+			}{
+				Article: TestArticle{
+					Author: TestProfile{
+						Username:  "alice",
+						Following: following,
+					},
+					Body:           "body",
+					Title:          "Followers and favorites",
+					Description:    "about",
+					CreatedAt:      FakeTime{true},
+					UpdatedAt:      FakeTime{true},
+					TagList:        []string{"social"},
+					Favorited:      favorited,
+					FavoritesCount: count,
+				},
+			}
+		}
+	}
+
+	feed := func(count int) func() interface{} {
+		return func() interface{} {
+			articles := []TestArticle{}
+			for i := 0; i < count; i++ {
+				articles = append(articles, TestArticle{
+					Author: TestProfile{
+						Username:  "alice",
+						Following: true,
+					},
+					Body:           "body",
+					Title:          "Followers and favorites",
+					Description:    "about",
+					CreatedAt:      FakeTime{true},
+					UpdatedAt:      FakeTime{true},
+					TagList:        []string{"social"},
+					Favorited:      true,
+					FavoritesCount: 1,
+				})
+			}
+			return &struct {
+				Articles      []TestArticle `json:"articles"`
+				ArticlesCount int           `json:"articlesCount"`
+			}{
+				Articles:      articles,
+				ArticlesCount: count,
+			}
+		}
+	}
+
+	profile := func(following bool) func() interface{} {
+		return func() interface{} {
+			return &struct {
+				Profile TestProfile
+			}{
+				Profile: TestProfile{
+					Username:  "alice",
+					Following: following,
+				},
+			}
+		}
+	}
+
+	comments := func(bodies ...string) func() interface{} {
+		return func() interface{} {
+			res := &struct {
+				Comments []TestComment `json:"comments"`
+			}{
+				Comments: []TestComment{},
+			}
+			for _, body := range bodies {
+				res.Comments = append(res.Comments, TestComment{
+					CreatedAt: FakeTime{true},
+					UpdatedAt: FakeTime{true},
+					Body:      body,
+					Author:    TestProfile{Username: "bob"},
+				})
+			}
+			return res
+		}
+	}
+
+	testCases := []*ApiTestCase{
+		registerCase(tplParams, "alice"),
+		registerCase(tplParams, "bob"),
+		&ApiTestCase{
+			Name:           "Create article",
+			Method:         "POST",
+			Body:           `{"article":{"title":"Followers and favorites", "description":"about", "body":"body", "tagList":["social"]}}`,
+			URL:            "{{APIURL}}/articles",
+			TokenName:      "token_alice",
+			ResponseStatus: 201,
+			Expected:       article(false, 0, false),
+			After:          rememberString(tplParams, "slug", "Article.Slug"),
+		},
+
+		&ApiTestCase{
+			Name:           "Feed - requires auth",
+			Method:         "GET",
+			URL:            "{{APIURL}}/articles/feed",
+			ResponseStatus: 401,
+		},
+		&ApiTestCase{
+			Name:           "Feed - empty before follow",
+			Method:         "GET",
+			URL:            "{{APIURL}}/articles/feed",
+			TokenName:      "token_bob",
+			ResponseStatus: 200,
+			Expected:       feed(0),
+		},
+		&ApiTestCase{
+			Name:           "Follow - requires auth",
+			Method:         "POST",
+			URL:            "{{APIURL}}/profiles/alice/follow",
+			ResponseStatus: 401,
+		},
+		&ApiTestCase{
+			Name:           "Follow - yourself",
+			Method:         "POST",
+			URL:            "{{APIURL}}/profiles/alice/follow",
+			TokenName:      "token_alice",
+			ResponseStatus: 422,
+		},
+		&ApiTestCase{
+			Name:           "Follow - unknown user",
+			Method:         "POST",
+			URL:            "{{APIURL}}/profiles/nobody/follow",
+			TokenName:      "token_bob",
+			ResponseStatus: 404,
+		},
+		&ApiTestCase{
+			Name:           "Follow",
+			Method:         "POST",
+			URL:            "{{APIURL}}/profiles/alice/follow",
+			TokenName:      "token_bob",
+			ResponseStatus: 200,
+			Expected:       profile(true),
+		},
+		&ApiTestCase{
+			Name:           "Profile - follower",
+			Method:         "GET",
+			URL:            "{{APIURL}}/profiles/alice",
+			TokenName:      "token_bob",
+			ResponseStatus: 200,
+			Expected:       profile(true),
+		},
+		&ApiTestCase{
+			Name:           "Profile - anonymous",
+			Method:         "GET",
+			URL:            "{{APIURL}}/profiles/alice",
+			ResponseStatus: 200,
+			Expected:       profile(false),
+		},
+
+		&ApiTestCase{
+			Name:           "Favorite - requires auth",
+			Method:         "POST",
+			URL:            "{{APIURL}}/articles/{{slug}}/favorite",
+			ResponseStatus: 401,
+		},
+		&ApiTestCase{
+			Name:           "Favorite",
+			Method:         "POST",
+			URL:            "{{APIURL}}/articles/{{slug}}/favorite",
+			TokenName:      "token_bob",
+			ResponseStatus: 200,
+			Expected:       article(true, 1, true),
+		},
+		&ApiTestCase{
+			Name:           "Favorite - twice counts once",
+			Method:         "POST",
+			URL:            "{{APIURL}}/articles/{{slug}}/favorite",
+			TokenName:      "token_bob",
+			ResponseStatus: 200,
+			Expected:       article(true, 1, true),
+		},
+		&ApiTestCase{
+			Name:           "Article - seen by author",
+			Method:         "GET",
+			URL:            "{{APIURL}}/articles/{{slug}}",
+			TokenName:      "token_alice",
+			ResponseStatus: 200,
+			Expected:       article(false, 1, false),
+		},
+		&ApiTestCase{
+			Name:           "Feed - followed author",
+			Method:         "GET",
+			URL:            "{{APIURL}}/articles/feed",
+			TokenName:      "token_bob",
+			ResponseStatus: 200,
+			Expected:       feed(1),
+		},
+		&ApiTestCase{
+			Name:           "Articles - favorited by",
+			Method:         "GET",
+			URL:            "{{APIURL}}/articles?favorited=bob",
+			TokenName:      "token_bob",
+			ResponseStatus: 200,
+			Expected:       feed(1),
+		},
+
+		&ApiTestCase{
+			Name:           "Comment - requires auth",
+			Method:         "POST",
+			Body:           `{"comment":{"body":"nice"}}`,
+			URL:            "{{APIURL}}/articles/{{slug}}/comments",
+			ResponseStatus: 401,
+		},
+		&ApiTestCase{
+			Name:           "Comment - empty body",
+			Method:         "POST",
+			Body:           `{"comment":{"body":""}}`,
+			URL:            "{{APIURL}}/articles/{{slug}}/comments",
+			TokenName:      "token_bob",
+			ResponseStatus: 422,
+		},
+		&ApiTestCase{
+			Name:           "Comment",
+			Method:         "POST",
+			Body:           `{"comment":{"body":"nice"}}`,
+			URL:            "{{APIURL}}/articles/{{slug}}/comments",
+			TokenName:      "token_bob",
+			ResponseStatus: 200,
+			Expected: func() interface{} {
+				return &struct {
+					Comment TestComment
+				}{
+					Comment: TestComment{
+						CreatedAt: FakeTime{true},
+						UpdatedAt: FakeTime{true},
+						Body:      "nice",
+						Author:    TestProfile{Username: "bob"},
+					},
+				}
+			},
+			After: rememberComment(tplParams, "comment"),
+		},
+		&ApiTestCase{
+			Name:           "Comments - list",
+			Method:         "GET",
+			URL:            "{{APIURL}}/articles/{{slug}}/comments",
+			ResponseStatus: 200,
+			Expected:       comments("nice"),
+		},
+		&ApiTestCase{
+			Name:           "Comment - delete by another user",
+			Method:         "DELETE",
+			URL:            "{{APIURL}}/articles/{{slug}}/comments/{{comment}}",
+			TokenName:      "token_alice",
+			ResponseStatus: 403,
+		},
+		&ApiTestCase{
+			Name:           "Comment - delete",
+			Method:         "DELETE",
+			URL:            "{{APIURL}}/articles/{{slug}}/comments/{{comment}}",
+			TokenName:      "token_bob",
+			ResponseStatus: 200,
+		},
+		&ApiTestCase{
+			Name:           "Comments - empty after delete",
+			Method:         "GET",
+			URL:            "{{APIURL}}/articles/{{slug}}/comments",
+			ResponseStatus: 200,
+			Expected:       comments(),
+		},
+
+		&ApiTestCase{
+			Name:           "Unfavorite",
+			Method:         "DELETE",
+			URL:            "{{APIURL}}/articles/{{slug}}/favorite",
+			TokenName:      "token_bob",
+			ResponseStatus: 200,
+			Expected:       article(false, 0, true),
+		},
+		&ApiTestCase{
+			Name:           "Unfollow",
+			Method:         "DELETE",
+			URL:            "{{APIURL}}/profiles/alice/follow",
+			TokenName:      "token_bob",
+			ResponseStatus: 200,
+			Expected:       profile(false),
+		},
+		&ApiTestCase{
+			Name:           "Feed - empty after unfollow",
+			Method:         "GET",
+			URL:            "{{APIURL}}/articles/feed",
+			TokenName:      "token_bob",
+			ResponseStatus: 200,
+			Expected:       feed(0),
+		},
+	}
+
+	runApiTestCases(t, tplParams, testCases)
+}